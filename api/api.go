@@ -18,15 +18,16 @@ const DefaultPort = 7892
 type Server struct {
 	router *gin.Engine
 	port   int
+	deps   routes.Deps
 }
 
 // NewServer 创建 API 服务器
-func NewServer() *Server {
-	return NewServerWithPort(DefaultPort)
+func NewServer(deps routes.Deps) *Server {
+	return NewServerWithPort(DefaultPort, deps)
 }
 
 // NewServerWithPort 创建指定端口的 API 服务器
-func NewServerWithPort(port int) *Server {
+func NewServerWithPort(port int, deps routes.Deps) *Server {
 	gin.SetMode(gin.ReleaseMode)
 
 	r := gin.New()
@@ -37,6 +38,7 @@ func NewServerWithPort(port int) *Server {
 	s := &Server{
 		router: r,
 		port:   port,
+		deps:   deps,
 	}
 
 	s.registerRoutes()
@@ -56,12 +58,14 @@ func (s *Server) registerRoutes() {
 	authorized.Use(middleware.JWTAuth())
 	{
 		routes.RegisterLogRoutes(authorized)
-		routes.RegisterProgramRoutes(authorized)
+		routes.RegisterProgramRoutes(authorized, s.deps)
 		routes.RegisterConfigRoutes(authorized)
-		routes.RegisterBangumiRoutes(authorized)
+		routes.RegisterBangumiRoutes(authorized, s.deps)
 		routes.RegisterRSSRoutes(authorized)
 		routes.RegisterSearchRoutes(authorized)
-		routes.RegisterTorrentRoutes(authorized)
+		routes.RegisterTorrentRoutes(authorized, s.deps)
+		routes.RegisterDeadLetterRoutes(authorized, s.deps)
+		routes.RegisterReconcileRoutes(authorized, s.deps)
 	}
 }
 
@@ -1,8 +1,10 @@
 package routes
 
 import (
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -28,6 +30,11 @@ type BangumiUpdateRequest struct {
 	PosterLink    string `json:"poster_link,omitempty"`
 	Enabled       *bool  `json:"enabled,omitempty"`
 	SavePath      string `json:"save_path,omitempty"`
+	Downloader    string `json:"downloader,omitempty"` // 使用的下载器名称，为空时使用默认下载器
+	// 做种策略，不传时使用全局配置
+	SeedRatioLimit  *float64 `json:"seed_ratio_limit,omitempty"`
+	SeedingTime     *int     `json:"seeding_time,omitempty"`
+	SeedDeleteFiles *bool    `json:"seed_delete_files,omitempty"`
 }
 
 // BangumiIDsRequest 批量操作请求
@@ -36,7 +43,7 @@ type BangumiIDsRequest struct {
 }
 
 // RegisterBangumiRoutes 注册番剧管理路由
-func RegisterBangumiRoutes(r *gin.RouterGroup) {
+func RegisterBangumiRoutes(r *gin.RouterGroup, deps Deps) {
 	bangumi := r.Group("/bangumi")
 	{
 		bangumi.GET("/get/all", getAllBangumi)
@@ -50,6 +57,7 @@ func RegisterBangumiRoutes(r *gin.RouterGroup) {
 		bangumi.GET("/refresh/poster/all", refreshAllPosters)
 		bangumi.GET("/reset/all", resetAllBangumi)
 		bangumi.GET("/posters/*path", getPoster)
+		bangumi.GET("/timeline/:id", getBangumiTimeline(deps))
 	}
 }
 
@@ -164,3 +172,23 @@ func getPoster(c *gin.Context) {
 	// 返回文件
 	c.File(fullPath)
 }
+
+// getBangumiTimeline 获取番剧下所有种子的生命周期记录
+// GET /api/v1/bangumi/timeline/:id
+func getBangumiTimeline(deps Deps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			response.BadRequest(c, "Invalid bangumi id", "无效的番剧 ID")
+			return
+		}
+
+		histories, err := deps.DB.ListHistoryByBangumi(c.Request.Context(), uint(id))
+		if err != nil {
+			slog.Error("[api] 获取番剧生命周期失败", "id", id, "error", err)
+			response.InternalError(c, "Failed to get bangumi timeline", "获取番剧生命周期失败")
+			return
+		}
+		response.Success(c, histories)
+	}
+}
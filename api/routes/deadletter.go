@@ -0,0 +1,119 @@
+package routes
+
+import (
+	"log/slog"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"goto-bangumi/api/response"
+	"goto-bangumi/internal/model"
+	"goto-bangumi/internal/task"
+)
+
+// RedriveResult 死信重投结果
+type RedriveResult struct {
+	Marked    int64 `json:"marked"`
+	Submitted int   `json:"submitted"`
+}
+
+// RegisterDeadLetterRoutes 注册死信队列路由
+func RegisterDeadLetterRoutes(r *gin.RouterGroup, deps Deps) {
+	deadLetter := r.Group("/deadletter")
+	{
+		deadLetter.GET("", listDeadLetters(deps))
+		deadLetter.POST("/redrive", redriveDeadLetters(deps))
+		deadLetter.POST("/redrive/:id", redriveDeadLetter(deps))
+		deadLetter.DELETE("/:id", deleteDeadLetter(deps))
+	}
+}
+
+// listDeadLetters 获取死信列表
+// GET /api/v1/deadletter?bangumi_id=xxx&error_class=xxx
+func listDeadLetters(deps Deps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filter := model.DeadLetterFilter{ErrorClass: c.Query("error_class")}
+		if s := c.Query("bangumi_id"); s != "" {
+			id, err := strconv.ParseUint(s, 10, 64)
+			if err != nil {
+				response.BadRequest(c, "Invalid bangumi id", "无效的番剧 ID")
+				return
+			}
+			filter.BangumiID = uint(id)
+		}
+
+		letters, err := deps.DB.ListDeadLetters(c.Request.Context(), filter)
+		if err != nil {
+			slog.Error("[api] 获取死信列表失败", "error", err)
+			response.InternalError(c, "Failed to list dead letters", "获取死信列表失败")
+			return
+		}
+		response.Success(c, letters)
+	}
+}
+
+// redriveDeadLetters 按条件批量重投死信，条件为空时重投全部
+// POST /api/v1/deadletter/redrive
+func redriveDeadLetters(deps Deps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var filter model.DeadLetterFilter
+		if err := c.ShouldBindJSON(&filter); err != nil {
+			response.BadRequest(c, "Invalid request body", "请求参数错误")
+			return
+		}
+		redrive(c, deps, filter)
+	}
+}
+
+// redriveDeadLetter 重投单条死信
+// POST /api/v1/deadletter/redrive/:id
+func redriveDeadLetter(deps Deps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			response.BadRequest(c, "Invalid dead letter id", "无效的死信 ID")
+			return
+		}
+		redrive(c, deps, model.DeadLetterFilter{IDs: []uint{uint(id)}})
+	}
+}
+
+func redrive(c *gin.Context, deps Deps, filter model.DeadLetterFilter) {
+	ctx := c.Request.Context()
+	marked, err := deps.DB.MarkDeadLettersRedrive(ctx, filter)
+	if err != nil {
+		slog.Error("[api] 标记死信重投失败", "error", err)
+		response.InternalError(c, "Failed to redrive dead letters", "重投死信失败")
+		return
+	}
+
+	result := RedriveResult{Marked: marked}
+	// runner 未启动时只做标记，由死信重投任务接手
+	if deps.Runner != nil {
+		result.Submitted, err = task.RedriveDeadLetters(ctx, deps.DB, deps.Runner)
+		if err != nil {
+			slog.Error("[api] 重投死信失败", "error", err)
+			response.InternalError(c, "Failed to redrive dead letters", "重投死信失败")
+			return
+		}
+	}
+	response.SuccessWithMessage(c, "Dead letters redriven", "死信已重投", result)
+}
+
+// deleteDeadLetter 删除死信
+// DELETE /api/v1/deadletter/:id
+func deleteDeadLetter(deps Deps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			response.BadRequest(c, "Invalid dead letter id", "无效的死信 ID")
+			return
+		}
+		if err := deps.DB.DeleteDeadLetter(c.Request.Context(), uint(id)); err != nil {
+			slog.Error("[api] 删除死信失败", "id", id, "error", err)
+			response.InternalError(c, "Failed to delete dead letter", "删除死信失败")
+			return
+		}
+		response.SuccessWithMessage(c, "Dead letter deleted", "死信已删除", nil)
+	}
+}
@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"goto-bangumi/internal/database"
+	"goto-bangumi/internal/download"
+	"goto-bangumi/internal/task"
+	"goto-bangumi/internal/taskrunner"
+)
+
+// Deps 路由处理函数依赖的运行时对象，由创建 API 服务器的一方传入
+type Deps struct {
+	DB          *database.DB
+	Runner      *taskrunner.TaskRunner
+	Reconciler  *task.ReconcileTask
+	Downloaders *download.Manager
+}
@@ -1,12 +1,18 @@
 package routes
 
 import (
+	"log/slog"
 	"os"
 	"runtime"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"goto-bangumi/api/response"
+	"goto-bangumi/internal/conf"
+	"goto-bangumi/internal/download"
+	"goto-bangumi/internal/model"
+	"goto-bangumi/internal/taskrunner"
 )
 
 // Version 程序版本
@@ -34,23 +40,60 @@ type VersionInfo struct {
 	HasUpdate      bool   `json:"has_update"`
 }
 
-// DownloaderStatus 下载器状态
+// DownloaderStatus 下载器状态，state 为 disconnected、connected、backoff 或 auth_failed
 type DownloaderStatus struct {
-	Connected bool   `json:"connected"`
-	Type      string `json:"type"`
+	Name      string     `json:"name"`
+	Connected bool       `json:"connected"`
+	Type      string     `json:"type"`
+	State     string     `json:"state"`
+	Error     string     `json:"error,omitempty"`
+	RetryAt   *time.Time `json:"retry_at,omitempty"` // 网络错误后下次重新登录的时间
+}
+
+// RunnerLimits 任务执行器并发上限，slot_timeout 单位为分钟
+type RunnerLimits struct {
+	MaxConcurrency int `json:"max_concurrency"`
+	MaxDownload    int `json:"max_download"`
+	SlotTimeout    int `json:"slot_timeout"`
+}
+
+// RunnerStatus 任务执行器占用情况
+type RunnerStatus struct {
+	RunnerLimits
+	Running       int `json:"running"`
+	DownloadSlots int `json:"download_slots"`
+	Tasks         int `json:"tasks"`
+	Waiting       int `json:"waiting"`
+}
+
+// RunnerTask 任务执行器中未结束的任务，message 为等待的原因，比如离线配额不足或下载失败后重新开始
+type RunnerTask struct {
+	Link       string            `json:"link"`
+	Name       string            `json:"name"`
+	Downloader string            `json:"downloader"`
+	Phase      string            `json:"phase"`
+	State      string            `json:"state"`
+	RetryCount int               `json:"retry_count"`
+	NextPoll   *time.Time        `json:"next_poll,omitempty"`
+	Message    string            `json:"message,omitempty"`
+	Failovers  []model.Failover  `json:"failovers,omitempty"`   // 下载停滞后换用其他发布的记录
+	RenamePlan *model.RenamePlan `json:"rename_plan,omitempty"` // 添加时预先计算的重命名结果
 }
 
 // RegisterProgramRoutes 注册程序控制路由
-func RegisterProgramRoutes(r *gin.RouterGroup) {
+func RegisterProgramRoutes(r *gin.RouterGroup, deps Deps) {
 	r.GET("/restart", restart)
 	r.GET("/start", start)
 	r.GET("/stop", stop)
 	r.GET("/status", status)
 	r.GET("/shutdown", shutdown)
-	r.GET("/check/downloader", checkDownloader)
+	r.GET("/check/downloader", checkDownloader(deps))
 	r.GET("/check/update", checkUpdate)
 	r.POST("/program/update", programUpdate)
 	r.GET("/update/status", updateStatus)
+	r.GET("/runner/status", runnerStatus(deps))
+	r.GET("/runner/tasks", runnerTasks(deps))
+	r.PUT("/runner/limits", updateRunnerLimits(deps))
 }
 
 // restart 重启程序
@@ -97,16 +140,37 @@ func shutdown(c *gin.Context) {
 	}()
 }
 
-// checkDownloader 检查下载器状态
+// checkDownloader 检查所有下载器的连接状态，未连接的下载器会尝试登录一次
 // GET /api/v1/check/downloader
-func checkDownloader(c *gin.Context) {
-	// TODO: 实现下载器检查逻辑
-	status := DownloaderStatus{
-		Connected: false,
-		Type:      "unknown",
+func checkDownloader(deps Deps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if deps.Downloaders == nil {
+			response.InternalError(c, "Downloaders not initialized", "下载器未初始化")
+			return
+		}
+		clients := deps.Downloaders.Clients()
+		result := make([]DownloaderStatus, 0, len(clients))
+		for _, client := range clients {
+			// 退避和认证失败时 EnsureLogin 直接返回，不会请求下载器
+			_ = client.EnsureLogin(c.Request.Context())
+			session := client.Session()
+			status := DownloaderStatus{
+				Name:      client.Name,
+				Connected: session.State == download.SessionConnected,
+				Type:      client.Type(),
+				State:     string(session.State),
+			}
+			if session.Err != nil {
+				status.Error = session.Err.Error()
+			}
+			if session.State == download.SessionBackoff {
+				retryAt := session.RetryAt
+				status.RetryAt = &retryAt
+			}
+			result = append(result, status)
+		}
+		response.Success(c, result)
 	}
-
-	response.Success(c, status)
 }
 
 // checkUpdate 检查版本更新
@@ -141,3 +205,100 @@ func updateStatus(c *gin.Context) {
 
 	response.Success(c, status)
 }
+
+// runnerStatus 获取任务执行器的并发上限和当前占用
+// GET /api/v1/runner/status
+func runnerStatus(deps Deps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if deps.Runner == nil {
+			response.InternalError(c, "Task runner not started", "任务执行器未启动")
+			return
+		}
+		stats := deps.Runner.Stats()
+		response.Success(c, RunnerStatus{
+			RunnerLimits: RunnerLimits{
+				MaxConcurrency: stats.MaxConcurrency,
+				MaxDownload:    stats.MaxDownload,
+				SlotTimeout:    int(stats.SlotTimeout / time.Minute),
+			},
+			Running:       stats.Running,
+			DownloadSlots: stats.DownloadSlots,
+			Tasks:         stats.Tasks,
+			Waiting:       stats.Waiting,
+		})
+	}
+}
+
+// runnerTasks 获取任务执行器中所有未结束任务的状态
+// GET /api/v1/runner/tasks
+func runnerTasks(deps Deps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if deps.Runner == nil {
+			response.InternalError(c, "Task runner not started", "任务执行器未启动")
+			return
+		}
+		tasks := deps.Runner.Tasks()
+		result := make([]RunnerTask, 0, len(tasks))
+		for _, task := range tasks {
+			item := RunnerTask{
+				Link:       task.Link,
+				Name:       task.Name,
+				Downloader: task.Downloader,
+				Phase:      task.Phase.String(),
+				State:      task.State.String(),
+				RetryCount: task.RetryCount,
+				Message:    task.Message,
+				Failovers:  task.Failovers,
+				RenamePlan: task.RenamePlan,
+			}
+			if !task.NextPoll.IsZero() {
+				nextPoll := task.NextPoll
+				item.NextPoll = &nextPoll
+			}
+			result = append(result, item)
+		}
+		response.Success(c, result)
+	}
+}
+
+// updateRunnerLimits 运行时调整任务执行器的并发上限并写回配置，值为 0 的字段保持不变
+// PUT /api/v1/runner/limits
+func updateRunnerLimits(deps Deps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if deps.Runner == nil {
+			response.InternalError(c, "Task runner not started", "任务执行器未启动")
+			return
+		}
+		var req RunnerLimits
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, "Invalid request body", "请求参数错误")
+			return
+		}
+		if req.MaxConcurrency < 0 || req.MaxDownload < 0 || req.SlotTimeout < 0 {
+			response.BadRequest(c, "Limits must not be negative", "上限不能为负数")
+			return
+		}
+
+		deps.Runner.SetLimits(taskrunner.Limits{
+			MaxConcurrency: req.MaxConcurrency,
+			MaxDownload:    req.MaxDownload,
+			SlotTimeout:    time.Duration(req.SlotTimeout) * time.Minute,
+		})
+		limits := deps.Runner.Limits()
+		err := conf.Update(func(cfg *model.Config) {
+			cfg.Task.MaxConcurrency = limits.MaxConcurrency
+			cfg.Task.MaxDownload = limits.MaxDownload
+			cfg.Task.SlotTimeout = int(limits.SlotTimeout / time.Minute)
+		})
+		if err != nil {
+			slog.Error("[api] 保存任务执行器配置失败", "error", err)
+			response.InternalError(c, "Failed to save config", "保存配置失败")
+			return
+		}
+		response.SuccessWithMessage(c, "Runner limits updated", "并发上限已更新", RunnerLimits{
+			MaxConcurrency: limits.MaxConcurrency,
+			MaxDownload:    limits.MaxDownload,
+			SlotTimeout:    int(limits.SlotTimeout / time.Minute),
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package routes
+
+import (
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+
+	"goto-bangumi/api/response"
+)
+
+// RegisterReconcileRoutes 注册下载器对账路由
+func RegisterReconcileRoutes(r *gin.RouterGroup, deps Deps) {
+	reconcile := r.Group("/reconcile")
+	{
+		reconcile.GET("/report", reconcileReport(deps))
+		reconcile.POST("", runReconcile(deps))
+	}
+}
+
+// reconcileReport 获取最近一次对账的结果，还没有对账过时返回空
+// GET /api/v1/reconcile/report
+func reconcileReport(deps Deps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if deps.Reconciler == nil {
+			response.InternalError(c, "Reconciler not started", "对账任务未启动")
+			return
+		}
+		response.Success(c, deps.Reconciler.Report())
+	}
+}
+
+// runReconcile 立即执行一次对账并返回结果
+// POST /api/v1/reconcile
+func runReconcile(deps Deps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if deps.Reconciler == nil {
+			response.InternalError(c, "Reconciler not started", "对账任务未启动")
+			return
+		}
+		report, err := deps.Reconciler.Reconcile(c.Request.Context())
+		if err != nil {
+			slog.Error("[api] 对账失败", "error", err)
+			response.InternalError(c, "Failed to reconcile", "对账失败")
+			return
+		}
+		response.SuccessWithMessage(c, "Reconcile finished", "对账完成", report)
+	}
+}
@@ -15,6 +15,8 @@ type RSSAddRequest struct {
 	Enabled   bool   `json:"enabled"`
 	Filter    string `json:"filter,omitempty"`
 	Include   string `json:"include,omitempty"`
+	// 新番剧使用的下载器名称，为空时使用默认下载器
+	Downloader string `json:"downloader,omitempty"`
 }
 
 // RSSUpdateRequest RSS 更新请求
@@ -26,6 +28,8 @@ type RSSUpdateRequest struct {
 	Enabled   *bool  `json:"enabled,omitempty"`
 	Filter    string `json:"filter,omitempty"`
 	Include   string `json:"include,omitempty"`
+	// 新番剧使用的下载器名称，为空时使用默认下载器
+	Downloader string `json:"downloader,omitempty"`
 }
 
 // RSSIDsRequest 批量操作请求
@@ -1,6 +1,8 @@
 package routes
 
 import (
+	"log/slog"
+
 	"github.com/gin-gonic/gin"
 
 	"goto-bangumi/api/response"
@@ -19,13 +21,14 @@ type TorrentDownloadRequest struct {
 }
 
 // RegisterTorrentRoutes 注册种子管理路由
-func RegisterTorrentRoutes(r *gin.RouterGroup) {
+func RegisterTorrentRoutes(r *gin.RouterGroup, deps Deps) {
 	torrent := r.Group("/torrent")
 	{
 		torrent.GET("/get_all", getAllTorrents)
 		torrent.POST("/delete", deleteTorrent)
 		torrent.POST("/disable", disableTorrent)
 		torrent.POST("/download", downloadTorrent)
+		torrent.GET("/timeline", getTorrentTimeline(deps))
 	}
 }
 
@@ -74,3 +77,23 @@ func downloadTorrent(c *gin.Context) {
 	// TODO: 实现手动下载种子的逻辑
 	response.SuccessWithMessage(c, "Torrent download started", "开始下载种子", nil)
 }
+
+// getTorrentTimeline 获取种子的生命周期记录
+// GET /api/v1/torrent/timeline?link=xxx
+func getTorrentTimeline(deps Deps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		link := c.Query("link")
+		if link == "" {
+			response.BadRequest(c, "Missing torrent link", "缺少种子链接")
+			return
+		}
+
+		histories, err := deps.DB.ListHistoryByTorrent(c.Request.Context(), link)
+		if err != nil {
+			slog.Error("[api] 获取种子生命周期失败", "link", link, "error", err)
+			response.InternalError(c, "Failed to get torrent timeline", "获取种子生命周期失败")
+			return
+		}
+		response.Success(c, histories)
+	}
+}
@@ -0,0 +1,42 @@
+package apperrors
+
+import (
+	"context"
+	"errors"
+)
+
+// 错误分类，用于死信队列按类别筛选和重投
+const (
+	ClassNetwork   = "network"
+	ClassParse     = "parse"
+	ClassAuth      = "auth"
+	ClassNotFound  = "not_found"
+	ClassQuota     = "quota"
+	ClassTimeout   = "timeout"
+	ClassCancelled = "cancelled"
+	ClassUnknown   = "unknown"
+)
+
+// Classify 返回错误所属的类别
+func Classify(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, context.Canceled):
+		return ClassCancelled
+	case errors.Is(err, context.DeadlineExceeded):
+		return ClassTimeout
+	case IsDownloadLoginError(err), IsDownloadAuthenticationError(err), IsDownloadForbiddenError(err):
+		return ClassAuth
+	case IsDownloadQuotaError(err):
+		return ClassQuota
+	case IsKeyError(err):
+		return ClassNotFound
+	case IsNetworkError(err):
+		return ClassNetwork
+	case IsParseError(err):
+		return ClassParse
+	default:
+		return ClassUnknown
+	}
+}
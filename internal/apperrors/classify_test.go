@@ -0,0 +1,32 @@
+package apperrors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"nil", nil, ""},
+		{"network", &NetworkError{Err: errors.New("connection refused")}, ClassNetwork},
+		{"wrapped login", fmt.Errorf("登录失败: %w", NewDownloadLoginError(errors.New("bad password"))), ClassAuth},
+		{"key", &DownloadKeyError{Err: errors.New("种子不存在"), Key: "hash"}, ClassNotFound},
+		{"quota", &DownloadQuotaError{Err: errors.New("offline quota exhausted")}, ClassQuota},
+		{"parse", &ParseError{Err: errors.New("bad torrent")}, ClassParse},
+		{"deadline", fmt.Errorf("poll: %w", context.DeadlineExceeded), ClassTimeout},
+		{"other", errors.New("no valid hash found"), ClassUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Classify(tt.err); got != tt.want {
+				t.Errorf("Classify() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
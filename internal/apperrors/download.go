@@ -79,3 +79,21 @@ func IsDownloadLoginError(err error) bool {
 	var loginErr *DownloadLoginError
 	return errors.As(err, &loginErr)
 }
+
+// DownloadQuotaError 下载器的配额已经用完，比如网盘离线下载次数，需要等配额恢复后再添加
+type DownloadQuotaError struct {
+	Err error
+}
+
+func (e *DownloadQuotaError) Error() string {
+	return "download quota error: " + e.Err.Error()
+}
+
+func (e *DownloadQuotaError) Unwrap() error {
+	return e.Err
+}
+
+func IsDownloadQuotaError(err error) bool {
+	var quotaErr *DownloadQuotaError
+	return errors.As(err, &quotaErr)
+}
@@ -6,6 +6,8 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 
 	"github.com/BurntSushi/toml"
 	"github.com/ilyakaznacheev/cleanenv"
@@ -43,11 +45,54 @@ func Init() error {
 		}
 	}
 
+	if err := applyDownloaderDefaults(configPath, loaded); err != nil {
+		return err
+	}
+
 	// Write complete config back (backfill defaults)
 	cfg = loaded
 	return save(cfg)
 }
 
+// applyDownloaderDefaults cleanenv 不会对 [[downloaders]] 数组中的元素应用 env-default，
+// 按配置文件中实际写了的键，把没有写的字段补成 model.DefaultDownloaderConfig 的值
+func applyDownloaderDefaults(path string, c *model.Config) error {
+	var raw struct {
+		Downloaders []map[string]any `toml:"downloaders"`
+	}
+	if _, err := toml.DecodeFile(path, &raw); err != nil {
+		return err
+	}
+	def := reflect.ValueOf(model.DefaultDownloaderConfig())
+	for i := range c.Downloaders {
+		if i >= len(raw.Downloaders) {
+			break
+		}
+		mergeDefaults(reflect.ValueOf(&c.Downloaders[i]).Elem(), def, raw.Downloaders[i])
+	}
+	return nil
+}
+
+// mergeDefaults 把 raw 中没有出现的字段设为 def 中的值，嵌套的结构体按子表递归处理
+func mergeDefaults(dst, def reflect.Value, raw map[string]any) {
+	t := dst.Type()
+	for i := range t.NumField() {
+		key, _, _ := strings.Cut(t.Field(i).Tag.Get("toml"), ",")
+		if key == "" || key == "-" {
+			continue
+		}
+		value, ok := raw[key]
+		if dst.Field(i).Kind() == reflect.Struct {
+			sub, _ := value.(map[string]any)
+			mergeDefaults(dst.Field(i), def.Field(i), sub)
+			continue
+		}
+		if !ok {
+			dst.Field(i).Set(def.Field(i))
+		}
+	}
+}
+
 // Get returns the global config.
 func Get() *model.Config {
 	return cfg
@@ -99,6 +99,49 @@ func TestUpdateWritesTomlConfig(t *testing.T) {
 	}
 }
 
+func TestDefaultDownloaderConfigMatchesEnvDefault(t *testing.T) {
+	useTempConfig(t)
+
+	if err := Init(); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	if got, want := Get().Downloader, model.DefaultDownloaderConfig(); got != want {
+		t.Fatalf("DefaultDownloaderConfig() = %+v, want env-default %+v", want, got)
+	}
+}
+
+func TestInitAppliesDefaultsToNamedDownloaders(t *testing.T) {
+	path := useTempConfig(t)
+
+	data := []byte("[[downloaders]]\nname = \"mock\"\ntype = \"mock\"\nseed_ratio = 0\n[downloaders.mock]\nsimulate = true\n")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("WriteFile(%q) error = %v", path, err)
+	}
+
+	if err := Init(); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+
+	got := Get().Downloaders
+	if len(got) != 1 {
+		t.Fatalf("Downloaders = %+v, want one entry", got)
+	}
+	d := got[0]
+	if d.Name != "mock" || d.Type != "mock" {
+		t.Errorf("name/type = %s/%s, want mock/mock", d.Name, d.Type)
+	}
+	if d.Category != "GotoBangumi" || d.SavePath != "/downloads/Bangumi" {
+		t.Errorf("category/path = %q/%q, want defaults", d.Category, d.SavePath)
+	}
+	// 配置文件中写了的 0 不能被默认值覆盖
+	if d.SeedRatio != 0 {
+		t.Errorf("SeedRatio = %v, want explicit 0", d.SeedRatio)
+	}
+	if !d.Mock.Simulate || d.Mock.TimeScale != 1 || d.Mock.Duration != 60 {
+		t.Errorf("Mock = %+v, want simulate with default time scale and duration", d.Mock)
+	}
+}
+
 func readConfigFile(t *testing.T, path string) string {
 	t.Helper()
 
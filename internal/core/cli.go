@@ -0,0 +1,62 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"goto-bangumi/internal/database"
+	"goto-bangumi/internal/model"
+)
+
+// RunRedriveCommand 命令行标记死信重投
+// 只修改数据库中的标记，由正在运行的程序的死信重投任务在下一个周期提交
+//
+//	goto-bangumi redrive -id 1,2
+//	goto-bangumi redrive -bangumi 3 -class network
+//	goto-bangumi redrive -all
+func RunRedriveCommand(args []string) error {
+	fs := flag.NewFlagSet("redrive", flag.ContinueOnError)
+	ids := fs.String("id", "", "死信 ID，多个用逗号分隔")
+	bangumiID := fs.Uint("bangumi", 0, "番剧 ID")
+	errorClass := fs.String("class", "", "错误类别，如 network、auth、not_found、timeout")
+	all := fs.Bool("all", false, "重投全部死信")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	filter := model.DeadLetterFilter{
+		BangumiID:  *bangumiID,
+		ErrorClass: *errorClass,
+	}
+	for _, s := range strings.Split(*ids, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return fmt.Errorf("无效的死信 ID %q", s)
+		}
+		filter.IDs = append(filter.IDs, uint(id))
+	}
+	if !*all && len(filter.IDs) == 0 && filter.BangumiID == 0 && filter.ErrorClass == "" {
+		return errors.New("需要指定 -id、-bangumi、-class 或 -all")
+	}
+
+	db, err := database.NewDB(nil)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	n, err := db.MarkDeadLettersRedrive(context.Background(), filter)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("已标记 %d 条死信等待重投\n", n)
+	return nil
+}
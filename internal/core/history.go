@@ -0,0 +1,63 @@
+package core
+
+import (
+	"context"
+	"log/slog"
+
+	"goto-bangumi/internal/apperrors"
+	"goto-bangumi/internal/database"
+	"goto-bangumi/internal/model"
+	"goto-bangumi/internal/taskrunner"
+)
+
+// historyRecorder 把每次阶段结束写入任务历史表
+func historyRecorder(db *database.DB) taskrunner.TransitionFunc {
+	return func(t taskrunner.Transition) {
+		history := &model.TaskHistory{
+			TorrentLink: t.Task.Torrent.Link,
+			BangumiID:   t.Task.Torrent.BangumiID,
+			Phase:       t.Phase.String(),
+			State:       t.State,
+			Duration:    t.Duration.Milliseconds(),
+			RetryCount:  t.RetryCount,
+		}
+		if history.BangumiID == 0 && t.Task.Bangumi != nil {
+			history.BangumiID = t.Task.Bangumi.ID
+		}
+		if t.Err != nil {
+			history.Error = t.Err.Error()
+		} else if t.Message != "" {
+			// 替换任务的原因和添加时发现的重命名问题不是错误，没有单独的字段，同样记在 error 里
+			history.Error = t.Message
+		}
+		if err := db.CreateTaskHistory(context.Background(), history); err != nil {
+			slog.Error("[program] 写入任务历史失败", "torrent", t.Task.Torrent.Name, "error", err)
+		}
+	}
+}
+
+// deadLetterRecorder 把失败的任务写入死信队列，保留从失败阶段重投所需的数据
+func deadLetterRecorder(db *database.DB) taskrunner.TransitionFunc {
+	return func(t taskrunner.Transition) {
+		if t.State != model.HistoryStateFailed {
+			return
+		}
+		letter := &model.DeadLetter{
+			TorrentLink: t.Task.Torrent.Link,
+			BangumiID:   t.Task.Torrent.BangumiID,
+			Phase:       t.Phase,
+			ErrorClass:  apperrors.Classify(t.Err),
+			Guids:       t.Task.Guids,
+			Episodes:    t.Task.RenamedEpisodes,
+		}
+		if letter.BangumiID == 0 && t.Task.Bangumi != nil {
+			letter.BangumiID = t.Task.Bangumi.ID
+		}
+		if t.Err != nil {
+			letter.Error = t.Err.Error()
+		}
+		if err := db.SaveDeadLetter(context.Background(), letter); err != nil {
+			slog.Error("[program] 写入死信失败", "torrent", t.Task.Torrent.Name, "error", err)
+		}
+	}
+}
@@ -3,6 +3,8 @@ package core
 import (
 	"context"
 	"log/slog"
+	"path/filepath"
+	"time"
 
 	"goto-bangumi/internal/conf"
 	"goto-bangumi/internal/database"
@@ -26,7 +28,9 @@ type Program struct {
 	ctx        context.Context
 	cancel     context.CancelFunc
 	db         *database.DB
-	downloader *download.DownloadClient
+	downloader *download.Manager
+	runner     *taskrunner.TaskRunner
+	reconciler *task.ReconcileTask
 }
 
 func InitProgram(ctx context.Context) *Program {
@@ -56,31 +60,71 @@ func InitProgram(ctx context.Context) *Program {
 	parser.Init(&cfg.Parser)
 	notification.NotificationClient.Init(&cfg.Notification)
 	rename.Init(&cfg.Rename)
+	download.InitFileSelector(&cfg.Selector)
+	download.InitTorrentCache(filepath.Join("data", "torrent_cache"))
 
-	downloader := download.NewDownloadClient()
-	downloader.Init(&cfg.Downloader)
+	// [downloader] 为默认下载器，[[downloaders]] 为按名称路由的其他下载器
+	downloader := download.NewManager()
+	downloader.Init(append([]model.DownloaderConfig{cfg.Downloader}, cfg.Downloaders...))
 
 	return &Program{db: db, downloader: downloader}
 }
 
 func (p *Program) Start(ctx context.Context) {
 	p.ctx, p.cancel = context.WithCancel(ctx)
-	go p.downloader.Login(p.ctx)
+	p.downloader.OnStateChange(logSessionChange)
+	p.downloader.LoginAll(p.ctx)
 
 	// 创建并启动 taskrunner
 	renamer := rename.New(p.db, p.downloader)
 	refresher := refresh.New(p.db)
-	runner := taskrunner.New(8, 4)
-	runner.Register(model.PhaseAdding, handlers.NewAddHandler(p.downloader))                    // 唯一受限阶段（持有流水线槽位）
-	runner.Register(model.PhaseChecking, handlers.NewCheckHandler(p.db, p.downloader))          // 轻量查询
-	runner.Register(model.PhaseDownloading, handlers.NewDownloadingHandler(p.db, p.downloader)) // 轻量轮询
-	runner.Register(model.PhaseRenaming, handlers.NewRenameHandler(p.db, renamer))              // 本地文件操作
+	taskCfg := conf.Get().Task
+	runner := taskrunner.New(taskCfg.MaxConcurrency, taskCfg.MaxDownload)
+	runner.SetLimits(taskrunner.Limits{SlotTimeout: time.Duration(taskCfg.SlotTimeout) * time.Minute})
+	poller := download.NewStatusPoller(p.downloader, time.Duration(taskCfg.PollInterval)*time.Second, runner.Wake)
+	downloading := handlers.NewDownloadingHandler(p.db, poller, p.downloader, refresher, conf.Get().Failover)
+	runner.Register(model.PhaseAdding, handlers.NewAddHandler(p.db, p.downloader))                    // 唯一受限阶段（持有流水线槽位）
+	runner.Register(model.PhaseChecking, handlers.NewCheckHandler(p.db, p.downloader))                // 轻量查询
+	runner.Register(model.PhaseDownloading, downloading)                                              // 读取批量轮询结果，停滞时换用其他发布
+	runner.Register(model.PhaseRenaming, handlers.NewRenameHandler(p.db, renamer))                    // 本地文件操作
+	runner.Register(model.PhaseMoving, handlers.NewMoveHandler(p.downloader))                         // 转移到媒体库
+	runner.Register(model.PhaseNotifying, handlers.NewNotifyHandler(notification.NotificationClient)) // 独立重试，不重复重命名
+	runner.OnTransition(historyRecorder(p.db))
+	runner.OnTransition(deadLetterRecorder(p.db))
 	runner.Start(p.ctx)
+	poller.Start(p.ctx)
+
+	reconciler := task.NewReconcileTask(conf.Get().Reconcile, runner, p.db, p.downloader)
+	p.runner, p.reconciler = runner, reconciler
 
 	// 启动调度器
-	InitScheduler(p.ctx, runner, p.db, refresher)
+	InitScheduler(p.ctx, runner, p.db, refresher, p.downloader, reconciler)
+}
+
+// logSessionChange 记录下载器连接状态的变化
+func logSessionChange(event download.SessionEvent) {
+	switch event.To {
+	case download.SessionConnected:
+		slog.Info("[program] 下载器已连接", "name", event.Downloader)
+	case download.SessionAuthFailed:
+		slog.Error("[program] 下载器认证失败，请检查配置", "name", event.Downloader, "error", event.Err)
+	default:
+		slog.Warn("[program] 下载器连接状态变化", "name", event.Downloader, "from", event.From, "to", event.To, "error", event.Err)
+	}
 }
 
+// DB 返回数据库连接
+func (p *Program) DB() *database.DB { return p.db }
+
+// Downloaders 返回下载器管理器
+func (p *Program) Downloaders() *download.Manager { return p.downloader }
+
+// Runner 返回任务执行器，Start 之前为 nil
+func (p *Program) Runner() *taskrunner.TaskRunner { return p.runner }
+
+// Reconciler 返回下载器对账任务，Start 之前为 nil
+func (p *Program) Reconciler() *task.ReconcileTask { return p.reconciler }
+
 func (p *Program) Stop() {
 	p.cancel()
 	if p.db != nil {
@@ -92,7 +136,7 @@ func (p *Program) Stop() {
 }
 
 // InitScheduler 初始化并启动调度器
-func InitScheduler(ctx context.Context, runner *taskrunner.TaskRunner, db *database.DB, refresher *refresh.Refresher) {
+func InitScheduler(ctx context.Context, runner *taskrunner.TaskRunner, db *database.DB, refresher *refresh.Refresher, downloader *download.Manager, reconciler *task.ReconcileTask) {
 	scheduler.InitScheduler(ctx)
 
 	s := scheduler.GetScheduler()
@@ -102,6 +146,9 @@ func InitScheduler(ctx context.Context, runner *taskrunner.TaskRunner, db *datab
 	}
 
 	s.AddTask(task.NewRSSRefreshTask(conf.Get().Program, runner, db, refresher))
+	s.AddTask(task.NewDeadLetterRedriveTask(runner, db))
+	s.AddTask(task.NewSeedingCleanupTask(conf.Get().Seeding, db, downloader))
+	s.AddTask(reconciler)
 
 	s.Start()
 
@@ -16,12 +16,12 @@ var bangumiCreateMutex sync.Mutex
 
 // CreateBangumi 创建番剧
 func (db *DB) CreateBangumi(bangumi *model.Bangumi) error {
-	slog.Info("[database] 创建番剧", "标题", bangumi.OfficialTitle, "MikanID", bangumi.MikanID, "TmdbID", bangumi.TmdbID)
+	slog.Info("[database] 创建番剧", "标题", bangumi.OfficialTitle, "MikanID", bangumi.MikanID, "TmdbID", bangumi.TmdbID, "BgmID", bangumi.BgmID, "AnilistID", bangumi.AnilistID)
 	// 加锁防止并发创建重复的 Bangumi
 	bangumiCreateMutex.Lock()
 	defer bangumiCreateMutex.Unlock()
 
-	// 对于 Bangumi 要进行一个查重, 主要是看其对应的 mikanid 和 tmdbid
+	// 对于 Bangumi 要进行一个查重, 主要是看其对应的 mikanid, tmdbid, bgmid 和 anilistid
 	// 先是看 mikanid 有的话
 	var oldBangumi model.Bangumi
 	var tmdbID int
@@ -36,19 +36,35 @@ func (db *DB) CreateBangumi(bangumi *model.Bangumi) error {
 	} else if bangumi.MikanItem != nil {
 		mikanID = bangumi.MikanItem.ID
 	}
-	// 通过 mikanID 和 tmdbID 来查找 Bangumi
+	var bgmID int
+	if bangumi.BgmID != nil {
+		bgmID = *bangumi.BgmID
+	} else if bangumi.BgmItem != nil {
+		bgmID = bangumi.BgmItem.ID
+	}
+	var anilistID int
+	if bangumi.AnilistID != nil {
+		anilistID = *bangumi.AnilistID
+	} else if bangumi.AnilistItem != nil {
+		anilistID = bangumi.AnilistItem.ID
+	}
+	// 通过 mikanID, tmdbID, bgmID 和 anilistID 来查找 Bangumi
 	// err := db.Where("mikan_id = ? AND tmdb_id = ?", mikanID, tmdbID).First(&oldBangumi).Error
 	err := db.Preload("MikanItem").
 		Preload("TmdbItem").
+		Preload("BgmItem").
+		Preload("AnilistItem").
 		Preload("EpisodeMetadata").
 		Where("mikan_id = ?", mikanID).
-		Or("tmdb_id = ?", tmdbID).First(&oldBangumi).Error
+		Or("tmdb_id = ?", tmdbID).
+		Or("bgm_id = ?", bgmID).
+		Or("anilist_id = ?", anilistID).First(&oldBangumi).Error
 	if err != nil && err != gorm.ErrRecordNotFound {
 		slog.Info("[database] 查找番剧时出错", "错误", err)
 		return err
 	}
 	if oldBangumi.ID != 0 {
-		// 找到的话就更新一下 mikan, tmdb
+		// 找到的话就更新一下 mikan, tmdb, bgm, anilist
 		slog.Debug("[database] 番剧已存在，进行更新", "标题", oldBangumi.OfficialTitle)
 		if oldBangumi.MikanID == nil && bangumi.MikanItem != nil {
 			oldBangumi.MikanItem = bangumi.MikanItem
@@ -56,6 +72,12 @@ func (db *DB) CreateBangumi(bangumi *model.Bangumi) error {
 		if oldBangumi.TmdbID == nil && bangumi.TmdbItem != nil {
 			oldBangumi.TmdbItem = bangumi.TmdbItem
 		}
+		if oldBangumi.BgmID == nil && bangumi.BgmItem != nil {
+			oldBangumi.BgmItem = bangumi.BgmItem
+		}
+		if oldBangumi.AnilistID == nil && bangumi.AnilistItem != nil {
+			oldBangumi.AnilistItem = bangumi.AnilistItem
+		}
 		// 只追加不存在的 EpisodeMetadata
 		existingKeys := make(map[string]struct{}, len(oldBangumi.EpisodeMetadata))
 		for _, e := range oldBangumi.EpisodeMetadata {
@@ -43,12 +43,16 @@ func NewDB(dsn *string) (*DB, error) {
 		// 基础表（无外键依赖）
 		&model.MikanItem{},
 		&model.TmdbItem{},
+		&model.BangumiItem{},
+		&model.AnilistItem{},
 		&model.EpisodeMetadata{},
 		&model.RSSItem{},
 
 		// 有外键依赖的表
-		&model.Bangumi{}, // 依赖 MikanItem, TmdbItem，多对多关联 BangumiParse
+		&model.Bangumi{}, // 依赖 MikanItem, TmdbItem, BangumiItem, AnilistItem，多对多关联 BangumiParse
 		&model.Torrent{}, // 依赖 Bangumi, BangumiParse
+		&model.TaskHistory{},
+		&model.DeadLetter{},
 	); err != nil {
 		fmt.Println("Error migrating database:", err)
 		return nil, err
@@ -126,6 +130,24 @@ func (db *DB) FindUnrenamedTorrent(ctx context.Context) ([]*model.Torrent, error
 	return torrents, err
 }
 
+// FindSeedingTorrents 查询已下载且已重命名、仍留在下载器中的种子，附带所属番剧
+func (db *DB) FindSeedingTorrents(ctx context.Context) ([]*model.Torrent, error) {
+	var torrents []*model.Torrent
+	err := db.WithContext(ctx).Preload("Bangumi").
+		Where("downloaded = ? AND renamed = ?", model.DownloadDone, true).
+		Find(&torrents).Error
+	return torrents, err
+}
+
+// FindSentTorrents 查询发送过下载器的种子（有下载 UID），附带所属番剧，用于和下载器对账
+func (db *DB) FindSentTorrents(ctx context.Context) ([]*model.Torrent, error) {
+	var torrents []*model.Torrent
+	err := db.WithContext(ctx).Preload("Bangumi").
+		Where("download_uid <> ''").
+		Find(&torrents).Error
+	return torrents, err
+}
+
 // CheckNewTorrents 检查新种子（不存在的种子）
 func (db *DB) CheckNewTorrents(ctx context.Context, torrents []*model.Torrent) ([]*model.Torrent, error) {
 	var newTorrents []*model.Torrent
@@ -289,11 +311,13 @@ func (db *DB) GetParsesByBangumiID(ctx context.Context, bangumiID uint) ([]*mode
 
 // ============ Bangumi 复合查询方法 ============
 
-// GetBangumiWithDetails 获取 Bangumi 及其关联的 TMDB、Mikan、Parse 信息
+// GetBangumiWithDetails 获取 Bangumi 及其关联的 TMDB、Mikan、bgm.tv、AniList、Parse 信息
 func (db *DB) GetBangumiWithDetails(ctx context.Context, id uint) (*model.Bangumi, error) {
 	var bangumi model.Bangumi
 	err := db.WithContext(ctx).Preload("TmdbItem").
 		Preload("MikanItem").
+		Preload("BgmItem").
+		Preload("AnilistItem").
 		Preload("EpisodeMetadata").
 		First(&bangumi, id).Error
 	if err != nil {
@@ -307,6 +331,8 @@ func (db *DB) ListBangumiWithDetails(ctx context.Context) ([]*model.Bangumi, err
 	var bangumis []*model.Bangumi
 	err := db.WithContext(ctx).Preload("TmdbItem").
 		Preload("MikanItem").
+		Preload("BgmItem").
+		Preload("AnilistItem").
 		Preload("EpisodeMetadata").
 		Find(&bangumis).Error
 	return bangumis, err
@@ -0,0 +1,88 @@
+package database
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"goto-bangumi/internal/model"
+)
+
+// ============ DeadLetter 相关方法 ============
+
+// SaveDeadLetter 写入死信，同一个种子再次失败时覆盖失败信息并累加失败次数
+func (db *DB) SaveDeadLetter(ctx context.Context, letter *model.DeadLetter) error {
+	return db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "torrent_link"}},
+		DoUpdates: clause.Assignments(map[string]any{
+			"bangumi_id":   letter.BangumiID,
+			"phase":        letter.Phase,
+			"error":        letter.Error,
+			"error_class":  letter.ErrorClass,
+			"guids":        gorm.Expr("excluded.guids"),
+			"episodes":     gorm.Expr("excluded.episodes"),
+			"redrive":      false,
+			"failed_count": gorm.Expr("failed_count + 1"),
+			"updated_at":   gorm.Expr("excluded.updated_at"),
+		}),
+	}).Create(letter).Error
+}
+
+// ListDeadLetters 按条件获取死信
+func (db *DB) ListDeadLetters(ctx context.Context, filter model.DeadLetterFilter) ([]*model.DeadLetter, error) {
+	var letters []*model.DeadLetter
+	err := deadLetterQuery(db.WithContext(ctx), filter).
+		Order("updated_at DESC, id DESC").
+		Find(&letters).Error
+	return letters, err
+}
+
+// MarkDeadLettersRedrive 把符合条件的死信标记为待重投，返回标记的数量
+// filter 为空时标记全部死信
+func (db *DB) MarkDeadLettersRedrive(ctx context.Context, filter model.DeadLetterFilter) (int64, error) {
+	result := deadLetterQuery(db.WithContext(ctx).Model(&model.DeadLetter{}), filter).
+		Where("redrive = ?", false).
+		Update("redrive", true)
+	return result.RowsAffected, result.Error
+}
+
+// ListRedriveDeadLetters 获取等待重投的死信
+func (db *DB) ListRedriveDeadLetters(ctx context.Context) ([]*model.DeadLetter, error) {
+	var letters []*model.DeadLetter
+	err := db.WithContext(ctx).Where("redrive = ?", true).
+		Order("id").
+		Find(&letters).Error
+	return letters, err
+}
+
+// DeadLetterLinks 获取所有死信的种子链接
+func (db *DB) DeadLetterLinks(ctx context.Context) (map[string]bool, error) {
+	var links []string
+	if err := db.WithContext(ctx).Model(&model.DeadLetter{}).Pluck("torrent_link", &links).Error; err != nil {
+		return nil, err
+	}
+	result := make(map[string]bool, len(links))
+	for _, link := range links {
+		result[link] = true
+	}
+	return result, nil
+}
+
+// DeleteDeadLetter 删除死信
+func (db *DB) DeleteDeadLetter(ctx context.Context, id uint) error {
+	return db.WithContext(ctx).Delete(&model.DeadLetter{}, id).Error
+}
+
+func deadLetterQuery(tx *gorm.DB, filter model.DeadLetterFilter) *gorm.DB {
+	if len(filter.IDs) > 0 {
+		tx = tx.Where("id IN ?", filter.IDs)
+	}
+	if filter.BangumiID != 0 {
+		tx = tx.Where("bangumi_id = ?", filter.BangumiID)
+	}
+	if filter.ErrorClass != "" {
+		tx = tx.Where("error_class = ?", filter.ErrorClass)
+	}
+	return tx
+}
@@ -0,0 +1,62 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"goto-bangumi/internal/model"
+)
+
+func TestDeadLetterSaveAndRedrive(t *testing.T) {
+	testdb := ":memory:"
+	db, err := NewDB(&testdb)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	ctx := context.Background()
+
+	letters := []*model.DeadLetter{
+		{TorrentLink: "a", BangumiID: 1, Phase: model.PhaseChecking, ErrorClass: "not_found", Guids: []string{"h1", "h2"}},
+		{TorrentLink: "b", BangumiID: 1, Phase: model.PhaseAdding, ErrorClass: "network"},
+		{TorrentLink: "c", BangumiID: 2, Phase: model.PhaseDownloading, ErrorClass: "network"},
+	}
+	for _, l := range letters {
+		if err := db.SaveDeadLetter(ctx, l); err != nil {
+			t.Fatalf("SaveDeadLetter() error = %v", err)
+		}
+	}
+	// 同一个种子再次失败时覆盖而不是新增
+	again := &model.DeadLetter{TorrentLink: "a", BangumiID: 1, Phase: model.PhaseDownloading, Error: "timeout", ErrorClass: "timeout"}
+	if err := db.SaveDeadLetter(ctx, again); err != nil {
+		t.Fatalf("SaveDeadLetter() error = %v", err)
+	}
+
+	all, err := db.ListDeadLetters(ctx, model.DeadLetterFilter{})
+	if err != nil {
+		t.Fatalf("ListDeadLetters() error = %v", err)
+	}
+	if len(all) != 3 {
+		t.Fatalf("ListDeadLetters() count = %d, want 3", len(all))
+	}
+	byBangumi, _ := db.ListDeadLetters(ctx, model.DeadLetterFilter{BangumiID: 1})
+	for _, l := range byBangumi {
+		if l.TorrentLink == "a" && (l.Phase != model.PhaseDownloading || l.FailedCount != 2 || l.ErrorClass != "timeout") {
+			t.Errorf("updated dead letter = %+v, want downloading/timeout failed twice", l)
+		}
+	}
+
+	n, err := db.MarkDeadLettersRedrive(ctx, model.DeadLetterFilter{ErrorClass: "network"})
+	if err != nil {
+		t.Fatalf("MarkDeadLettersRedrive() error = %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("MarkDeadLettersRedrive() marked %d, want 2", n)
+	}
+	pending, err := db.ListRedriveDeadLetters(ctx)
+	if err != nil {
+		t.Fatalf("ListRedriveDeadLetters() error = %v", err)
+	}
+	if len(pending) != 2 || pending[0].TorrentLink != "b" || pending[1].TorrentLink != "c" {
+		t.Fatalf("pending = %+v, want b and c", pending)
+	}
+}
@@ -0,0 +1,32 @@
+package database
+
+import (
+	"context"
+
+	"goto-bangumi/internal/model"
+)
+
+// ============ TaskHistory 相关方法 ============
+
+// CreateTaskHistory 写入一条任务阶段变更记录
+func (db *DB) CreateTaskHistory(ctx context.Context, history *model.TaskHistory) error {
+	return db.WithContext(ctx).Create(history).Error
+}
+
+// ListHistoryByTorrent 按时间顺序获取种子的阶段变更记录
+func (db *DB) ListHistoryByTorrent(ctx context.Context, link string) ([]*model.TaskHistory, error) {
+	var histories []*model.TaskHistory
+	err := db.WithContext(ctx).Where("torrent_link = ?", link).
+		Order("created_at, id").
+		Find(&histories).Error
+	return histories, err
+}
+
+// ListHistoryByBangumi 按时间顺序获取番剧下所有种子的阶段变更记录
+func (db *DB) ListHistoryByBangumi(ctx context.Context, bangumiID uint) ([]*model.TaskHistory, error) {
+	var histories []*model.TaskHistory
+	err := db.WithContext(ctx).Where("bangumi_id = ?", bangumiID).
+		Order("created_at, id").
+		Find(&histories).Error
+	return histories, err
+}
@@ -0,0 +1,49 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"goto-bangumi/internal/model"
+)
+
+func TestTaskHistoryTimeline(t *testing.T) {
+	testdb := ":memory:"
+	db, err := NewDB(&testdb)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	ctx := context.Background()
+
+	link := "https://mikanani.me/Download/20250829/1b13cab156276b2d29db032e12ee5548afb3f847.torrent"
+	histories := []*model.TaskHistory{
+		{TorrentLink: link, BangumiID: 1, Phase: model.PhaseAdding.String(), State: model.HistoryStateDone},
+		{TorrentLink: link, BangumiID: 1, Phase: model.PhaseChecking.String(), State: model.HistoryStateDone},
+		{TorrentLink: link, BangumiID: 1, Phase: model.PhaseDownloading.String(), State: model.HistoryStateFailed, Error: "download timeout after 4 hours"},
+		{TorrentLink: "other", BangumiID: 2, Phase: model.PhaseAdding.String(), State: model.HistoryStateDone},
+	}
+	for _, h := range histories {
+		if err := db.CreateTaskHistory(ctx, h); err != nil {
+			t.Fatalf("CreateTaskHistory() error = %v", err)
+		}
+	}
+
+	byTorrent, err := db.ListHistoryByTorrent(ctx, link)
+	if err != nil {
+		t.Fatalf("ListHistoryByTorrent() error = %v", err)
+	}
+	if len(byTorrent) != 3 {
+		t.Fatalf("ListHistoryByTorrent() count = %d, want 3", len(byTorrent))
+	}
+	if byTorrent[2].Phase != "downloading" || byTorrent[2].Error == "" {
+		t.Errorf("last history = %+v, want failed downloading with error", byTorrent[2])
+	}
+
+	byBangumi, err := db.ListHistoryByBangumi(ctx, 2)
+	if err != nil {
+		t.Fatalf("ListHistoryByBangumi() error = %v", err)
+	}
+	if len(byBangumi) != 1 || byBangumi[0].TorrentLink != "other" {
+		t.Errorf("ListHistoryByBangumi() = %+v, want the single history of bangumi 2", byBangumi)
+	}
+}
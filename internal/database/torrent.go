@@ -60,11 +60,35 @@ func (db *DB) TorrentRenamed(ctx context.Context, link string) error {
 	return err
 }
 
+// TorrentRemoved 标记种子已从下载器中移除，保留记录避免重复下载
+func (db *DB) TorrentRemoved(ctx context.Context, link string) error {
+	return db.WithContext(ctx).Model(&model.Torrent{}).Where("link = ?", link).Update("downloaded", model.DownloadRemoved).Error
+}
+
 // DeleteTorrent 删除种子
 func (db *DB) DeleteTorrent(ctx context.Context, link string) error {
 	return db.WithContext(ctx).Where("link = ?", link).Delete(&model.Torrent{}).Error
 }
 
+// SetTorrentDownloader 记录种子所在的下载器
+func (db *DB) SetTorrentDownloader(ctx context.Context, link string, downloader string) error {
+	return db.WithContext(ctx).Model(&model.Torrent{}).Where("link = ?", link).Update("downloader", downloader).Error
+}
+
+// FindDownloadedTorrents 查询番剧已经下载完成的种子，包括做种结束已移除的，exclude 为要排除的种子链接
+func (db *DB) FindDownloadedTorrents(ctx context.Context, bangumiID uint, exclude string) ([]*model.Torrent, error) {
+	var torrents []*model.Torrent
+	err := db.WithContext(ctx).
+		Where("bangumi_id = ? AND downloaded IN ? AND link <> ?", bangumiID, []model.DownloadStatus{model.DownloadDone, model.DownloadRemoved}, exclude).
+		Find(&torrents).Error
+	return torrents, err
+}
+
+// SetTorrentInfoHash 记录种子的 info hash，用于找到本地缓存的种子文件
+func (db *DB) SetTorrentInfoHash(ctx context.Context, link string, infoHash string) error {
+	return db.WithContext(ctx).Model(&model.Torrent{}).Where("link = ?", link).Update("info_hash", infoHash).Error
+}
+
 // AddTorrentDUID 为种子添加下载 UID
 func (db *DB) AddTorrentDUID(ctx context.Context, link string, guid string) error {
 	t := model.Torrent{}
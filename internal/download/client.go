@@ -6,25 +6,24 @@ import (
 	"fmt"
 	"log/slog"
 	"strings"
+	"sync"
 
-	"golang.org/x/sync/singleflight"
-
-	"goto-bangumi/internal/apperrors"
 	"goto-bangumi/internal/download/downloader"
 	"goto-bangumi/internal/model"
-	"goto-bangumi/internal/network"
 )
 
 // DownloadClient 下载客户端，负责登录管理
+// 所有操作都经过 session：未登录时先登录，操作返回认证错误时重新登录并重试一次
 type DownloadClient struct {
+	Name           string // 下载器名称，由 Manager 设置
 	Downloader     downloader.BaseDownloader
 	SavePath       string
+	MediaPath      string
+	Category       string // 配置的分类，不支持分类的下载器按标签或忽略
 	downloaderType string
 
-	// 登录控制
-	logined    bool // 是否已登录
-	LoginError bool // 登录错误通道
-	loginGroup singleflight.Group
+	mu      sync.RWMutex // 保护 Init 对下载器的替换
+	session session
 }
 
 // NewDownloadClient 创建下载客户端实例
@@ -32,8 +31,12 @@ func NewDownloadClient() *DownloadClient {
 	return &DownloadClient{}
 }
 
+// Init 应用配置并清除登录状态，修改账号密码后重新 Init 即可恢复认证失败的下载器
 func (c *DownloadClient) Init(config *model.DownloaderConfig) {
+	c.mu.Lock()
 	c.SavePath = config.SavePath
+	c.MediaPath = config.MediaPath
+	c.Category = config.Category
 
 	downloaderType := strings.ToLower(config.Type)
 	if c.downloaderType != downloaderType {
@@ -42,160 +45,146 @@ func (c *DownloadClient) Init(config *model.DownloaderConfig) {
 		c.Downloader = dl
 	}
 	c.Downloader.Init(config)
+	c.mu.Unlock()
+	c.session.reset(c.Name)
 }
 
-func (c *DownloadClient) Check(ctx context.Context, hash string) (string, error) {
-	if err := c.EnsureLogin(ctx); err != nil {
-		return "", fmt.Errorf("登录失败: %w", err)
-	}
+// backend 返回当前的下载器
+func (c *DownloadClient) backend() downloader.BaseDownloader {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Downloader
+}
+
+// Type 返回下载器类型
+func (c *DownloadClient) Type() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.downloaderType
+}
 
-	return c.Downloader.CheckHash(ctx, hash)
+// Session 返回当前的连接状态
+func (c *DownloadClient) Session() SessionStatus {
+	return c.session.snapshot()
+}
+
+// OnStateChange 注册连接状态变化回调
+func (c *DownloadClient) OnStateChange(fn SessionListener) {
+	c.session.subscribe(fn)
+}
+
+func (c *DownloadClient) Check(ctx context.Context, hash string) (string, error) {
+	return call(ctx, c, func(dl downloader.BaseDownloader) (string, error) {
+		return dl.CheckHash(ctx, hash)
+	})
 }
 
 // Login 登录（使用 singleflight 确保同一时间只有一个登录协程）
-// 如果是网络错误，自动重试；如果是认证错误，等待外部重新请求
+// 网络错误进入退避，退避结束后的下一次调用自动重试；认证错误需要修改配置后重新 Init
 func (c *DownloadClient) Login(ctx context.Context) error {
-	_, err, _ := c.loginGroup.Do("login", func() (any, error) {
-		_, err := c.Downloader.Auth(ctx)
-		if apperrors.IsDownloadAuthenticationError(err) || apperrors.IsDownloadForbiddenError(err) {
-			c.LoginError = true
-			return nil, apperrors.NewDownloadLoginError(
-				fmt.Errorf("下载客户端认证失败，请检查配置: %w", err))
-		}
-		if err != nil {
-			slog.Error("[download client]下载客户端登录失败，网络错误", "error", err)
-			return nil, err
-		}
-		return nil, nil
-	})
-	if err == nil {
-		// 登录成功，更新状态
-		c.logined = true
-	}
-	return err
+	return c.session.login(ctx, c.Name, c.backend().Auth)
 }
 
+// EnsureLogin 确保已登录，认证失败和退避期间直接返回错误而不请求下载器
 func (c *DownloadClient) EnsureLogin(ctx context.Context) error {
-	if c.LoginError {
-		return &apperrors.DownloadLoginError{Err: fmt.Errorf("下载器配置错误")}
-	}
-	if !c.logined {
-		return c.Login(ctx)
-	}
-	return nil
+	return c.session.ensure(ctx, c.Name, c.backend().Auth)
 }
 
-// Add 添加种子
-func (c *DownloadClient) Add(ctx context.Context, url, savePath string) ([]string, error) {
-	// 1. 确保已登录
+// call 登录后执行操作，登录失效时重新登录并重试一次
+func call[T any](ctx context.Context, c *DownloadClient, fn func(dl downloader.BaseDownloader) (T, error)) (T, error) {
+	var zero T
 	if err := c.EnsureLogin(ctx); err != nil {
-		return nil, fmt.Errorf("登录失败: %w", err)
+		return zero, fmt.Errorf("登录失败: %w", err)
+	}
+	result, err := fn(c.backend())
+	if err == nil || !isSessionExpired(err) {
+		return result, err
 	}
 
-	// 解析种子或磁力链接
-	var torrentInfo *model.TorrentInfo
-	var err error
-
-	if !strings.HasPrefix(url, "magnet:") {
-		// 下载种子文件
-		networkClient := network.GetRequestClient()
-		respBody, err := networkClient.Get(ctx, url)
-		if err != nil {
-			return nil, fmt.Errorf("[download client] 下载种子文件失败: %w", err)
-		}
-
-		// 解析种子文件
-		torrentInfo, err = ParseTorrent(respBody)
-		if err != nil {
-			return nil, fmt.Errorf("[download client] 解析种子文件失败: %w", err)
-		}
-	} else {
-		// 解析磁力链接
-		torrentInfo, err = ParseTorrentURL(url)
-		if err != nil {
-			return nil, fmt.Errorf("[download client] 解析磁力链接失败: %w", err)
-		}
+	slog.Info("[download client] 登录已失效，重新登录", "name", c.Name, "error", err)
+	c.session.expire(c.Name)
+	if err := c.EnsureLogin(ctx); err != nil {
+		return zero, fmt.Errorf("重新登录失败: %w", err)
 	}
+	// 重新登录期间下载器可能被重新配置，重试时使用当前的下载器
+	return fn(c.backend())
+}
 
-	// 3. 调用实际方法
-	hashs, err := c.Downloader.Add(ctx, torrentInfo, savePath)
-	// 4. 如果是认证错误，重置登录状态
-	if err != nil {
-		if apperrors.IsDownloadAuthenticationError(err) {
-			c.logined = false
-		}
-		return nil, err
+// Add 添加解析好的种子，tags 会附加到支持标签的下载器中
+// 种子文件由 LoadTorrent 获取，重试时可以直接使用缓存而不必重新下载
+func (c *DownloadClient) Add(ctx context.Context, torrentInfo *model.TorrentInfo, savePath string, tags ...string) ([]string, error) {
+	torrentInfo.Tags = tags
+	torrentInfo.SkipFiles = fileSelector.SkipIndexes(torrentInfo.Files)
+	if len(torrentInfo.SkipFiles) > 0 {
+		slog.Debug("[download client] 跳过种子内不需要的文件", "name", torrentInfo.Name, "count", len(torrentInfo.SkipFiles))
 	}
 	// TODO: 要对拿回来的 做一个 check, 会有很多情况, 比如有 v2但是qb 不认,所以这传回来的应该是个 list
 	// 然后通过一些可能的 check , 来确认到底是哪一个
-	// 5. check hash 拿到 真实的 hash
+	// check hash 拿到 真实的 hash
 	// duid, err := c.Downloader.CheckHash(url)
-
-	return hashs, err
+	return call(ctx, c, func(dl downloader.BaseDownloader) ([]string, error) {
+		return dl.Add(ctx, torrentInfo, savePath)
+	})
 }
 
-// Delete 删除种子
-func (c *DownloadClient) Delete(ctx context.Context, hashes []string) error {
-	if err := c.EnsureLogin(ctx); err != nil {
-		return fmt.Errorf("登录失败: %w", err)
-	}
+// Delete 删除种子，deleteFiles 为 false 时保留已下载的文件
+func (c *DownloadClient) Delete(ctx context.Context, hashes []string, deleteFiles bool) error {
+	_, err := call(ctx, c, func(dl downloader.BaseDownloader) (bool, error) {
+		return dl.Delete(ctx, hashes, deleteFiles)
+	})
+	return err
+}
 
-	_, err := c.Downloader.Delete(ctx, hashes)
-	if err != nil && apperrors.IsDownloadAuthenticationError(err) {
-		c.logined = false
+// Restart 重新开始下载失败的种子，下载器不支持时返回 false
+func (c *DownloadClient) Restart(ctx context.Context, hash string) (bool, error) {
+	if _, ok := c.backend().(downloader.Restarter); !ok {
+		return false, nil
 	}
-
-	return err
+	_, err := call(ctx, c, func(dl downloader.BaseDownloader) (struct{}, error) {
+		return struct{}{}, dl.(downloader.Restarter).Restart(ctx, hash)
+	})
+	return true, err
 }
 
 // Rename 重命名种子文件
 func (c *DownloadClient) Rename(ctx context.Context, hash, oldPath, newPath string) error {
-	if err := c.EnsureLogin(ctx); err != nil {
-		return fmt.Errorf("登录失败: %w", err)
-	}
-
-	_, err := c.Downloader.Rename(ctx, hash, oldPath, newPath)
-	if err != nil && apperrors.IsDownloadAuthenticationError(err) {
-		c.logined = false
-	}
-
+	_, err := call(ctx, c, func(dl downloader.BaseDownloader) (bool, error) {
+		return dl.Rename(ctx, hash, oldPath, newPath)
+	})
 	return err
 }
 
 // Move 移动种子
 func (c *DownloadClient) Move(ctx context.Context, hashes []string, location string) error {
-	if err := c.EnsureLogin(ctx); err != nil {
-		return fmt.Errorf("登录失败: %w", err)
-	}
-
-	_, err := c.Downloader.Move(ctx, hashes, location)
-	if err != nil && apperrors.IsDownloadAuthenticationError(err) {
-		c.logined = false
-	}
-
+	_, err := call(ctx, c, func(dl downloader.BaseDownloader) (bool, error) {
+		return dl.Move(ctx, hashes, location)
+	})
 	return err
 }
 
 // GetTorrentFiles 获取种子文件列表
 func (c *DownloadClient) GetTorrentFiles(ctx context.Context, hash string) ([]string, error) {
-	if err := c.EnsureLogin(ctx); err != nil {
-		return nil, fmt.Errorf("登录失败: %w", err)
-	}
-	return c.Downloader.GetTorrentFiles(ctx, hash)
+	return call(ctx, c, func(dl downloader.BaseDownloader) ([]string, error) {
+		return dl.GetTorrentFiles(ctx, hash)
+	})
 }
 
-func (c *DownloadClient) GetTorrentInfo(ctx context.Context, hash string) (*model.TorrentDownloadInfo, error) {
-	if err := c.EnsureLogin(ctx); err != nil {
-		return nil, fmt.Errorf("登录失败: %w", err)
-	}
-	return c.Downloader.GetTorrentInfo(ctx, hash)
+func (c *DownloadClient) GetTorrentInfo(ctx context.Context, hash string) (*model.TorrentStatus, error) {
+	return call(ctx, c, func(dl downloader.BaseDownloader) (*model.TorrentStatus, error) {
+		return dl.GetTorrentInfo(ctx, hash)
+	})
 }
 
-// TorrentsInfo 获取种子信息列表
-func (c *DownloadClient) TorrentsInfo(ctx context.Context, statusFilter, category string, tag *string, limit int) ([]map[string]any, error) {
-	if err := c.EnsureLogin(ctx); err != nil {
-		return nil, fmt.Errorf("登录失败: %w", err)
-	}
+// GetTorrentsInfo 批量获取种子信息
+func (c *DownloadClient) GetTorrentsInfo(ctx context.Context, hashes []string) (map[string]*model.TorrentStatus, error) {
+	return call(ctx, c, func(dl downloader.BaseDownloader) (map[string]*model.TorrentStatus, error) {
+		return dl.GetTorrentsInfo(ctx, hashes)
+	})
+}
 
-	return c.Downloader.TorrentsInfo(ctx, statusFilter, category, tag, limit)
+// TorrentsInfo 获取种子信息列表
+func (c *DownloadClient) TorrentsInfo(ctx context.Context, statusFilter, category string, tag *string, limit int) ([]*model.TorrentStatus, error) {
+	return call(ctx, c, func(dl downloader.BaseDownloader) ([]*model.TorrentStatus, error) {
+		return dl.TorrentsInfo(ctx, statusFilter, category, tag, limit)
+	})
 }
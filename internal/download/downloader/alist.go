@@ -1,3 +1,776 @@
 package downloader
 
-// TODO: Alist downloader implementation pending
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"goto-bangumi/internal/apperrors"
+	"goto-bangumi/internal/model"
+
+	"github.com/go-resty/resty/v2"
+)
+
+/*
+Alist 的几点说明
+1: 配置了 Token 时直接使用，否则用用户名密码登录拿 token，请求头 Authorization 直接放 token，不带 Bearer
+2: 离线下载只能提交 URL，所以和 CloudDrive 一样只用磁力链接，下载工具由配置的 offline_tool 决定
+3: Alist 用任务 ID 标识离线下载，和 CloudDrive 用 info hash 找离线文件一样，这里用 hash 作为 key，
+   记录 hash 对应的任务和保存目录，同时写到 StateDir 下按任务 ID 命名的文件，重启后从这里找回，
+   转移过的任务也能找到新的目录；没有记录时通过任务名里的 btih 和目录找回来
+4: 文件路径都相对于任务的保存目录
+5: 同一季的任务共用保存目录，添加时记录任务自己的顶层文件或目录，列出、移动和删除都只处理这些；
+   没有任务记录时用任务名里磁力链接的 dn 找回
+*/
+
+// AlistAPI Alist v3 API URL 定义
+var AlistAPI = map[string]string{
+	"login":      "/api/auth/login",
+	"me":         "/api/me",
+	"addOffline": "/api/fs/add_offline_download",
+	"undone":     "/api/task/offline_download/undone",
+	"done":       "/api/task/offline_download/done",
+	"deleteTask": "/api/task/offline_download/delete",
+	"list":       "/api/fs/list",
+	"mkdir":      "/api/fs/mkdir",
+	"rename":     "/api/fs/rename",
+	"move":       "/api/fs/move",
+	"remove":     "/api/fs/remove",
+}
+
+var (
+	alistBtihRe   = regexp.MustCompile(`(?i)btih:([0-9a-z]+)`)
+	alistDirRe    = regexp.MustCompile(`to \[?[^\]]*\]?\((.+)\)$`)
+	alistMagnetRe = regexp.MustCompile(`magnet:\?\S+`)
+)
+
+// AlistDownloader Alist v3 离线下载实现
+type AlistDownloader struct {
+	client      *resty.Client
+	config      *model.DownloaderConfig
+	APIInterval int // API 调用间隔（毫秒）
+	limiter     *apiLimiter
+
+	// StateDir 保存任务记录的目录
+	StateDir string
+
+	mu    sync.RWMutex
+	token string
+	tasks map[string]*alistTask // key 为小写的 info hash，同一任务的 v1、v2 hash 共用一条记录
+}
+
+// alistTask hash 对应的离线下载任务
+type alistTask struct {
+	ID     string   `json:"id"`
+	Hashes []string `json:"hashes"` // 小写的 info hash
+	Dir    string   `json:"dir"`    // 保存目录
+	Names  []string `json:"names"`  // 任务在保存目录下的顶层文件或目录
+}
+
+// NewAlistDownloader 创建新的 Alist 下载器
+func NewAlistDownloader() *AlistDownloader {
+	return &AlistDownloader{
+		client:      resty.New(),
+		APIInterval: 200,
+		limiter:     newAPILimiter(200 * time.Millisecond),
+		StateDir:    filepath.Join("data", "alist"),
+		tasks:       make(map[string]*alistTask),
+	}
+}
+
+// Init 初始化下载器
+func (d *AlistDownloader) Init(config *model.DownloaderConfig) error {
+	if config == nil {
+		return fmt.Errorf("配置不能为空")
+	}
+	d.config = config
+	if d.limiter == nil {
+		d.limiter = newAPILimiter(time.Duration(d.APIInterval) * time.Millisecond)
+	} else {
+		d.limiter.SetInterval(time.Duration(d.APIInterval) * time.Millisecond)
+	}
+
+	d.client.SetBaseURL(hostURL(config))
+	d.client.SetTimeout(10 * time.Second)
+	d.client.SetHeader("User-Agent", "goto-bangumi")
+	if !config.Ssl {
+		d.client.SetTLSClientConfig(&tls.Config{
+			InsecureSkipVerify: true,
+		})
+	}
+	d.restore()
+	return nil
+}
+
+// restore 从 StateDir 恢复任务记录
+func (d *AlistDownloader) restore() {
+	entries, err := os.ReadDir(d.StateDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			slog.Warn("[Alist] 读取任务记录目录失败", "dir", d.StateDir, "error", err)
+		}
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(d.StateDir, e.Name()))
+		if err != nil {
+			continue
+		}
+		at := &alistTask{}
+		if err := json.Unmarshal(data, at); err != nil || at.ID == "" {
+			slog.Warn("[Alist] 解析任务记录失败", "file", e.Name(), "error", err)
+			continue
+		}
+		for _, hash := range at.Hashes {
+			d.tasks[hash] = at
+		}
+	}
+}
+
+// saveTask 写入任务记录，调用方持有 d.mu
+func (d *AlistDownloader) saveTask(at *alistTask) {
+	data, err := json.Marshal(at)
+	if err == nil {
+		err = os.MkdirAll(d.StateDir, 0o755)
+	}
+	if err == nil {
+		err = os.WriteFile(d.statePath(at.ID), data, 0o644)
+	}
+	if err != nil {
+		slog.Warn("[Alist] 保存任务记录失败，重启后无法找回", "id", at.ID, "error", err)
+	}
+}
+
+// statePath 任务记录的文件，任务 ID 由 Alist 生成，转义后作为文件名
+func (d *AlistDownloader) statePath(id string) string {
+	return filepath.Join(d.StateDir, url.PathEscape(id)+".json")
+}
+
+func (d *AlistDownloader) wait(ctx context.Context) error {
+	if d.limiter == nil {
+		return nil
+	}
+	return d.limiter.Wait(ctx, "Alist")
+}
+
+// request 发送请求并解析统一响应，body 为 nil 时使用 GET
+func (d *AlistDownloader) request(ctx context.Context, api string, body any, data any) error {
+	if err := d.wait(ctx); err != nil {
+		return err
+	}
+	d.mu.RLock()
+	token := d.token
+	d.mu.RUnlock()
+
+	req := d.client.R().SetContext(ctx).SetHeader("Authorization", token)
+	var resp *resty.Response
+	var err error
+	if body != nil {
+		resp, err = req.SetBody(body).Post(AlistAPI[api])
+	} else {
+		resp, err = req.Get(AlistAPI[api])
+	}
+	if err != nil {
+		slog.Error("[Alist] 连接错误", "api", api, "error", err)
+		return &apperrors.NetworkError{Err: fmt.Errorf("连接到Alist时出错: %w", err)}
+	}
+
+	var result model.AlistResponse[json.RawMessage]
+	if err := json.Unmarshal(resp.Body(), &result); err != nil {
+		return &apperrors.NetworkError{
+			Err:        fmt.Errorf("解析 %s 响应失败：状态码 %d: %w", api, resp.StatusCode(), err),
+			StatusCode: resp.StatusCode(),
+		}
+	}
+	switch result.Code {
+	case 200:
+	case 401:
+		return &apperrors.DownloadAuthenticationError{Err: fmt.Errorf("%s", result.Message), Name: d.config.Username}
+	case 403:
+		return &apperrors.DownloadForbiddenError{Err: fmt.Errorf("%s", result.Message)}
+	default:
+		if strings.Contains(result.Message, "not found") {
+			return &apperrors.DownloadKeyError{Err: fmt.Errorf("%s", result.Message), Key: api}
+		}
+		return fmt.Errorf("[Alist] %s 失败: %s", api, result.Message)
+	}
+	if data != nil && len(result.Data) > 0 && string(result.Data) != "null" {
+		if err := json.Unmarshal(result.Data, data); err != nil {
+			return fmt.Errorf("解析 %s 响应失败: %w", api, err)
+		}
+	}
+	return nil
+}
+
+// Auth 配置了 Token 时直接校验，否则用用户名密码登录
+func (d *AlistDownloader) Auth(ctx context.Context) (bool, error) {
+	if d.config.Token != "" {
+		d.mu.Lock()
+		d.token = d.config.Token
+		d.mu.Unlock()
+		if err := d.request(ctx, "me", nil, nil); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
+	var data struct {
+		Token string `json:"token"`
+	}
+	err := d.request(ctx, "login", map[string]string{
+		"username": d.config.Username,
+		"password": d.config.Password,
+	}, &data)
+	if err != nil {
+		// 登录接口用 400 表示密码错误
+		if !apperrors.IsNetworkError(err) && !apperrors.IsDownloadForbiddenError(err) && !apperrors.IsDownloadAuthenticationError(err) {
+			slog.Error("[Alist] 登录失败，请检查用户名/密码", "username", d.config.Username)
+			return false, &apperrors.DownloadAuthenticationError{Err: err, Name: d.config.Username}
+		}
+		return false, err
+	}
+	d.mu.Lock()
+	d.token = data.Token
+	d.mu.Unlock()
+	return true, nil
+}
+
+// Logout 清掉 token
+func (d *AlistDownloader) Logout(ctx context.Context) (bool, error) {
+	d.mu.Lock()
+	d.token = ""
+	d.mu.Unlock()
+	return true, nil
+}
+
+// listTasks 列出未完成和已完成的离线下载任务
+func (d *AlistDownloader) listTasks(ctx context.Context) ([]model.AlistTask, error) {
+	var undone, done []model.AlistTask
+	if err := d.request(ctx, "undone", nil, &undone); err != nil {
+		return nil, err
+	}
+	if err := d.request(ctx, "done", nil, &done); err != nil {
+		return nil, err
+	}
+	return append(undone, done...), nil
+}
+
+// taskHash 从任务名中取出 info hash
+func taskHash(name string) string {
+	if m := alistBtihRe.FindStringSubmatch(name); m != nil {
+		return strings.ToLower(m[1])
+	}
+	return ""
+}
+
+// magnetName 从任务名里的磁力链接取出 dn，也就是种子的名称
+func magnetName(name string) string {
+	m := alistMagnetRe.FindString(name)
+	if m == "" {
+		return ""
+	}
+	u, err := url.Parse(m)
+	if err != nil {
+		return ""
+	}
+	return u.Query().Get("dn")
+}
+
+// entryNames 种子在保存目录下的顶层文件或目录：有文件列表时取每个文件路径的第一段，否则是种子名称
+func entryNames(torrentInfo *model.TorrentInfo) []string {
+	var names []string
+	for _, f := range torrentInfo.Files {
+		name, _, _ := strings.Cut(f.Path, "/")
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 && torrentInfo.Name != "" {
+		names = append(names, torrentInfo.Name)
+	}
+	return names
+}
+
+// findTasks 把 hash 对应到任务，没有记录的通过任务名找回
+func (d *AlistDownloader) findTasks(ctx context.Context, hashes []string) (map[string]*model.AlistTask, error) {
+	tasks, err := d.listTasks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*model.AlistTask, len(tasks))
+	byHash := make(map[string]*model.AlistTask, len(tasks))
+	for i := range tasks {
+		t := &tasks[i]
+		byID[t.ID] = t
+		if h := taskHash(t.Name); h != "" {
+			// 同一个 hash 重复提交时以最后一个为准
+			byHash[h] = t
+		}
+	}
+
+	result := make(map[string]*model.AlistTask, len(hashes))
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, hash := range hashes {
+		key := strings.ToLower(hash)
+		at, ok := d.tasks[key]
+		if ok {
+			if t, ok := byID[at.ID]; ok {
+				result[hash] = t
+				continue
+			}
+		}
+		t, found := byHash[key]
+		if !found {
+			continue
+		}
+		result[hash] = t
+		if ok {
+			// 同一个 hash 重新提交过，保留记录里的目录和文件，只更新任务 ID
+			if err := os.Remove(d.statePath(at.ID)); err != nil && !os.IsNotExist(err) {
+				slog.Debug("[Alist] 删除任务记录失败", "id", at.ID, "error", err)
+			}
+			at.ID = t.ID
+			d.saveTask(at)
+			continue
+		}
+		dir := d.config.SavePath
+		if m := alistDirRe.FindStringSubmatch(t.Name); m != nil {
+			dir = m[1]
+		}
+		at = &alistTask{ID: t.ID, Hashes: []string{key}, Dir: dir}
+		if name := magnetName(t.Name); name != "" {
+			at.Names = []string{name}
+		}
+		d.tasks[key] = at
+	}
+	return result, nil
+}
+
+// task 返回任务的保存目录和顶层文件
+func (d *AlistDownloader) task(ctx context.Context, hash string) (alistTask, error) {
+	key := strings.ToLower(hash)
+	d.mu.RLock()
+	at, ok := d.tasks[key]
+	d.mu.RUnlock()
+	if ok {
+		return d.snapshot(at), nil
+	}
+	tasks, err := d.findTasks(ctx, []string{hash})
+	if err != nil {
+		return alistTask{}, err
+	}
+	if _, ok := tasks[hash]; !ok {
+		return alistTask{}, &apperrors.DownloadKeyError{Err: fmt.Errorf("离线任务不存在"), Key: hash}
+	}
+	return d.snapshot(d.tasks[key]), nil
+}
+
+// snapshot 在锁内复制任务记录
+func (d *AlistDownloader) snapshot(at *alistTask) alistTask {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return alistTask{ID: at.ID, Hashes: slices.Clone(at.Hashes), Dir: at.Dir, Names: slices.Clone(at.Names)}
+}
+
+// alistInfo 转换为统一的种子状态，Alist 只提供进度和状态
+func alistInfo(hash string, t *model.AlistTask, dir string) *model.TorrentStatus {
+	info := &model.TorrentStatus{
+		Hash:        hash,
+		Name:        t.Name,
+		State:       alistState(t),
+		Message:     t.Error,
+		Progress:    t.Progress / 100,
+		ETA:         -1,
+		Ratio:       -1,
+		SeedingTime: -1,
+		SavePath:    dir,
+	}
+	if info.State != model.DownloadStateFailed && info.Message == "" {
+		info.Message = t.Status
+	}
+	if t.State == model.AlistTaskSucceeded {
+		info.ETA = 0
+		info.Progress = 1
+		info.Completed = int(time.Now().Unix())
+	}
+	return info
+}
+
+// alistState 归一化 Alist 的任务状态
+func alistState(t *model.AlistTask) model.DownloadState {
+	switch t.State {
+	case model.AlistTaskPending:
+		return model.DownloadStateWaiting
+	case model.AlistTaskRunning:
+		return model.DownloadStateDownloading
+	case model.AlistTaskSucceeded:
+		return model.DownloadStateCompleted
+	case model.AlistTaskCanceling, model.AlistTaskCanceled, model.AlistTaskErrored, model.AlistTaskFailing, model.AlistTaskFailed:
+		return model.DownloadStateFailed
+	}
+	return model.DownloadStateUnknown
+}
+
+// GetTorrentInfo 获取单个种子的离线下载进度
+func (d *AlistDownloader) GetTorrentInfo(ctx context.Context, hash string) (*model.TorrentStatus, error) {
+	infos, err := d.GetTorrentsInfo(ctx, []string{hash})
+	if err != nil {
+		return nil, err
+	}
+	info, ok := infos[hash]
+	if !ok {
+		return nil, &apperrors.DownloadKeyError{Err: fmt.Errorf("离线任务不存在"), Key: hash}
+	}
+	return info, nil
+}
+
+// GetTorrentsInfo 一次列出全部任务并按 hash 匹配
+func (d *AlistDownloader) GetTorrentsInfo(ctx context.Context, hashes []string) (map[string]*model.TorrentStatus, error) {
+	result := make(map[string]*model.TorrentStatus, len(hashes))
+	if len(hashes) == 0 {
+		return result, nil
+	}
+	tasks, err := d.findTasks(ctx, hashes)
+	if err != nil {
+		return nil, err
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for hash, t := range tasks {
+		result[hash] = alistInfo(hash, t, d.tasks[strings.ToLower(hash)].Dir)
+	}
+	return result, nil
+}
+
+// TorrentsInfo 获取离线任务列表，Alist 不支持分类和标签
+func (d *AlistDownloader) TorrentsInfo(ctx context.Context, statusFilter, category string, tag *string, limit int) ([]*model.TorrentStatus, error) {
+	tasks, err := d.listTasks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	result := make([]*model.TorrentStatus, 0, len(tasks))
+	for i := range tasks {
+		t := &tasks[i]
+		done := t.State == model.AlistTaskSucceeded
+		if (statusFilter == "completed" && !done) || (statusFilter == "downloading" && done) {
+			continue
+		}
+		hash := taskHash(t.Name)
+		var dir string
+		if local, ok := d.tasks[strings.ToLower(hash)]; ok {
+			dir = local.Dir
+		}
+		result = append(result, alistInfo(hash, t, dir))
+		if limit > 0 && len(result) >= limit {
+			break
+		}
+	}
+	return result, nil
+}
+
+// CheckHash 检查离线任务是否存在
+func (d *AlistDownloader) CheckHash(ctx context.Context, hash string) (string, error) {
+	info, err := d.GetTorrentInfo(ctx, hash)
+	if info != nil {
+		return hash, nil
+	}
+	return hash, err
+}
+
+// Add 提交离线下载，只支持磁力链接
+func (d *AlistDownloader) Add(ctx context.Context, torrentInfo *model.TorrentInfo, savePath string) ([]string, error) {
+	if torrentInfo.MagnetURI == "" {
+		return nil, fmt.Errorf("[Alist] 离线下载需要磁力链接")
+	}
+	dir := savePath
+	if !path.IsAbs(dir) {
+		dir = path.Join(d.config.SavePath, savePath)
+	}
+
+	var data struct {
+		Tasks []model.AlistTask `json:"tasks"`
+	}
+	err := d.request(ctx, "addOffline", map[string]any{
+		"path":          dir,
+		"urls":          []string{torrentInfo.MagnetURI},
+		"tool":          d.config.OfflineTool,
+		"delete_policy": "delete_on_upload_succeed",
+	}, &data)
+	if err != nil {
+		return nil, err
+	}
+	if len(data.Tasks) == 0 {
+		return nil, fmt.Errorf("[Alist] 添加离线下载失败: 没有返回任务")
+	}
+
+	hashes := make([]string, 0, 2)
+	if torrentInfo.InfoHashV1 != "" {
+		hashes = append(hashes, torrentInfo.InfoHashV1)
+	}
+	if torrentInfo.InfoHashV2 != "" {
+		v2Hash := torrentInfo.InfoHashV2
+		if len(v2Hash) > 40 {
+			v2Hash = v2Hash[:40]
+		}
+		hashes = append(hashes, v2Hash)
+	}
+	at := &alistTask{ID: data.Tasks[0].ID, Dir: dir, Names: entryNames(torrentInfo)}
+	for _, hash := range hashes {
+		at.Hashes = append(at.Hashes, strings.ToLower(hash))
+	}
+	d.mu.Lock()
+	for _, hash := range at.Hashes {
+		d.tasks[hash] = at
+	}
+	d.saveTask(at)
+	d.mu.Unlock()
+	return hashes, nil
+}
+
+// listDir 递归列出目录下的文件，返回相对 root 的路径
+func (d *AlistDownloader) listDir(ctx context.Context, root, rel string) ([]string, error) {
+	var data struct {
+		Content []model.AlistObject `json:"content"`
+	}
+	err := d.request(ctx, "list", map[string]any{
+		"path":     path.Join(root, rel),
+		"page":     1,
+		"per_page": 0,
+		"refresh":  rel == "",
+	}, &data)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, obj := range data.Content {
+		name := path.Join(rel, obj.Name)
+		if !obj.IsDir {
+			files = append(files, name)
+			continue
+		}
+		sub, err := d.listDir(ctx, root, name)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, sub...)
+	}
+	return files, nil
+}
+
+// GetTorrentFiles 列出任务在保存目录下的文件
+func (d *AlistDownloader) GetTorrentFiles(ctx context.Context, hash string) ([]string, error) {
+	at, err := d.task(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := d.taskEntries(ctx, at)
+	if err != nil {
+		// 上传还没完成时目录可能不存在
+		slog.Warn("[Alist] 获取文件列表失败", "path", at.Dir, "error", err)
+		return nil, nil
+	}
+	var files []string
+	for _, obj := range entries {
+		if !obj.IsDir {
+			files = append(files, obj.Name)
+			continue
+		}
+		sub, err := d.listDir(ctx, at.Dir, obj.Name)
+		if err != nil {
+			slog.Warn("[Alist] 获取文件列表失败", "path", path.Join(at.Dir, obj.Name), "error", err)
+			return nil, nil
+		}
+		files = append(files, sub...)
+	}
+	return files, nil
+}
+
+// Rename 重命名文件，只修改文件名，不会跨目录
+func (d *AlistDownloader) Rename(ctx context.Context, torrentHash, oldPath, newPath string) (bool, error) {
+	at, err := d.task(ctx, torrentHash)
+	if err != nil {
+		return false, err
+	}
+	err = d.request(ctx, "rename", map[string]string{
+		"path": path.Join(at.Dir, oldPath),
+		"name": path.Base(newPath),
+	}, nil)
+	if err != nil {
+		slog.Error("[Alist] 重命名错误", "oldPath", oldPath, "newPath", newPath, "error", err)
+		return false, err
+	}
+	// 单文件的种子直接放在保存目录下，改名后顶层文件名也跟着变
+	if !strings.Contains(oldPath, "/") {
+		d.mu.Lock()
+		if local, ok := d.tasks[strings.ToLower(torrentHash)]; ok {
+			if i := slices.Index(local.Names, oldPath); i >= 0 {
+				local.Names[i] = path.Base(newPath)
+				d.saveTask(local)
+			}
+		}
+		d.mu.Unlock()
+	}
+	return true, nil
+}
+
+// Move 把任务在保存目录下的文件移动到新目录
+func (d *AlistDownloader) Move(ctx context.Context, hashes []string, newLocation string) (bool, error) {
+	if err := d.request(ctx, "mkdir", map[string]string{"path": newLocation}, nil); err != nil {
+		return false, err
+	}
+	for _, hash := range hashes {
+		at, err := d.task(ctx, hash)
+		if err != nil {
+			return false, err
+		}
+		names, err := d.entryNames(ctx, at)
+		if err != nil {
+			return false, err
+		}
+		if len(names) == 0 {
+			return false, &apperrors.DownloadKeyError{Err: fmt.Errorf("保存目录 %s 下没有找到任务的文件", at.Dir), Key: hash}
+		}
+		err = d.request(ctx, "move", map[string]any{
+			"src_dir": at.Dir,
+			"dst_dir": newLocation,
+			"names":   names,
+		}, nil)
+		if err != nil {
+			return false, err
+		}
+		// v1、v2 hash 共用同一条记录，一起更新
+		d.mu.Lock()
+		local := d.tasks[strings.ToLower(hash)]
+		local.Dir = newLocation
+		d.saveTask(local)
+		d.mu.Unlock()
+	}
+	return true, nil
+}
+
+// taskEntries 列出保存目录下属于任务的顶层文件和目录，同一目录下其他任务的文件不会列出
+func (d *AlistDownloader) taskEntries(ctx context.Context, at alistTask) ([]model.AlistObject, error) {
+	var data struct {
+		Content []model.AlistObject `json:"content"`
+	}
+	err := d.request(ctx, "list", map[string]any{
+		"path":     at.Dir,
+		"page":     1,
+		"per_page": 0,
+		"refresh":  true,
+	}, &data)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]model.AlistObject, 0, len(at.Names))
+	for _, obj := range data.Content {
+		if slices.Contains(at.Names, obj.Name) {
+			entries = append(entries, obj)
+		}
+	}
+	return entries, nil
+}
+
+// entryNames 保存目录下属于任务的顶层文件和目录的名称
+func (d *AlistDownloader) entryNames(ctx context.Context, at alistTask) ([]string, error) {
+	entries, err := d.taskEntries(ctx, at)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(entries))
+	for _, obj := range entries {
+		names = append(names, obj.Name)
+	}
+	return names, nil
+}
+
+// Delete 删除离线任务，deleteFiles 为 true 时同时删除任务在保存目录下的文件
+func (d *AlistDownloader) Delete(ctx context.Context, hashes []string, deleteFiles bool) (bool, error) {
+	tasks, err := d.findTasks(ctx, hashes)
+	if err != nil {
+		return false, err
+	}
+	for _, hash := range hashes {
+		t, ok := tasks[hash]
+		if !ok {
+			continue
+		}
+		if err := d.deleteTask(ctx, t.ID); err != nil {
+			slog.Warn("[Alist] 删除离线任务失败", "hash", hash, "error", err)
+		}
+
+		key := strings.ToLower(hash)
+		if deleteFiles {
+			at, err := d.task(ctx, hash)
+			if err != nil {
+				return false, err
+			}
+			names, err := d.entryNames(ctx, at)
+			if err != nil {
+				slog.Warn("[Alist] 获取文件列表失败", "path", at.Dir, "error", err)
+			} else if len(names) > 0 {
+				err = d.request(ctx, "remove", map[string]any{"dir": at.Dir, "names": names}, nil)
+				if err != nil {
+					return false, err
+				}
+			}
+		}
+
+		d.mu.Lock()
+		if at, ok := d.tasks[key]; ok {
+			for _, h := range at.Hashes {
+				delete(d.tasks, h)
+			}
+			if err := os.Remove(d.statePath(at.ID)); err != nil && !os.IsNotExist(err) {
+				slog.Debug("[Alist] 删除任务记录失败", "id", at.ID, "error", err)
+			}
+		}
+		delete(d.tasks, key)
+		d.mu.Unlock()
+	}
+	return true, nil
+}
+
+// deleteTask 删除离线下载任务记录，任务 ID 通过 query 参数传递
+func (d *AlistDownloader) deleteTask(ctx context.Context, id string) error {
+	if err := d.wait(ctx); err != nil {
+		return err
+	}
+	d.mu.RLock()
+	token := d.token
+	d.mu.RUnlock()
+	var result model.AlistResponse[json.RawMessage]
+	resp, err := d.client.R().
+		SetContext(ctx).
+		SetHeader("Authorization", token).
+		SetQueryParam("tid", id).
+		SetResult(&result).
+		Post(AlistAPI["deleteTask"])
+	if err != nil {
+		return &apperrors.NetworkError{Err: fmt.Errorf("连接到Alist时出错: %w", err)}
+	}
+	if result.Code != 200 {
+		return fmt.Errorf("[Alist] 删除任务失败: 状态码 %d: %s", resp.StatusCode(), result.Message)
+	}
+	return nil
+}
@@ -0,0 +1,276 @@
+package downloader
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"goto-bangumi/internal/apperrors"
+	"goto-bangumi/internal/model"
+)
+
+// fakeAlist 进程内的 Alist v3 服务，文件系统只记录文件的完整路径
+type fakeAlist struct {
+	mu      sync.Mutex
+	files   map[string]bool
+	tasks   []model.AlistTask
+	lastAdd map[string]any
+}
+
+func newFakeAlist(t *testing.T) (*fakeAlist, *httptest.Server) {
+	t.Helper()
+	f := &fakeAlist{files: make(map[string]bool)}
+	srv := httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func (f *fakeAlist) serve(w http.ResponseWriter, r *http.Request) {
+	reply := func(code int, message string, data any) {
+		json.NewEncoder(w).Encode(map[string]any{"code": code, "message": message, "data": data})
+	}
+	var body map[string]any
+	json.NewDecoder(r.Body).Decode(&body)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.URL.Path == AlistAPI["login"] {
+		if body["username"] != "admin" || body["password"] != "alist" {
+			reply(400, "password is incorrect", nil)
+			return
+		}
+		reply(200, "success", map[string]string{"token": "token-1"})
+		return
+	}
+	if r.Header.Get("Authorization") != "token-1" {
+		reply(401, "that's not even a token", nil)
+		return
+	}
+
+	switch r.URL.Path {
+	case AlistAPI["me"]:
+		reply(200, "success", map[string]string{"username": "admin"})
+	case AlistAPI["addOffline"]:
+		f.lastAdd = body
+		url := body["urls"].([]any)[0].(string)
+		task := model.AlistTask{
+			ID:   "task-1",
+			Name: "download " + url + " to (" + body["path"].(string) + ")",
+		}
+		f.tasks = append(f.tasks, task)
+		reply(200, "success", map[string]any{"tasks": []model.AlistTask{task}})
+	case AlistAPI["undone"], AlistAPI["done"]:
+		done := r.URL.Path == AlistAPI["done"]
+		tasks := []model.AlistTask{}
+		for _, t := range f.tasks {
+			if (t.State == model.AlistTaskSucceeded || t.State == model.AlistTaskFailed) == done {
+				tasks = append(tasks, t)
+			}
+		}
+		reply(200, "success", tasks)
+	case AlistAPI["deleteTask"]:
+		tid := r.URL.Query().Get("tid")
+		for i, t := range f.tasks {
+			if t.ID == tid {
+				f.tasks = append(f.tasks[:i], f.tasks[i+1:]...)
+				reply(200, "success", nil)
+				return
+			}
+		}
+		reply(500, "task not found", nil)
+	case AlistAPI["list"]:
+		dir := body["path"].(string)
+		seen := map[string]bool{}
+		content := []model.AlistObject{}
+		for p := range f.files {
+			rel, ok := strings.CutPrefix(p, dir+"/")
+			if !ok {
+				continue
+			}
+			name, _, isDir := strings.Cut(rel, "/")
+			if !seen[name] {
+				seen[name] = true
+				content = append(content, model.AlistObject{Name: name, IsDir: isDir})
+			}
+		}
+		if len(content) == 0 {
+			reply(500, "object not found", nil)
+			return
+		}
+		sort.Slice(content, func(i, j int) bool { return content[i].Name < content[j].Name })
+		reply(200, "success", map[string]any{"content": content, "total": len(content)})
+	case AlistAPI["mkdir"]:
+		reply(200, "success", nil)
+	case AlistAPI["rename"]:
+		old := body["path"].(string)
+		if !f.files[old] {
+			reply(500, "object not found", nil)
+			return
+		}
+		delete(f.files, old)
+		f.files[path.Join(path.Dir(old), body["name"].(string))] = true
+		reply(200, "success", nil)
+	case AlistAPI["move"], AlistAPI["remove"]:
+		src := body["src_dir"]
+		if src == nil {
+			src = body["dir"]
+		}
+		for _, n := range body["names"].([]any) {
+			prefix := path.Join(src.(string), n.(string))
+			for p := range f.files {
+				if p != prefix && !strings.HasPrefix(p, prefix+"/") {
+					continue
+				}
+				delete(f.files, p)
+				if dst, ok := body["dst_dir"].(string); ok {
+					f.files[path.Join(dst, strings.TrimPrefix(p, src.(string)+"/"))] = true
+				}
+			}
+		}
+		reply(200, "success", nil)
+	default:
+		reply(404, "not found", nil)
+	}
+}
+
+func newTestAlist(t *testing.T, host, password string) *AlistDownloader {
+	t.Helper()
+	d := NewAlistDownloader()
+	d.APIInterval = 0
+	d.StateDir = t.TempDir()
+	err := d.Init(&model.DownloaderConfig{
+		Type:        "alist",
+		SavePath:    "/local/Bangumi",
+		Host:        host,
+		Username:    "admin",
+		Password:    password,
+		OfflineTool: "aria2",
+	})
+	if err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	return d
+}
+
+func TestAlist_Auth(t *testing.T) {
+	_, srv := newFakeAlist(t)
+	ctx := context.Background()
+
+	_, err := newTestAlist(t, srv.URL, "wrong").Auth(ctx)
+	if !apperrors.IsDownloadAuthenticationError(err) {
+		t.Fatalf("Auth() with wrong password error = %v, want DownloadAuthenticationError", err)
+	}
+
+	d := newTestAlist(t, srv.URL, "alist")
+	if _, err := d.GetTorrentInfo(ctx, "hash"); !apperrors.IsDownloadAuthenticationError(err) {
+		t.Fatalf("request before login error = %v, want DownloadAuthenticationError", err)
+	}
+	if ok, err := d.Auth(ctx); err != nil || !ok {
+		t.Fatalf("Auth() = %v, %v, want true", ok, err)
+	}
+
+	d = newTestAlist(t, srv.URL, "")
+	d.config.Token = "token-1"
+	if ok, err := d.Auth(ctx); err != nil || !ok {
+		t.Fatalf("Auth() with token = %v, %v, want true", ok, err)
+	}
+}
+
+func TestAlist_TorrentLifecycle(t *testing.T) {
+	f, srv := newFakeAlist(t)
+	d := newTestAlist(t, srv.URL, "alist")
+	ctx := context.Background()
+	if _, err := d.Auth(ctx); err != nil {
+		t.Fatalf("Auth error: %v", err)
+	}
+	hash := "1317e47882474c771e29ed2271b282fbfb56e7d2"
+	magnet := "magnet:?xt=urn:btih:" + hash + "&dn=%5BLilith-Raws%5D+Frieren"
+
+	if _, err := d.Add(ctx, &model.TorrentInfo{InfoHashV1: hash, File: []byte("d4:infoe")}, "Frieren/Season 1"); err == nil {
+		t.Fatal("Add without magnet should fail")
+	}
+	hashes, err := d.Add(ctx, &model.TorrentInfo{InfoHashV1: hash, MagnetURI: magnet}, "Frieren/Season 1")
+	if err != nil {
+		t.Fatalf("Add error: %v", err)
+	}
+	if len(hashes) != 1 || hashes[0] != hash {
+		t.Fatalf("Add hashes = %v", hashes)
+	}
+	if f.lastAdd["path"] != "/local/Bangumi/Frieren/Season 1" || f.lastAdd["tool"] != "aria2" {
+		t.Errorf("add_offline_download body = %v", f.lastAdd)
+	}
+
+	info, err := d.GetTorrentInfo(ctx, hash)
+	if err != nil || info.ETA != -1 || info.Completed != 0 {
+		t.Fatalf("GetTorrentInfo = %+v, %v", info, err)
+	}
+	if _, err := d.GetTorrentInfo(ctx, "missing"); !apperrors.IsKeyError(err) {
+		t.Fatalf("GetTorrentInfo(missing) error = %v, want key error", err)
+	}
+
+	f.mu.Lock()
+	f.tasks[0].State = model.AlistTaskSucceeded
+	f.files["/local/Bangumi/Frieren/Season 1/[Lilith-Raws] Frieren/[Lilith-Raws] Frieren - 01.mkv"] = true
+	// 同一季的其他任务也下载到这个目录
+	f.files["/local/Bangumi/Frieren/Season 1/Frieren S01E02.mkv"] = true
+	f.mu.Unlock()
+
+	// 新实例没有内存记录，需要通过任务名找回任务和目录
+	d = newTestAlist(t, srv.URL, "alist")
+	d.Auth(ctx)
+	infos, err := d.GetTorrentsInfo(ctx, []string{hash, "missing"})
+	if err != nil || len(infos) != 1 || infos[hash].Completed == 0 || infos[hash].SavePath != "/local/Bangumi/Frieren/Season 1" {
+		t.Fatalf("GetTorrentsInfo = %+v, %v", infos, err)
+	}
+
+	files, err := d.GetTorrentFiles(ctx, hash)
+	if err != nil || len(files) != 1 || files[0] != "[Lilith-Raws] Frieren/[Lilith-Raws] Frieren - 01.mkv" {
+		t.Fatalf("GetTorrentFiles = %v, %v", files, err)
+	}
+	if _, err := d.Rename(ctx, hash, files[0], "Frieren S01E01.mkv"); err != nil {
+		t.Fatalf("Rename error: %v", err)
+	}
+	if files, _ := d.GetTorrentFiles(ctx, hash); files[0] != "[Lilith-Raws] Frieren/Frieren S01E01.mkv" {
+		t.Errorf("files after rename = %v", files)
+	}
+
+	if _, err := d.Move(ctx, []string{hash}, "/media/Bangumi"); err != nil {
+		t.Fatalf("Move error: %v", err)
+	}
+	if !f.files["/media/Bangumi/[Lilith-Raws] Frieren/Frieren S01E01.mkv"] || !f.files["/local/Bangumi/Frieren/Season 1/Frieren S01E02.mkv"] {
+		t.Errorf("files after Move = %v", f.files)
+	}
+
+	// 重启后从任务记录找回转移后的目录
+	stateDir, config := d.StateDir, d.config
+	d = NewAlistDownloader()
+	d.APIInterval = 0
+	d.StateDir = stateDir
+	if err := d.Init(config); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	d.Auth(ctx)
+	if info, err := d.GetTorrentInfo(ctx, hash); err != nil || info.SavePath != "/media/Bangumi" {
+		t.Fatalf("GetTorrentInfo after restart = %+v, %v, want /media/Bangumi", info, err)
+	}
+	if files, _ := d.GetTorrentFiles(ctx, hash); len(files) != 1 || files[0] != "[Lilith-Raws] Frieren/Frieren S01E01.mkv" {
+		t.Errorf("files after restart = %v", files)
+	}
+
+	if _, err := d.Delete(ctx, []string{hash}, true); err != nil {
+		t.Fatalf("Delete error: %v", err)
+	}
+	if len(f.files) != 1 || len(f.tasks) != 0 {
+		t.Errorf("after Delete files = %v, tasks = %v", f.files, f.tasks)
+	}
+	if _, err := d.CheckHash(ctx, hash); !apperrors.IsKeyError(err) {
+		t.Fatalf("CheckHash after Delete error = %v, want key error", err)
+	}
+}
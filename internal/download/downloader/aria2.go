@@ -0,0 +1,682 @@
+package downloader
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"goto-bangumi/internal/apperrors"
+	"goto-bangumi/internal/model"
+
+	"github.com/go-resty/resty/v2"
+)
+
+/*
+aria2 的几点说明
+1: 认证使用 rpc-secret，配置里的 Token 会作为 "token:xxx" 放在每个请求参数的第一位
+2: aria2 用 GID 标识任务，Add 返回 info hash，CheckHash 再通过 tellActive/tellWaiting/tellStopped 把 info hash 换成 GID，
+   之后 DownloadUID 存的都是 GID；磁力链接会先生成一个下载元数据的任务，完成后由 followedBy 指向真正的任务
+3: aria2 不能重命名和移动文件，下载完成后直接操作本地文件系统，所以 aria2 和本程序要能访问同一个目录
+4: 第一次重命名或移动前会把任务从 aria2 中移除，任务不再做种；否则 aria2 仍指向原来的文件，恢复任务时会重新下载
+   移除前把任务的位置记录在内存里，同时写到下载目录下的 .goto-bangumi/aria2-<GID>.json，
+   之后这个任务的状态、文件列表和删除都以记录为准，重启后从记录找回
+*/
+
+const aria2RPCPath = "/jsonrpc"
+
+// 列出任务时需要的字段
+var aria2Keys = []string{
+	"gid", "status", "totalLength", "completedLength", "downloadSpeed", "infoHash", "dir", "followedBy", "errorMessage", "files",
+	"uploadLength", "uploadSpeed", "connections",
+}
+
+// Aria2Downloader aria2 JSON-RPC 下载器实现
+type Aria2Downloader struct {
+	client      *resty.Client
+	config      *model.DownloaderConfig
+	APIInterval int // API 调用间隔（毫秒）
+	limiter     *apiLimiter
+
+	// 已从 aria2 移除的任务的位置，key 为 GID，值为 nil 表示确认过没有记录
+	mu    sync.RWMutex
+	local map[string]*aria2Local
+}
+
+// aria2Local 从 aria2 移除后的任务记录
+type aria2Local struct {
+	Hash  string   `json:"info_hash"`
+	Name  string   `json:"name"`
+	Size  int64    `json:"size"`
+	Dir   string   `json:"dir"`
+	Files []string `json:"files"` // 相对 Dir 的路径
+}
+
+// info 已移除的任务按记录返回已完成的状态
+func (l *aria2Local) info() *model.TorrentStatus {
+	return &model.TorrentStatus{
+		Hash:        l.Hash,
+		Name:        l.Name,
+		State:       model.DownloadStateCompleted,
+		Progress:    1,
+		Size:        l.Size,
+		Downloaded:  l.Size,
+		ETA:         0,
+		Ratio:       -1,
+		SeedingTime: -1,
+		SavePath:    l.Dir,
+		Completed:   int(time.Now().Unix()),
+	}
+}
+
+// NewAria2Downloader 创建新的 aria2 下载器
+func NewAria2Downloader() *Aria2Downloader {
+	return &Aria2Downloader{
+		client:      resty.New(),
+		APIInterval: 100,
+		limiter:     newAPILimiter(100 * time.Millisecond),
+		local:       make(map[string]*aria2Local),
+	}
+}
+
+// Init 初始化下载器
+func (d *Aria2Downloader) Init(config *model.DownloaderConfig) error {
+	if config == nil {
+		return fmt.Errorf("配置不能为空")
+	}
+	d.config = config
+	if d.limiter == nil {
+		d.limiter = newAPILimiter(time.Duration(d.APIInterval) * time.Millisecond)
+	} else {
+		d.limiter.SetInterval(time.Duration(d.APIInterval) * time.Millisecond)
+	}
+
+	d.client.SetBaseURL(hostURL(config))
+	d.client.SetTimeout(10 * time.Second)
+	d.client.SetHeader("User-Agent", "goto-bangumi")
+	if !config.Ssl {
+		d.client.SetTLSClientConfig(&tls.Config{
+			InsecureSkipVerify: true,
+		})
+	}
+	return nil
+}
+
+func (d *Aria2Downloader) wait(ctx context.Context) error {
+	if d.limiter == nil {
+		return nil
+	}
+	return d.limiter.Wait(ctx, "aria2")
+}
+
+// aria2Error aria2 返回的 JSON-RPC 错误
+type aria2Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *aria2Error) Error() string {
+	return fmt.Sprintf("aria2 error %d: %s", e.Code, e.Message)
+}
+
+// call 调用 RPC 方法，自动在参数前加上 secret token
+func (d *Aria2Downloader) call(ctx context.Context, method string, result any, params ...any) error {
+	if err := d.wait(ctx); err != nil {
+		return err
+	}
+	if d.config.Token != "" {
+		params = append([]any{"token:" + d.config.Token}, params...)
+	}
+
+	resp, err := d.client.R().
+		SetContext(ctx).
+		SetBody(map[string]any{
+			"jsonrpc": "2.0",
+			"id":      "goto-bangumi",
+			"method":  method,
+			"params":  params,
+		}).
+		Post(aria2RPCPath)
+	if err != nil {
+		slog.Error("[aria2] 连接错误", "method", method, "error", err)
+		return &apperrors.NetworkError{Err: fmt.Errorf("连接到aria2时出错: %w", err)}
+	}
+
+	var body struct {
+		Result json.RawMessage `json:"result"`
+		Error  *aria2Error     `json:"error"`
+	}
+	if err := json.Unmarshal(resp.Body(), &body); err != nil {
+		return &apperrors.NetworkError{
+			Err:        fmt.Errorf("解析 %s 响应失败：状态码 %d: %w", method, resp.StatusCode(), err),
+			StatusCode: resp.StatusCode(),
+		}
+	}
+	if body.Error != nil {
+		if body.Error.Message == "Unauthorized" {
+			slog.Error("[aria2] 认证失败，请检查 rpc-secret")
+			return &apperrors.DownloadAuthenticationError{Err: body.Error, Name: "rpc-secret"}
+		}
+		return body.Error
+	}
+	if result != nil {
+		if err := json.Unmarshal(body.Result, result); err != nil {
+			return fmt.Errorf("解析 %s 响应失败: %w", method, err)
+		}
+	}
+	return nil
+}
+
+// Auth 通过 getVersion 验证连接和 rpc-secret
+func (d *Aria2Downloader) Auth(ctx context.Context) (bool, error) {
+	var version struct {
+		Version string `json:"version"`
+	}
+	if err := d.call(ctx, "aria2.getVersion", &version); err != nil {
+		return false, err
+	}
+	slog.Debug("[aria2] 连接成功", "version", version.Version)
+	return true, nil
+}
+
+// Logout aria2 没有会话，不需要登出
+func (d *Aria2Downloader) Logout(ctx context.Context) (bool, error) {
+	return true, nil
+}
+
+// tellStatus 获取单个任务，任务不存在时返回 DownloadKeyError
+func (d *Aria2Downloader) tellStatus(ctx context.Context, gid string) (*model.Aria2Status, error) {
+	var status model.Aria2Status
+	if err := d.call(ctx, "aria2.tellStatus", &status, gid, aria2Keys); err != nil {
+		var rpcErr *aria2Error
+		if errors.As(err, &rpcErr) && strings.Contains(rpcErr.Message, "is not found") {
+			return nil, &apperrors.DownloadKeyError{Err: err, Key: gid}
+		}
+		return nil, err
+	}
+	return &status, nil
+}
+
+// listAll 列出 aria2 中的所有任务
+func (d *Aria2Downloader) listAll(ctx context.Context) ([]model.Aria2Status, error) {
+	var active, waiting, stopped []model.Aria2Status
+	if err := d.call(ctx, "aria2.tellActive", &active, aria2Keys); err != nil {
+		return nil, err
+	}
+	if err := d.call(ctx, "aria2.tellWaiting", &waiting, 0, 1000, aria2Keys); err != nil {
+		return nil, err
+	}
+	if err := d.call(ctx, "aria2.tellStopped", &stopped, 0, 1000, aria2Keys); err != nil {
+		return nil, err
+	}
+	all := make([]model.Aria2Status, 0, len(active)+len(waiting)+len(stopped))
+	all = append(all, active...)
+	all = append(all, waiting...)
+	return append(all, stopped...), nil
+}
+
+// info 转换为统一的种子状态，aria2 不提供做种时长
+func (d *Aria2Downloader) info(s *model.Aria2Status) *model.TorrentStatus {
+	total, _ := strconv.ParseInt(s.TotalLength, 10, 64)
+	completed, _ := strconv.ParseInt(s.CompletedLength, 10, 64)
+	speed, _ := strconv.ParseInt(s.DownloadSpeed, 10, 64)
+	uploaded, _ := strconv.ParseInt(s.UploadLength, 10, 64)
+	uploadSpeed, _ := strconv.ParseInt(s.UploadSpeed, 10, 64)
+
+	info := &model.TorrentStatus{
+		Hash:          s.InfoHash,
+		Name:          aria2Name(s),
+		State:         aria2State(s, total, completed, speed),
+		Message:       s.ErrorMessage,
+		Size:          total,
+		Downloaded:    completed,
+		Uploaded:      uploaded,
+		DownloadSpeed: speed,
+		UploadSpeed:   uploadSpeed,
+		ETA:           -1,
+		Ratio:         -1,
+		SeedingTime:   -1,
+		SavePath:      s.Dir,
+	}
+	if total > 0 {
+		info.Progress = float64(completed) / float64(total)
+		info.Ratio = float64(uploaded) / float64(total)
+	}
+
+	// 做种时状态仍是 active，按长度判断是否完成
+	if aria2Done(s) {
+		info.ETA = 0
+		info.Completed = int(time.Now().Unix())
+	} else if speed > 0 {
+		info.ETA = int((total - completed) / speed)
+	}
+	return info
+}
+
+// aria2Done 任务是否下载完成，做种时状态仍是 active
+func aria2Done(s *model.Aria2Status) bool {
+	total, _ := strconv.ParseInt(s.TotalLength, 10, 64)
+	completed, _ := strconv.ParseInt(s.CompletedLength, 10, 64)
+	return s.Status == "complete" || (total > 0 && completed >= total)
+}
+
+// aria2State 归一化 aria2 的任务状态
+func aria2State(s *model.Aria2Status, total, completed, speed int64) model.DownloadState {
+	done := aria2Done(s)
+	switch s.Status {
+	case "active":
+		if done {
+			return model.DownloadStateSeeding
+		}
+		if isAria2Metadata(s) {
+			return model.DownloadStateMetadata
+		}
+		if speed == 0 && s.Connections == "0" {
+			return model.DownloadStateStalled
+		}
+		return model.DownloadStateDownloading
+	case "waiting":
+		return model.DownloadStateWaiting
+	case "paused":
+		if done {
+			return model.DownloadStateCompleted
+		}
+		return model.DownloadStatePaused
+	case "complete":
+		return model.DownloadStateCompleted
+	case "error":
+		return model.DownloadStateFailed
+	default:
+		return model.DownloadStateUnknown
+	}
+}
+
+// isAria2Metadata 磁力链接的元数据任务，文件路径是 [METADATA]xxx
+func isAria2Metadata(s *model.Aria2Status) bool {
+	return len(s.Files) > 0 && strings.HasPrefix(s.Files[0].Path, "[METADATA]")
+}
+
+// aria2Name 用第一个文件所在的顶层目录或文件名作为任务名
+func aria2Name(s *model.Aria2Status) string {
+	files := relativeFiles(s)
+	if len(files) == 0 {
+		return s.Gid
+	}
+	name, _, _ := strings.Cut(files[0], "/")
+	return name
+}
+
+// GetTorrentFiles 获取任务的文件列表，路径相对于下载目录
+func (d *Aria2Downloader) GetTorrentFiles(ctx context.Context, hash string) ([]string, error) {
+	if l, ok := d.record(hash); ok {
+		return l.Files, nil
+	}
+
+	s, err := d.tellStatus(ctx, hash)
+	if err != nil {
+		if apperrors.IsKeyError(err) {
+			slog.Warn("[aria2] 找不到任务", "gid", hash)
+			return nil, nil
+		}
+		return nil, err
+	}
+	return relativeFiles(s), nil
+}
+
+// statePath 任务记录的位置，放在下载目录下，按 GID 命名
+func (d *Aria2Downloader) statePath(gid string) string {
+	return filepath.Join(d.config.SavePath, ".goto-bangumi", "aria2-"+gid+".json")
+}
+
+// record 返回已从 aria2 移除的任务记录，内存里没有时从下载目录下的记录找回
+func (d *Aria2Downloader) record(gid string) (aria2Local, bool) {
+	d.mu.RLock()
+	l, ok := d.local[gid]
+	d.mu.RUnlock()
+	if !ok {
+		if data, err := os.ReadFile(d.statePath(gid)); err == nil {
+			var saved aria2Local
+			if err := json.Unmarshal(data, &saved); err != nil || saved.Dir == "" {
+				slog.Warn("[aria2] 任务记录无效", "path", d.statePath(gid), "error", err)
+			} else {
+				l = &saved
+			}
+		}
+		d.mu.Lock()
+		d.local[gid] = l
+		d.mu.Unlock()
+	}
+	if l == nil {
+		return aria2Local{}, false
+	}
+	return aria2Local{Hash: l.Hash, Name: l.Name, Size: l.Size, Dir: l.Dir, Files: append([]string(nil), l.Files...)}, true
+}
+
+// save 保存任务记录，写入失败时只在内存里生效
+func (d *Aria2Downloader) save(gid string, l *aria2Local) {
+	d.mu.Lock()
+	d.local[gid] = l
+	d.mu.Unlock()
+	statePath := d.statePath(gid)
+	data, err := json.Marshal(l)
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(statePath), 0o755)
+	}
+	if err == nil {
+		err = os.WriteFile(statePath, data, 0o644)
+	}
+	if err != nil {
+		slog.Warn("[aria2] 保存任务记录失败，重启后无法找回", "gid", gid, "error", err)
+	}
+}
+
+// forget 删除任务记录
+func (d *Aria2Downloader) forget(gid string) {
+	d.mu.Lock()
+	delete(d.local, gid)
+	d.mu.Unlock()
+	if err := os.Remove(d.statePath(gid)); err != nil && !os.IsNotExist(err) {
+		slog.Debug("[aria2] 删除任务记录失败", "gid", gid, "error", err)
+	}
+}
+
+func relativeFiles(s *model.Aria2Status) []string {
+	files := make([]string, 0, len(s.Files))
+	for _, f := range s.Files {
+		// 元数据任务的文件路径是 [METADATA]xxx，不是真正的文件
+		if f.Path == "" || strings.HasPrefix(f.Path, "[METADATA]") {
+			continue
+		}
+		// 未选中的文件不会下载
+		if f.Selected == "false" {
+			continue
+		}
+		rel, err := filepath.Rel(s.Dir, f.Path)
+		if err != nil {
+			rel = f.Path
+		}
+		files = append(files, filepath.ToSlash(rel))
+	}
+	return files
+}
+
+// GetTorrentInfo 获取单个任务信息
+func (d *Aria2Downloader) GetTorrentInfo(ctx context.Context, hash string) (*model.TorrentStatus, error) {
+	if l, ok := d.record(hash); ok {
+		return l.info(), nil
+	}
+	s, err := d.tellStatus(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	if s.Status == "removed" {
+		return nil, &apperrors.DownloadKeyError{Err: fmt.Errorf("任务已移除"), Key: hash}
+	}
+	return d.info(s), nil
+}
+
+// GetTorrentsInfo 列出一次全部任务，按 GID 或 info hash 匹配
+func (d *Aria2Downloader) GetTorrentsInfo(ctx context.Context, hashes []string) (map[string]*model.TorrentStatus, error) {
+	result := make(map[string]*model.TorrentStatus, len(hashes))
+	remaining := make([]string, 0, len(hashes))
+	for _, hash := range hashes {
+		if l, ok := d.record(hash); ok {
+			result[hash] = l.info()
+		} else {
+			remaining = append(remaining, hash)
+		}
+	}
+	if len(remaining) == 0 {
+		return result, nil
+	}
+	all, err := d.listAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[string]*model.Aria2Status, len(all)*2)
+	for i := range all {
+		s := &all[i]
+		if s.Status == "removed" {
+			continue
+		}
+		found[s.Gid] = s
+		// 元数据任务和真正的任务 info hash 相同，以没有 followedBy 的为准
+		if s.InfoHash != "" && len(s.FollowedBy) == 0 {
+			found[strings.ToLower(s.InfoHash)] = s
+		}
+	}
+	for _, hash := range remaining {
+		s, ok := found[hash]
+		if !ok {
+			s, ok = found[strings.ToLower(hash)]
+		}
+		if ok {
+			result[hash] = d.info(s)
+		}
+	}
+	return result, nil
+}
+
+// TorrentsInfo 获取任务信息列表，aria2 没有分类和标签，这两个参数会被忽略
+func (d *Aria2Downloader) TorrentsInfo(ctx context.Context, statusFilter, category string, tag *string, limit int) ([]*model.TorrentStatus, error) {
+	all, err := d.listAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*model.TorrentStatus, 0, len(all))
+	for i := range all {
+		s := &all[i]
+		if s.InfoHash == "" || len(s.FollowedBy) > 0 {
+			continue
+		}
+		info := d.info(s)
+		done := info.Completed > 0
+		if (statusFilter == "completed" && !done) || (statusFilter == "downloading" && done) {
+			continue
+		}
+		result = append(result, info)
+		if limit > 0 && len(result) >= limit {
+			break
+		}
+	}
+	return result, nil
+}
+
+// CheckHash 把 info hash 换成 aria2 的 GID
+func (d *Aria2Downloader) CheckHash(ctx context.Context, hash string) (string, error) {
+	all, err := d.listAll(ctx)
+	if err != nil {
+		return "", err
+	}
+	for i := range all {
+		s := &all[i]
+		if !strings.EqualFold(s.InfoHash, hash) && s.Gid != hash {
+			continue
+		}
+		if len(s.FollowedBy) > 0 {
+			return s.FollowedBy[0], nil
+		}
+		return s.Gid, nil
+	}
+	return "", &apperrors.DownloadKeyError{Err: fmt.Errorf("任务不存在"), Key: hash}
+}
+
+// Add 添加种子，有种子文件时使用 addTorrent，否则使用 addUri 添加磁力链接
+func (d *Aria2Downloader) Add(ctx context.Context, torrentInfo *model.TorrentInfo, savePath string) ([]string, error) {
+	options := map[string]string{"dir": d.downloadDir(savePath)}
+	if selected := aria2SelectFile(torrentInfo); selected != "" {
+		options["select-file"] = selected
+	}
+
+	var gid string
+	var err error
+	if len(torrentInfo.File) > 0 {
+		encoded := base64.StdEncoding.EncodeToString(torrentInfo.File)
+		err = d.call(ctx, "aria2.addTorrent", &gid, encoded, []string{}, options)
+	} else {
+		err = d.call(ctx, "aria2.addUri", &gid, []string{torrentInfo.MagnetURI}, options)
+	}
+	if err != nil {
+		return nil, err
+	}
+	slog.Debug("[aria2] 添加任务成功", "name", torrentInfo.Name, "gid", gid)
+
+	hashes := make([]string, 0, 2)
+	if torrentInfo.InfoHashV1 != "" {
+		hashes = append(hashes, torrentInfo.InfoHashV1)
+	}
+	if torrentInfo.InfoHashV2 != "" {
+		v2Hash := torrentInfo.InfoHashV2
+		if len(v2Hash) > 40 {
+			v2Hash = v2Hash[:40]
+		}
+		hashes = append(hashes, v2Hash)
+	}
+	return hashes, nil
+}
+
+// aria2SelectFile 生成 select-file 选项，aria2 的文件下标从 1 开始，没有要跳过的文件时返回空
+func aria2SelectFile(torrentInfo *model.TorrentInfo) string {
+	if len(torrentInfo.SkipFiles) == 0 {
+		return ""
+	}
+	skip := make(map[int]bool, len(torrentInfo.SkipFiles))
+	for _, i := range torrentInfo.SkipFiles {
+		skip[i] = true
+	}
+	selected := make([]string, 0, len(torrentInfo.Files))
+	for i := range torrentInfo.Files {
+		if !skip[i] {
+			selected = append(selected, strconv.Itoa(i+1))
+		}
+	}
+	return strings.Join(selected, ",")
+}
+
+func (d *Aria2Downloader) downloadDir(savePath string) string {
+	if savePath == "" || path.IsAbs(savePath) {
+		return savePath
+	}
+	return path.Join(d.config.SavePath, savePath)
+}
+
+// Delete 停止任务，deleteFiles 为 true 时删除本地文件
+// 已经从 aria2 移除的任务只删除记录和文件
+func (d *Aria2Downloader) Delete(ctx context.Context, hashes []string, deleteFiles bool) (bool, error) {
+	for _, gid := range hashes {
+		l, ok := d.record(gid)
+		if !ok {
+			s, err := d.tellStatus(ctx, gid)
+			if err != nil {
+				if apperrors.IsKeyError(err) {
+					continue
+				}
+				return false, err
+			}
+			d.remove(ctx, gid)
+			l = aria2Local{Dir: s.Dir, Files: relativeFiles(s)}
+		}
+		if deleteFiles {
+			for _, f := range l.Files {
+				if err := os.Remove(filepath.Join(l.Dir, f)); err != nil && !os.IsNotExist(err) {
+					slog.Warn("[aria2] 删除文件失败", "path", f, "error", err)
+				}
+			}
+		}
+		d.forget(gid)
+	}
+	return true, nil
+}
+
+// remove 从 aria2 中移除任务和它的下载结果，任务可能已经结束，失败只记录日志
+func (d *Aria2Downloader) remove(ctx context.Context, gid string) {
+	if err := d.call(ctx, "aria2.forceRemove", nil, gid); err != nil {
+		slog.Debug("[aria2] 停止任务失败，可能已经结束", "gid", gid, "error", err)
+	}
+	if err := d.call(ctx, "aria2.removeDownloadResult", nil, gid); err != nil {
+		slog.Debug("[aria2] 清除任务记录失败", "gid", gid, "error", err)
+	}
+}
+
+// detach 返回可以操作文件的任务位置，未完成时不允许操作文件
+// 第一次操作文件前记下任务的位置，再把任务从 aria2 移除，aria2 不会再读写或重新下载这些文件
+func (d *Aria2Downloader) detach(ctx context.Context, gid string) (aria2Local, error) {
+	if l, ok := d.record(gid); ok {
+		return l, nil
+	}
+	s, err := d.tellStatus(ctx, gid)
+	if err != nil {
+		return aria2Local{}, err
+	}
+	if s.Status == "removed" {
+		return aria2Local{}, &apperrors.DownloadKeyError{Err: fmt.Errorf("任务已移除"), Key: gid}
+	}
+	if !aria2Done(s) {
+		return aria2Local{}, fmt.Errorf("[aria2] 任务未完成，不能操作文件: %s", gid)
+	}
+	size, _ := strconv.ParseInt(s.TotalLength, 10, 64)
+	l := aria2Local{Hash: s.InfoHash, Name: aria2Name(s), Size: size, Dir: s.Dir, Files: relativeFiles(s)}
+	d.save(gid, &l)
+	d.remove(ctx, gid)
+	slog.Info("[aria2] 操作文件前从 aria2 移除任务，不再做种", "gid", gid, "name", l.Name)
+	return l, nil
+}
+
+// Rename 在本地文件系统上重命名文件
+func (d *Aria2Downloader) Rename(ctx context.Context, torrentHash, oldPath, newPath string) (bool, error) {
+	l, err := d.detach(ctx, torrentHash)
+	if err != nil {
+		return false, err
+	}
+	dst := filepath.Join(l.Dir, newPath)
+	if _, err := os.Stat(dst); err == nil {
+		slog.Error("[aria2] 重命名错误，文件已存在", "oldPath", oldPath, "newPath", newPath)
+		return false, fmt.Errorf("文件已存在")
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return false, err
+	}
+	if err := os.Rename(filepath.Join(l.Dir, oldPath), dst); err != nil {
+		return false, fmt.Errorf("重命名失败: %w", err)
+	}
+
+	for i, f := range l.Files {
+		if f == oldPath {
+			l.Files[i] = newPath
+		}
+	}
+	d.save(torrentHash, &l)
+	return true, nil
+}
+
+// Move 在本地文件系统上把任务的文件移动到新目录，保持相对路径
+func (d *Aria2Downloader) Move(ctx context.Context, hashes []string, newLocation string) (bool, error) {
+	for _, gid := range hashes {
+		l, err := d.detach(ctx, gid)
+		if err != nil {
+			return false, err
+		}
+		for _, f := range l.Files {
+			dst := filepath.Join(newLocation, f)
+			if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+				return false, err
+			}
+			if err := moveFile(filepath.Join(l.Dir, f), dst); err != nil {
+				return false, fmt.Errorf("移动文件失败: %w", err)
+			}
+		}
+		l.Dir = newLocation
+		d.save(gid, &l)
+	}
+	return true, nil
+}
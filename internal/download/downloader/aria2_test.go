@@ -0,0 +1,255 @@
+package downloader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"goto-bangumi/internal/apperrors"
+	"goto-bangumi/internal/model"
+)
+
+// fakeAria2 进程内的 aria2 JSON-RPC 服务
+type fakeAria2 struct {
+	mu     sync.Mutex
+	secret string
+	tasks  map[string]*model.Aria2Status
+	order  []string
+	// 新任务的文件名，相对下载目录
+	file string
+}
+
+func newFakeAria2(t *testing.T, secret string) (*fakeAria2, *httptest.Server) {
+	t.Helper()
+	f := &fakeAria2{
+		secret: secret,
+		tasks:  make(map[string]*model.Aria2Status),
+		file:   "[Lilith-Raws] Sousou no Frieren - 01.mkv",
+	}
+	srv := httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func (f *fakeAria2) serve(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+	reply := func(result any, rpcErr *aria2Error) {
+		body := map[string]any{"jsonrpc": "2.0", "id": "goto-bangumi"}
+		if rpcErr != nil {
+			body["error"] = rpcErr
+		} else {
+			body["result"] = result
+		}
+		json.NewEncoder(w).Encode(body)
+	}
+
+	var token string
+	if len(req.Params) > 0 {
+		json.Unmarshal(req.Params[0], &token)
+	}
+	if f.secret != "" {
+		if token != "token:"+f.secret {
+			reply(nil, &aria2Error{Code: 1, Message: "Unauthorized"})
+			return
+		}
+		req.Params = req.Params[1:]
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	param := func(i int, v any) { json.Unmarshal(req.Params[i], v) }
+
+	switch req.Method {
+	case "aria2.getVersion":
+		reply(map[string]string{"version": "1.37.0"}, nil)
+	case "aria2.addTorrent", "aria2.addUri":
+		var options map[string]string
+		param(len(req.Params)-1, &options)
+		gid := fmt.Sprintf("%016d", len(f.order)+1)
+		f.tasks[gid] = &model.Aria2Status{
+			Gid:             gid,
+			Status:          "active",
+			TotalLength:     "1000",
+			CompletedLength: "500",
+			DownloadSpeed:   "10",
+			InfoHash:        "1317e47882474c771e29ed2271b282fbfb56e7d2",
+			Dir:             options["dir"],
+			Files:           []model.Aria2File{{Index: "1", Path: filepath.Join(options["dir"], f.file), Length: "1000"}},
+		}
+		f.order = append(f.order, gid)
+		reply(gid, nil)
+	case "aria2.tellStatus":
+		var gid string
+		param(0, &gid)
+		s, ok := f.tasks[gid]
+		if !ok {
+			reply(nil, &aria2Error{Code: 1, Message: "GID " + gid + " is not found"})
+			return
+		}
+		reply(s, nil)
+	case "aria2.tellActive", "aria2.tellWaiting", "aria2.tellStopped":
+		var list []*model.Aria2Status
+		for _, gid := range f.order {
+			s := f.tasks[gid]
+			active := s.Status == "active"
+			if (req.Method == "aria2.tellActive") == active && req.Method != "aria2.tellWaiting" {
+				list = append(list, s)
+			}
+		}
+		reply(list, nil)
+	case "aria2.forceRemove", "aria2.removeDownloadResult":
+		var gid string
+		param(0, &gid)
+		delete(f.tasks, gid)
+		reply("OK", nil)
+	default:
+		reply(nil, &aria2Error{Code: 1, Message: "Method not found"})
+	}
+}
+
+func newTestAria2(t *testing.T, host, token, savePath string) *Aria2Downloader {
+	t.Helper()
+	d := NewAria2Downloader()
+	d.APIInterval = 0
+	err := d.Init(&model.DownloaderConfig{
+		Type:     "aria2",
+		SavePath: savePath,
+		Host:     host,
+		Token:    token,
+	})
+	if err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	return d
+}
+
+func TestAria2_AuthSecretToken(t *testing.T) {
+	_, srv := newFakeAria2(t, "s3cret")
+	ctx := context.Background()
+
+	if ok, err := newTestAria2(t, srv.URL, "s3cret", t.TempDir()).Auth(ctx); err != nil || !ok {
+		t.Fatalf("Auth() = %v, %v, want true", ok, err)
+	}
+	_, err := newTestAria2(t, srv.URL, "wrong", t.TempDir()).Auth(ctx)
+	if !apperrors.IsDownloadAuthenticationError(err) {
+		t.Fatalf("Auth() with wrong secret error = %v, want DownloadAuthenticationError", err)
+	}
+}
+
+func TestAria2_TorrentLifecycle(t *testing.T) {
+	f, srv := newFakeAria2(t, "")
+	saveRoot := t.TempDir()
+	d := newTestAria2(t, srv.URL, "", saveRoot)
+	ctx := context.Background()
+	hash := "1317e47882474c771e29ed2271b282fbfb56e7d2"
+
+	hashes, err := d.Add(ctx, &model.TorrentInfo{Name: "Frieren", InfoHashV1: hash, File: []byte("d4:infoe")}, "Frieren/Season 1")
+	if err != nil {
+		t.Fatalf("Add error: %v", err)
+	}
+	if len(hashes) != 1 || hashes[0] != hash {
+		t.Fatalf("Add hashes = %v", hashes)
+	}
+
+	gid, err := d.CheckHash(ctx, hash)
+	if err != nil {
+		t.Fatalf("CheckHash error: %v", err)
+	}
+	if gid != "0000000000000001" {
+		t.Fatalf("CheckHash = %q, want gid", gid)
+	}
+
+	info, err := d.GetTorrentInfo(ctx, gid)
+	if err != nil {
+		t.Fatalf("GetTorrentInfo error: %v", err)
+	}
+	dir := filepath.Join(saveRoot, "Frieren/Season 1")
+	if info.Completed != 0 || info.ETA != 50 || info.SavePath != dir {
+		t.Fatalf("info = %+v, want eta 50 in %s", info, dir)
+	}
+	if _, err := d.Rename(ctx, gid, f.file, "Frieren S01E01.mkv"); err == nil {
+		t.Fatal("Rename before completion should fail")
+	}
+
+	// 模拟下载完成并写出文件
+	f.mu.Lock()
+	f.tasks[gid].CompletedLength = "1000"
+	f.mu.Unlock()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, f.file), []byte("video"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	infos, err := d.GetTorrentsInfo(ctx, []string{gid, "missing"})
+	if err != nil || len(infos) != 1 || infos[gid].Completed == 0 {
+		t.Fatalf("GetTorrentsInfo = %+v, %v, want completed gid only", infos, err)
+	}
+
+	files, err := d.GetTorrentFiles(ctx, gid)
+	if err != nil || len(files) != 1 || files[0] != f.file {
+		t.Fatalf("GetTorrentFiles = %v, %v", files, err)
+	}
+	if _, err := d.Rename(ctx, gid, f.file, "Frieren S01E01.mkv"); err != nil {
+		t.Fatalf("Rename error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Frieren S01E01.mkv")); err != nil {
+		t.Fatalf("renamed file missing: %v", err)
+	}
+	if files, _ := d.GetTorrentFiles(ctx, gid); files[0] != "Frieren S01E01.mkv" {
+		t.Errorf("files after rename = %v", files)
+	}
+	// 做种中的任务在操作文件前从 aria2 移除，之后按记录返回状态
+	f.mu.Lock()
+	_, exists := f.tasks[gid]
+	f.mu.Unlock()
+	if exists {
+		t.Error("task still in aria2 after Rename")
+	}
+	if info, err := d.GetTorrentInfo(ctx, gid); err != nil || info.State != model.DownloadStateCompleted || info.SavePath != dir || info.Hash != hash {
+		t.Errorf("info after Rename = %+v, %v, want completed in %s", info, err, dir)
+	}
+	// 重启后从下载目录下的记录找回重命名后的文件
+	d = newTestAria2(t, srv.URL, "", saveRoot)
+	if files, _ := d.GetTorrentFiles(ctx, gid); len(files) != 1 || files[0] != "Frieren S01E01.mkv" {
+		t.Errorf("files after restart = %v", files)
+	}
+
+	media := filepath.Join(t.TempDir(), "media")
+	if _, err := d.Move(ctx, []string{gid}, media); err != nil {
+		t.Fatalf("Move error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(media, "Frieren S01E01.mkv")); err != nil {
+		t.Fatalf("moved file missing: %v", err)
+	}
+	if info, _ := d.GetTorrentInfo(ctx, gid); info.SavePath != media {
+		t.Errorf("SavePath after Move = %q, want %q", info.SavePath, media)
+	}
+	d = newTestAria2(t, srv.URL, "", saveRoot)
+	if info, _ := d.GetTorrentInfo(ctx, gid); info.SavePath != media {
+		t.Errorf("SavePath after restart = %q, want %q", info.SavePath, media)
+	}
+
+	if _, err := d.Delete(ctx, []string{gid}, true); err != nil {
+		t.Fatalf("Delete error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(media, "Frieren S01E01.mkv")); !os.IsNotExist(err) {
+		t.Errorf("file still exists after Delete: %v", err)
+	}
+	if entries, _ := os.ReadDir(filepath.Join(saveRoot, ".goto-bangumi")); len(entries) != 0 {
+		t.Errorf("records after Delete = %v, want the task record removed", entries)
+	}
+	if _, err := d.GetTorrentInfo(ctx, gid); !apperrors.IsKeyError(err) {
+		t.Fatalf("GetTorrentInfo after Delete error = %v, want key error", err)
+	}
+}
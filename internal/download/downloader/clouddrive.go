@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"path"
 	"strings"
 	"sync"
 	"time"
@@ -51,12 +52,17 @@ type CloudDriveDownloader struct {
 	cloudAcctID string
 	apiInterval int
 	limiter     *apiLimiter
+	// locations 任务所在的目录，key 为小写 hash，转移或找回后记录
+	// 重启后没有记录，要操作文件时（GetTorrentFiles、Rename、Move）才先看 SavePath，再到 MediaPath 下搜索
+	// 查询状态只读这里的记录，不额外请求网盘
+	locations map[string]string
 }
 
 func NewCloudDriveDownloader() *CloudDriveDownloader {
 	return &CloudDriveDownloader{
 		apiInterval: 5,
 		limiter:     newAPILimiterFromQPS(5),
+		locations:   make(map[string]string),
 	}
 }
 
@@ -213,6 +219,10 @@ func (d *CloudDriveDownloader) Add(ctx context.Context, torrentInfo *model.Torre
 	if url == "" {
 		return nil, fmt.Errorf("[CloudDrive2] magnet URI required for offline download")
 	}
+	// 配额用完时添加必然失败，返回配额错误让任务等待配额恢复
+	if err := d.checkQuota(ctx); err != nil {
+		return nil, err
+	}
 
 	if err := d.wait(ctx); err != nil {
 		return nil, err
@@ -243,6 +253,36 @@ func (d *CloudDriveDownloader) Add(ctx context.Context, torrentInfo *model.Torre
 	return hashes, nil
 }
 
+// checkQuota returns a DownloadQuotaError when the account has no offline quota left.
+// 查询失败时不阻止添加，部分网盘不支持查询配额
+func (d *CloudDriveDownloader) checkQuota(ctx context.Context) error {
+	d.mu.RLock()
+	cloudName := d.cloudName
+	cloudAcctID := d.cloudAcctID
+	d.mu.RUnlock()
+	if cloudName == "" || cloudAcctID == "" {
+		return nil
+	}
+	if err := d.wait(ctx); err != nil {
+		return err
+	}
+
+	quota, err := d.rpc.GetOfflineQuotaInfo(d.authCtx(ctx), &clouddrive.OfflineQuotaRequest{
+		CloudName:      cloudName,
+		CloudAccountId: cloudAcctID,
+	})
+	if err != nil {
+		slog.Debug("[CloudDrive2] GetOfflineQuotaInfo failed, skip quota check", "error", err)
+		return nil
+	}
+	if quota.GetTotal() > 0 && quota.GetLeft() <= 0 {
+		return &apperrors.DownloadQuotaError{
+			Err: fmt.Errorf("offline quota exhausted: used %d/%d", quota.GetUsed(), quota.GetTotal()),
+		}
+	}
+	return nil
+}
+
 // listOfflineFiles returns all offline download tasks under SavePath.
 func (d *CloudDriveDownloader) listOfflineFiles(ctx context.Context) ([]*clouddrive.OfflineFile, error) {
 	if err := d.wait(ctx); err != nil {
@@ -259,7 +299,7 @@ func (d *CloudDriveDownloader) listOfflineFiles(ctx context.Context) ([]*clouddr
 }
 
 // GetTorrentInfo looks up an offline download task by its info hash.
-func (d *CloudDriveDownloader) GetTorrentInfo(ctx context.Context, hash string) (*model.TorrentDownloadInfo, error) {
+func (d *CloudDriveDownloader) GetTorrentInfo(ctx context.Context, hash string) (*model.TorrentStatus, error) {
 	files, err := d.listOfflineFiles(ctx)
 	if err != nil {
 		return nil, err
@@ -268,15 +308,7 @@ func (d *CloudDriveDownloader) GetTorrentInfo(ctx context.Context, hash string)
 	hashLower := strings.ToLower(hash)
 	for _, f := range files {
 		if strings.ToLower(f.GetInfoHash()) == hashLower {
-			info := &model.TorrentDownloadInfo{
-				SavePath: d.config.SavePath,
-				ETA:      -1,
-			}
-			if f.GetStatus() == clouddrive.OfflineFileStatus_OFFLINE_FINISHED {
-				info.ETA = 0
-				info.Completed = int(time.Now().Unix())
-			}
-			return info, nil
+			return d.offlineInfo(f), nil
 		}
 	}
 	return nil, &apperrors.DownloadKeyError{
@@ -285,6 +317,193 @@ func (d *CloudDriveDownloader) GetTorrentInfo(ctx context.Context, hash string)
 	}
 }
 
+// GetTorrentsInfo lists offline tasks once and picks out the requested hashes.
+func (d *CloudDriveDownloader) GetTorrentsInfo(ctx context.Context, hashes []string) (map[string]*model.TorrentStatus, error) {
+	result := make(map[string]*model.TorrentStatus, len(hashes))
+	if len(hashes) == 0 {
+		return result, nil
+	}
+	files, err := d.listOfflineFiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byHash := make(map[string]*clouddrive.OfflineFile, len(files))
+	for _, f := range files {
+		byHash[strings.ToLower(f.GetInfoHash())] = f
+	}
+	for _, hash := range hashes {
+		if f, ok := byHash[strings.ToLower(hash)]; ok {
+			result[hash] = d.offlineInfo(f)
+		}
+	}
+	return result, nil
+}
+
+// cachedLocation returns the recorded folder of the task, or SavePath when nothing is recorded yet.
+func (d *CloudDriveDownloader) cachedLocation(hash string) (string, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if dir, ok := d.locations[strings.ToLower(hash)]; ok {
+		return dir, true
+	}
+	return d.config.SavePath, false
+}
+
+// location returns the folder that currently holds the task, only called before touching its files.
+// 没有记录时，未完成的任务一定还在 SavePath 下；已完成的可能在重启前转移到了 MediaPath，需要找回
+func (d *CloudDriveDownloader) location(ctx context.Context, f *clouddrive.OfflineFile) string {
+	savePath, ok := d.cachedLocation(f.GetInfoHash())
+	if ok || f.GetStatus() != clouddrive.OfflineFileStatus_OFFLINE_FINISHED {
+		return savePath
+	}
+	dir, err := d.findTaskFolder(ctx, f.GetName())
+	if err != nil {
+		slog.Warn("[CloudDrive2] could not locate task folder", "name", f.GetName(), "error", err)
+		return savePath
+	}
+	if dir == "" {
+		return savePath
+	}
+	d.mu.Lock()
+	d.locations[strings.ToLower(f.GetInfoHash())] = dir
+	d.mu.Unlock()
+	return dir
+}
+
+// findTaskFolder 找到任务目录所在的位置：还在 SavePath 下就是 SavePath，否则到 MediaPath 下按名称搜索
+// 都没有找到时返回空
+func (d *CloudDriveDownloader) findTaskFolder(ctx context.Context, taskName string) (string, error) {
+	savePath := d.config.SavePath
+	if err := d.wait(ctx); err != nil {
+		return "", err
+	}
+	if _, err := d.rpc.FindFileByPath(d.authCtx(ctx), &clouddrive.FindFileByPathRequest{ParentPath: savePath, Path: taskName}); err == nil {
+		return savePath, nil
+	}
+	mediaPath := d.config.MediaPath
+	if mediaPath == "" || path.Clean(mediaPath) == path.Clean(savePath) {
+		return "", nil
+	}
+
+	if err := d.wait(ctx); err != nil {
+		return "", err
+	}
+	stream, err := d.rpc.GetSearchResults(d.authCtx(ctx), &clouddrive.SearchRequest{Path: mediaPath, SearchFor: taskName})
+	if err != nil {
+		return "", &apperrors.NetworkError{Err: fmt.Errorf("CloudDrive2 GetSearchResults: %w", err)}
+	}
+	for {
+		reply, err := stream.Recv()
+		if err == io.EOF {
+			return "", nil
+		}
+		if err != nil {
+			return "", &apperrors.NetworkError{Err: fmt.Errorf("CloudDrive2 GetSearchResults: %w", err)}
+		}
+		for _, f := range reply.GetSubFiles() {
+			if f.GetName() == taskName && f.GetFullPathName() != "" {
+				return path.Dir(f.GetFullPathName()), nil
+			}
+		}
+	}
+}
+
+// taskLocation returns the folder that holds the task identified by hash.
+func (d *CloudDriveDownloader) taskLocation(ctx context.Context, hash string) (string, error) {
+	if dir, ok := d.cachedLocation(hash); ok {
+		return dir, nil
+	}
+	files, err := d.listOfflineFiles(ctx)
+	if err != nil {
+		return "", err
+	}
+	key := strings.ToLower(hash)
+	for _, f := range files {
+		if strings.ToLower(f.GetInfoHash()) == key {
+			return d.location(ctx, f), nil
+		}
+	}
+	return "", &apperrors.DownloadKeyError{Err: fmt.Errorf("offline task not found"), Key: hash}
+}
+
+// offlineInfo converts an offline task into download info.
+// SavePath 只用已记录的位置，还没找回的任务先报 SavePath，状态轮询不会触发目录搜索
+func (d *CloudDriveDownloader) offlineInfo(f *clouddrive.OfflineFile) *model.TorrentStatus {
+	savePath, _ := d.cachedLocation(f.GetInfoHash())
+	info := &model.TorrentStatus{
+		Hash:        f.GetInfoHash(),
+		Name:        f.GetName(),
+		Progress:    f.GetPercendDone() / 100,
+		Size:        int64(f.GetSize()),
+		ETA:         -1,
+		Ratio:       -1,
+		SeedingTime: -1,
+		SavePath:    savePath,
+	}
+	switch f.GetStatus() {
+	case clouddrive.OfflineFileStatus_OFFLINE_FINISHED:
+		info.State = model.DownloadStateCompleted
+		info.Progress = 1
+		info.ETA = 0
+		info.Completed = int(time.Now().Unix())
+	case clouddrive.OfflineFileStatus_OFFLINE_DOWNLOADING:
+		info.State = model.DownloadStateDownloading
+		info.Message = fmt.Sprintf("%.1f%%, %d peers", f.GetPercendDone(), f.GetPeers())
+	case clouddrive.OfflineFileStatus_OFFLINE_ERROR:
+		// 网盘不返回失败原因，只能给出失败时的进度
+		info.State = model.DownloadStateFailed
+		info.Message = fmt.Sprintf("offline download failed at %.1f%%", f.GetPercendDone())
+	default:
+		// OFFLINE_INIT 还在网盘的队列里，OFFLINE_UNKNOWN 一般是网盘还没同步状态
+		info.State = model.DownloadStateWaiting
+		info.Message = strings.ToLower(strings.TrimPrefix(f.GetStatus().String(), "OFFLINE_"))
+	}
+	return info
+}
+
+// Restart restarts a failed offline task with RestartOfflineTask.
+func (d *CloudDriveDownloader) Restart(ctx context.Context, hash string) error {
+	d.mu.RLock()
+	cloudName := d.cloudName
+	cloudAcctID := d.cloudAcctID
+	d.mu.RUnlock()
+	if cloudName == "" || cloudAcctID == "" {
+		return fmt.Errorf("cloud account info not available")
+	}
+
+	files, err := d.listOfflineFiles(ctx)
+	if err != nil {
+		return err
+	}
+	var task *clouddrive.OfflineFile
+	for _, f := range files {
+		if strings.EqualFold(f.GetInfoHash(), hash) {
+			task = f
+			break
+		}
+	}
+	if task == nil {
+		return &apperrors.DownloadKeyError{Err: fmt.Errorf("offline task not found"), Key: hash}
+	}
+
+	if err := d.wait(ctx); err != nil {
+		return err
+	}
+	_, err = d.rpc.RestartOfflineTask(d.authCtx(ctx), &clouddrive.RestartOfflineFileRequest{
+		CloudName:      cloudName,
+		CloudAccountId: cloudAcctID,
+		InfoHash:       task.GetInfoHash(),
+		Url:            task.GetUrl(),
+		ParentId:       task.GetParentId(),
+	})
+	if err != nil {
+		return &apperrors.NetworkError{Err: fmt.Errorf("CloudDrive2 RestartOfflineTask: %w", err)}
+	}
+	slog.Info("[CloudDrive2] restarted offline task", "name", task.GetName(), "hash", hash)
+	return nil
+}
+
 // GetTorrentFiles lists video/subtitle files in the save folder corresponding
 // to the named offline download identified by hash.
 // 对于 cd2 来说， hash 应该是其下载的名字, 应该是 下载路径/hash/ 下面的文件
@@ -296,21 +515,22 @@ func (d *CloudDriveDownloader) GetTorrentFiles(ctx context.Context, hash string)
 	}
 
 	hashLower := strings.ToLower(hash)
-	var taskName string
+	var task *clouddrive.OfflineFile
 	for _, f := range offlineFiles {
 		if strings.ToLower(f.GetInfoHash()) == hashLower {
-			taskName = f.GetName()
+			task = f
 			break
 		}
 	}
-	if taskName == "" {
+	if task == nil || task.GetName() == "" {
 		return nil, &apperrors.DownloadKeyError{
 			Err: fmt.Errorf("offline task not found"),
 			Key: hash,
 		}
 	}
+	taskName := task.GetName()
 
-	folderPath := d.config.SavePath + "/" + taskName
+	folderPath := d.location(ctx, task) + "/" + taskName
 	subFiles, err := d.listSubFiles(ctx, folderPath)
 	if err != nil {
 		// Task may still be downloading; return no files rather than error
@@ -355,30 +575,25 @@ func (d *CloudDriveDownloader) listSubFiles(ctx context.Context, path string) ([
 	return all, nil
 }
 
-// TorrentsInfo returns all offline download tasks as generic maps.
+// TorrentsInfo returns offline download tasks; categories and tags are not supported.
 // statusFilter, category, tag, limit are accepted for interface compatibility
 // but CloudDrive2 does not support server-side filtering on offline tasks.
-func (d *CloudDriveDownloader) TorrentsInfo(ctx context.Context, statusFilter, category string, tag *string, limit int) ([]map[string]any, error) {
+func (d *CloudDriveDownloader) TorrentsInfo(ctx context.Context, statusFilter, category string, tag *string, limit int) ([]*model.TorrentStatus, error) {
 	files, err := d.listOfflineFiles(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	result := make([]map[string]any, 0, len(files))
-	for i, f := range files {
-		if limit > 0 && i >= limit {
-			break
+	result := make([]*model.TorrentStatus, 0, len(files))
+	for _, f := range files {
+		info := d.offlineInfo(f)
+		if (statusFilter == "completed" && !info.Done()) || (statusFilter == "downloading" && info.Done()) {
+			continue
 		}
-		entry := map[string]any{
-			"hash":      f.GetInfoHash(),
-			"name":      f.GetName(),
-			"size":      f.GetSize(),
-			"status":    f.GetStatus().String(),
-			"progress":  f.GetPercendDone(),
-			"save_path": d.config.SavePath,
-			"category":  "GotoBangumi",
+		result = append(result, info)
+		if limit > 0 && len(result) >= limit {
+			break
 		}
-		result = append(result, entry)
 	}
 	return result, nil
 }
@@ -399,10 +614,17 @@ func (d *CloudDriveDownloader) CheckHash(ctx context.Context, hash string) (stri
 }
 
 // Rename renames a file at oldPath to newPath on the CloudDrive2 filesystem.
-// torrentHash is unused; oldPath and newPath must be full CloudDrive2 virtual paths.
-// 需要的是旧的完整路径， 新的文件名（不带路径）， 以及torrentHash（目前没用到， 但是接口需要）
+// oldPath may be a full CloudDrive2 virtual path, or relative to the task folder as returned by GetTorrentFiles.
+// 需要的是旧的完整路径， 新的文件名（不带路径）， 相对路径用 torrentHash 找到任务所在的目录
 // 传入的 newPath 是完整的，所以还要提出新的文件名
 func (d *CloudDriveDownloader) Rename(ctx context.Context, torrentHash, oldPath, newPath string) (bool, error) {
+	if !strings.HasPrefix(oldPath, "/") {
+		dir, err := d.taskLocation(ctx, torrentHash)
+		if err != nil {
+			return false, err
+		}
+		oldPath = dir + "/" + oldPath
+	}
 	// newPath should be just the new filename, not the full path
 	newName := newPath
 	if idx := strings.LastIndexByte(newPath, '/'); idx >= 0 {
@@ -426,27 +648,80 @@ func (d *CloudDriveDownloader) Rename(ctx context.Context, torrentHash, oldPath,
 	return true, nil
 }
 
-// Move moves files (identified by their CloudDrive2 paths) to newLocation.
+// Move moves the task folders identified by info hash into newLocation, creating it first.
+// 移动的是任务的整个目录，移动后记录新的位置，之后的查询和重命名都在新目录下进行
 func (d *CloudDriveDownloader) Move(ctx context.Context, hashes []string, newLocation string) (bool, error) {
-	if err := d.wait(ctx); err != nil {
+	offlineFiles, err := d.listOfflineFiles(ctx)
+	if err != nil {
 		return false, err
 	}
-
-	result, err := d.rpc.MoveFile(d.authCtx(ctx), &clouddrive.MoveFileRequest{
-		TheFilePaths: hashes,
-		DestPath:     newLocation,
-	})
-	if err != nil {
-		return false, &apperrors.NetworkError{Err: fmt.Errorf("CloudDrive2 MoveFile: %w", err)}
+	tasks := make(map[string]*clouddrive.OfflineFile, len(offlineFiles))
+	for _, f := range offlineFiles {
+		tasks[strings.ToLower(f.GetInfoHash())] = f
 	}
-	if !result.GetSuccess() {
-		return false, fmt.Errorf("[CloudDrive2] move failed: %s", result.GetErrorMessage())
+	if err := d.mkdirAll(ctx, newLocation); err != nil {
+		return false, err
+	}
+
+	for _, hash := range hashes {
+		task, ok := tasks[strings.ToLower(hash)]
+		if !ok {
+			return false, &apperrors.DownloadKeyError{Err: fmt.Errorf("offline task not found"), Key: hash}
+		}
+		// 重启前已经转移过的任务会在 newLocation 下找回，不需要再移动
+		dir := d.location(ctx, task)
+		if path.Clean(dir) == path.Clean(newLocation) {
+			continue
+		}
+		if err := d.wait(ctx); err != nil {
+			return false, err
+		}
+		result, err := d.rpc.MoveFile(d.authCtx(ctx), &clouddrive.MoveFileRequest{
+			TheFilePaths: []string{dir + "/" + task.GetName()},
+			DestPath:     newLocation,
+		})
+		if err != nil {
+			return false, &apperrors.NetworkError{Err: fmt.Errorf("CloudDrive2 MoveFile: %w", err)}
+		}
+		if !result.GetSuccess() {
+			return false, fmt.Errorf("[CloudDrive2] move failed: %s", result.GetErrorMessage())
+		}
+		d.mu.Lock()
+		d.locations[strings.ToLower(hash)] = newLocation
+		d.mu.Unlock()
 	}
 	return true, nil
 }
 
-// Delete removes offline download tasks by their info hashes.
-func (d *CloudDriveDownloader) Delete(ctx context.Context, hashes []string) (bool, error) {
+// mkdirAll creates every missing folder of dir, CloudDrive2 can only create one level at a time.
+func (d *CloudDriveDownloader) mkdirAll(ctx context.Context, dir string) error {
+	parent := "/"
+	for _, name := range strings.Split(strings.Trim(dir, "/"), "/") {
+		if name == "" {
+			continue
+		}
+		if err := d.wait(ctx); err != nil {
+			return err
+		}
+		if _, err := d.rpc.FindFileByPath(d.authCtx(ctx), &clouddrive.FindFileByPathRequest{ParentPath: parent, Path: name}); err != nil {
+			if err := d.wait(ctx); err != nil {
+				return err
+			}
+			result, err := d.rpc.CreateFolder(d.authCtx(ctx), &clouddrive.CreateFolderRequest{ParentPath: parent, FolderName: name})
+			if err != nil {
+				return &apperrors.NetworkError{Err: fmt.Errorf("CloudDrive2 CreateFolder: %w", err)}
+			}
+			if r := result.GetResult(); r != nil && !r.GetSuccess() {
+				return fmt.Errorf("[CloudDrive2] create folder %s failed: %s", name, r.GetErrorMessage())
+			}
+		}
+		parent = path.Join(parent, name)
+	}
+	return nil
+}
+
+// Delete removes offline download tasks by their info hashes, optionally deleting the files.
+func (d *CloudDriveDownloader) Delete(ctx context.Context, hashes []string, deleteFiles bool) (bool, error) {
 	// 只是无法删除文件，到也是小问题
 	d.mu.RLock()
 	cloudName := d.cloudName
@@ -464,7 +739,7 @@ func (d *CloudDriveDownloader) Delete(ctx context.Context, hashes []string) (boo
 		CloudName:      cloudName,
 		CloudAccountId: cloudAcctID,
 		InfoHashes:     hashes,
-		DeleteFiles:    true,
+		DeleteFiles:    deleteFiles,
 	})
 	if err != nil {
 		return false, &apperrors.NetworkError{Err: fmt.Errorf("CloudDrive2 RemoveOfflineFiles: %w", err)}
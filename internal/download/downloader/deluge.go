@@ -0,0 +1,473 @@
+package downloader
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"goto-bangumi/internal/apperrors"
+	"goto-bangumi/internal/model"
+
+	"github.com/go-resty/resty/v2"
+)
+
+const delugeRPCPath = "/json"
+
+// core.get_torrent_status 需要的字段
+var delugeKeys = []string{
+	"hash", "name", "state", "save_path", "progress", "eta", "is_finished", "completed_time", "label", "files", "file_priorities", "ratio", "seeding_time",
+	"message", "total_wanted", "total_done", "total_uploaded", "download_payload_rate", "upload_payload_rate", "num_seeds", "num_peers",
+}
+
+// DelugeDownloader Deluge Web JSON-RPC 下载器实现
+// 通过 Web UI 的 /json 接口访问，auth.login 成功后使用 cookie 保持会话，
+// Web UI 没有连上 daemon 时会自动连接第一个 host
+type DelugeDownloader struct {
+	client      *resty.Client
+	config      *model.DownloaderConfig
+	APIInterval int // API 调用间隔（毫秒）
+	limiter     *apiLimiter
+	requestID   atomic.Int64
+}
+
+// NewDelugeDownloader 创建新的 Deluge 下载器
+func NewDelugeDownloader() *DelugeDownloader {
+	return &DelugeDownloader{
+		client:      resty.New(),
+		APIInterval: 200,
+		limiter:     newAPILimiter(200 * time.Millisecond),
+	}
+}
+
+// Init 初始化下载器
+func (d *DelugeDownloader) Init(config *model.DownloaderConfig) error {
+	if config == nil {
+		return fmt.Errorf("配置不能为空")
+	}
+	d.config = config
+	if d.limiter == nil {
+		d.limiter = newAPILimiter(time.Duration(d.APIInterval) * time.Millisecond)
+	} else {
+		d.limiter.SetInterval(time.Duration(d.APIInterval) * time.Millisecond)
+	}
+
+	d.client.SetBaseURL(hostURL(config))
+	d.client.SetTimeout(10 * time.Second)
+	d.client.SetHeader("User-Agent", "goto-bangumi")
+	if !config.Ssl {
+		d.client.SetTLSClientConfig(&tls.Config{
+			InsecureSkipVerify: true,
+		})
+	}
+	return nil
+}
+
+func (d *DelugeDownloader) wait(ctx context.Context) error {
+	if d.limiter == nil {
+		return nil
+	}
+	return d.limiter.Wait(ctx, "Deluge")
+}
+
+// delugeError Deluge 返回的 JSON-RPC 错误
+type delugeError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *delugeError) Error() string {
+	return fmt.Sprintf("deluge error %d: %s", e.Code, e.Message)
+}
+
+// call 调用 RPC 方法
+func (d *DelugeDownloader) call(ctx context.Context, method string, result any, params ...any) error {
+	if err := d.wait(ctx); err != nil {
+		return err
+	}
+	if params == nil {
+		params = []any{}
+	}
+
+	resp, err := d.client.R().
+		SetContext(ctx).
+		SetBody(map[string]any{
+			"id":     d.requestID.Add(1),
+			"method": method,
+			"params": params,
+		}).
+		Post(delugeRPCPath)
+	if err != nil {
+		slog.Error("[Deluge] 连接错误", "method", method, "error", err)
+		return &apperrors.NetworkError{Err: fmt.Errorf("连接到Deluge时出错: %w", err)}
+	}
+	if resp.StatusCode() != 200 {
+		return &apperrors.NetworkError{Err: fmt.Errorf("%s 失败：状态码 %d", method, resp.StatusCode()), StatusCode: resp.StatusCode()}
+	}
+
+	var body struct {
+		Result json.RawMessage `json:"result"`
+		Error  *delugeError    `json:"error"`
+	}
+	if err := json.Unmarshal(resp.Body(), &body); err != nil {
+		return fmt.Errorf("解析 %s 响应失败: %w", method, err)
+	}
+	if body.Error != nil {
+		if strings.Contains(body.Error.Message, "Not authenticated") {
+			return &apperrors.DownloadAuthenticationError{Err: body.Error, Name: "deluge-web"}
+		}
+		return body.Error
+	}
+	if result != nil {
+		if err := json.Unmarshal(body.Result, result); err != nil {
+			return fmt.Errorf("解析 %s 响应失败: %w", method, err)
+		}
+	}
+	return nil
+}
+
+// Auth 登录 Web UI，并确保 Web UI 已经连接到 daemon
+func (d *DelugeDownloader) Auth(ctx context.Context) (bool, error) {
+	var ok bool
+	if err := d.call(ctx, "auth.login", &ok, d.config.Password); err != nil {
+		return false, err
+	}
+	if !ok {
+		slog.Error("[Deluge] 登录失败，请检查密码")
+		return false, &apperrors.DownloadAuthenticationError{Err: fmt.Errorf("密码错误"), Name: "deluge-web"}
+	}
+
+	var connected bool
+	if err := d.call(ctx, "web.connected", &connected); err != nil {
+		return false, err
+	}
+	if connected {
+		return true, nil
+	}
+
+	// hosts: [[id, host, port, status], ...]
+	var hosts [][]any
+	if err := d.call(ctx, "web.get_hosts", &hosts); err != nil {
+		return false, err
+	}
+	if len(hosts) == 0 || len(hosts[0]) == 0 {
+		return false, fmt.Errorf("[Deluge] Web UI 没有可用的 daemon")
+	}
+	hostID, _ := hosts[0][0].(string)
+	if err := d.call(ctx, "web.connect", nil, hostID); err != nil {
+		return false, err
+	}
+	slog.Info("[Deluge] 已连接到 daemon", "host", hostID)
+	return true, nil
+}
+
+// Logout 登出
+func (d *DelugeDownloader) Logout(ctx context.Context) (bool, error) {
+	if err := d.call(ctx, "auth.delete_session", nil); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// torrentStatus 获取单个种子状态，不存在时返回 DownloadKeyError
+func (d *DelugeDownloader) torrentStatus(ctx context.Context, hash string) (*model.DelugeTorrentStatus, error) {
+	var status model.DelugeTorrentStatus
+	if err := d.call(ctx, "core.get_torrent_status", &status, hash, delugeKeys); err != nil {
+		return nil, err
+	}
+	// 种子不存在时返回空对象
+	if status.Hash == "" && status.Name == "" {
+		return nil, &apperrors.DownloadKeyError{Err: fmt.Errorf("种子不存在"), Key: hash}
+	}
+	if status.Hash == "" {
+		status.Hash = hash
+	}
+	return &status, nil
+}
+
+// torrentsStatus 按条件获取种子状态，key 为 hash
+func (d *DelugeDownloader) torrentsStatus(ctx context.Context, filter map[string]any) (map[string]*model.DelugeTorrentStatus, error) {
+	result := make(map[string]*model.DelugeTorrentStatus)
+	if err := d.call(ctx, "core.get_torrents_status", &result, filter, delugeKeys); err != nil {
+		return nil, err
+	}
+	for hash, s := range result {
+		if s.Hash == "" {
+			s.Hash = hash
+		}
+	}
+	return result, nil
+}
+
+// delugeInfo 转换为统一的种子状态
+func delugeInfo(s *model.DelugeTorrentStatus) *model.TorrentStatus {
+	info := &model.TorrentStatus{
+		Hash:          s.Hash,
+		Name:          s.Name,
+		State:         delugeState(s),
+		Progress:      s.Progress / 100,
+		Size:          s.TotalWanted,
+		Downloaded:    s.TotalDone,
+		Uploaded:      s.TotalUploaded,
+		DownloadSpeed: s.DownloadRate,
+		UploadSpeed:   s.UploadRate,
+		ETA:           int(s.Eta),
+		Ratio:         s.Ratio,
+		SeedingTime:   s.SeedingTime,
+		SavePath:      s.SavePath,
+		Category:      s.Label,
+	}
+	// 正常时 message 为 "OK"
+	if s.Message != "OK" {
+		info.Message = s.Message
+	}
+	if info.ETA <= 0 {
+		info.ETA = -1
+	}
+	if s.IsFinished || s.Progress >= 100 {
+		info.ETA = 0
+		info.Completed = int(s.CompletedTime)
+		if info.Completed <= 0 {
+			info.Completed = int(time.Now().Unix())
+		}
+	}
+	return info
+}
+
+// delugeState 归一化 Deluge 的种子状态
+func delugeState(s *model.DelugeTorrentStatus) model.DownloadState {
+	done := s.IsFinished || s.Progress >= 100
+	switch s.State {
+	case "Downloading":
+		if s.DownloadRate == 0 && s.NumSeeds == 0 && s.NumPeers == 0 {
+			return model.DownloadStateStalled
+		}
+		return model.DownloadStateDownloading
+	case "Seeding":
+		return model.DownloadStateSeeding
+	case "Queued", "Allocating":
+		if done {
+			return model.DownloadStateCompleted
+		}
+		return model.DownloadStateWaiting
+	case "Checking", "Moving":
+		return model.DownloadStateChecking
+	case "Paused":
+		if done {
+			return model.DownloadStateCompleted
+		}
+		return model.DownloadStatePaused
+	case "Error":
+		if strings.Contains(strings.ToLower(s.Message), "missing") {
+			return model.DownloadStateMissingFiles
+		}
+		return model.DownloadStateFailed
+	default:
+		return model.DownloadStateUnknown
+	}
+}
+
+// GetTorrentFiles 获取种子文件列表
+func (d *DelugeDownloader) GetTorrentFiles(ctx context.Context, hash string) ([]string, error) {
+	s, err := d.torrentStatus(ctx, hash)
+	if err != nil {
+		if apperrors.IsKeyError(err) {
+			slog.Warn("[Deluge] 找不到种子", "hash", hash)
+			return nil, nil
+		}
+		return nil, err
+	}
+	files := make([]string, 0, len(s.Files))
+	for _, f := range s.Files {
+		// 优先级为 0 的文件不会下载
+		if f.Index < len(s.FilePriority) && s.FilePriority[f.Index] == 0 {
+			continue
+		}
+		files = append(files, f.Path)
+	}
+	return files, nil
+}
+
+// GetTorrentInfo 获取单个种子信息
+func (d *DelugeDownloader) GetTorrentInfo(ctx context.Context, hash string) (*model.TorrentStatus, error) {
+	s, err := d.torrentStatus(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	return delugeInfo(s), nil
+}
+
+// GetTorrentsInfo 一次 core.get_torrents_status 查询多个种子
+func (d *DelugeDownloader) GetTorrentsInfo(ctx context.Context, hashes []string) (map[string]*model.TorrentStatus, error) {
+	result := make(map[string]*model.TorrentStatus, len(hashes))
+	if len(hashes) == 0 {
+		return result, nil
+	}
+	statuses, err := d.torrentsStatus(ctx, map[string]any{"id": hashes})
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[string]*model.TorrentStatus, len(statuses))
+	for hash, s := range statuses {
+		found[strings.ToLower(hash)] = delugeInfo(s)
+	}
+	for _, hash := range hashes {
+		if info, ok := found[strings.ToLower(hash)]; ok {
+			result[hash] = info
+		}
+	}
+	return result, nil
+}
+
+// TorrentsInfo 获取种子信息列表，category 对应 Label 插件的标签
+func (d *DelugeDownloader) TorrentsInfo(ctx context.Context, statusFilter, category string, tag *string, limit int) ([]*model.TorrentStatus, error) {
+	filter := map[string]any{}
+	if label := delugeLabel(category); label != "" {
+		filter["label"] = label
+	}
+	statuses, err := d.torrentsStatus(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.TorrentStatus, 0, len(statuses))
+	for _, s := range statuses {
+		info := delugeInfo(s)
+		done := info.Completed > 0
+		if (statusFilter == "completed" && !done) || (statusFilter == "downloading" && done) {
+			continue
+		}
+		result = append(result, info)
+		if limit > 0 && len(result) >= limit {
+			break
+		}
+	}
+	return result, nil
+}
+
+// CheckHash 检查种子是否存在
+func (d *DelugeDownloader) CheckHash(ctx context.Context, hash string) (string, error) {
+	info, err := d.GetTorrentInfo(ctx, hash)
+	if info != nil {
+		return hash, nil
+	}
+	return hash, err
+}
+
+// Add 添加种子，savePath 为相对路径时放在配置的下载目录下
+func (d *DelugeDownloader) Add(ctx context.Context, torrentInfo *model.TorrentInfo, savePath string) ([]string, error) {
+	options := map[string]any{
+		"download_location": d.downloadDir(savePath),
+		"add_paused":        false,
+	}
+	if len(torrentInfo.SkipFiles) > 0 {
+		priorities := make([]int, len(torrentInfo.Files))
+		for i := range priorities {
+			priorities[i] = 1
+		}
+		for _, i := range torrentInfo.SkipFiles {
+			priorities[i] = 0
+		}
+		options["file_priorities"] = priorities
+	}
+
+	var hash *string
+	var err error
+	if len(torrentInfo.File) > 0 {
+		encoded := base64.StdEncoding.EncodeToString(torrentInfo.File)
+		err = d.call(ctx, "core.add_torrent_file", &hash, torrentInfo.Name+".torrent", encoded, options)
+	} else {
+		err = d.call(ctx, "core.add_torrent_magnet", &hash, torrentInfo.MagnetURI, options)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make([]string, 0, 2)
+	if hash != nil && *hash != "" {
+		hashes = append(hashes, *hash)
+	}
+	if torrentInfo.InfoHashV1 != "" && (hash == nil || !strings.EqualFold(*hash, torrentInfo.InfoHashV1)) {
+		hashes = append(hashes, torrentInfo.InfoHashV1)
+	}
+	if len(hashes) == 0 {
+		return nil, fmt.Errorf("[Deluge]添加种子失败: 没有返回种子 hash")
+	}
+	d.setLabel(ctx, hashes[0])
+	return hashes, nil
+}
+
+// setLabel 把配置的分类设置为 Label 插件的标签，对账时按标签区分自己添加的种子
+// Label 插件未启用时只记录警告，不影响添加
+func (d *DelugeDownloader) setLabel(ctx context.Context, hash string) {
+	label := delugeLabel(d.config.Category)
+	if label == "" {
+		return
+	}
+	// 标签已经存在时 label.add 会返回错误，可以忽略
+	_ = d.call(ctx, "label.add", nil, label)
+	if err := d.call(ctx, "label.set_torrent", nil, hash, label); err != nil {
+		slog.Warn("[Deluge] 设置标签失败，请检查 Label 插件是否启用", "label", label, "error", err)
+	}
+}
+
+// delugeLabel Label 插件的标签只能是小写
+func delugeLabel(category string) string {
+	return strings.ToLower(category)
+}
+
+func (d *DelugeDownloader) downloadDir(savePath string) string {
+	if savePath == "" || path.IsAbs(savePath) {
+		return savePath
+	}
+	return path.Join(d.config.SavePath, savePath)
+}
+
+// Delete 删除种子，deleteFiles 为 true 时同时删除数据
+func (d *DelugeDownloader) Delete(ctx context.Context, hashes []string, deleteFiles bool) (bool, error) {
+	for _, hash := range hashes {
+		var removed bool
+		if err := d.call(ctx, "core.remove_torrent", &removed, hash, deleteFiles); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// Rename 重命名种子文件，core.rename_files 需要文件的 index
+func (d *DelugeDownloader) Rename(ctx context.Context, torrentHash, oldPath, newPath string) (bool, error) {
+	s, err := d.torrentStatus(ctx, torrentHash)
+	if err != nil {
+		return false, err
+	}
+	index := -1
+	for _, f := range s.Files {
+		if f.Path == oldPath {
+			index = f.Index
+			break
+		}
+	}
+	if index < 0 {
+		return false, &apperrors.DownloadKeyError{Err: fmt.Errorf("文件不存在: %s", oldPath), Key: torrentHash}
+	}
+
+	if err := d.call(ctx, "core.rename_files", nil, torrentHash, [][]any{{index, newPath}}); err != nil {
+		slog.Error("[Deluge] 重命名错误", "oldPath", oldPath, "newPath", newPath, "error", err)
+		return false, err
+	}
+	return true, nil
+}
+
+// Move 移动种子到新位置
+func (d *DelugeDownloader) Move(ctx context.Context, hashes []string, newLocation string) (bool, error) {
+	if err := d.call(ctx, "core.move_storage", nil, hashes, newLocation); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,238 @@
+package downloader
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"goto-bangumi/internal/apperrors"
+	"goto-bangumi/internal/model"
+)
+
+// fakeDeluge 进程内的 Deluge Web JSON-RPC 服务
+type fakeDeluge struct {
+	mu        sync.Mutex
+	connected bool
+	torrents  map[string]*model.DelugeTorrentStatus
+	lastAdd   map[string]any
+}
+
+func newFakeDeluge(t *testing.T) (*fakeDeluge, *httptest.Server) {
+	t.Helper()
+	f := &fakeDeluge{torrents: make(map[string]*model.DelugeTorrentStatus)}
+	srv := httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func (f *fakeDeluge) serve(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID     int64             `json:"id"`
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+	reply := func(result any, rpcErr *delugeError) {
+		json.NewEncoder(w).Encode(map[string]any{"id": req.ID, "result": result, "error": rpcErr})
+	}
+	param := func(i int, v any) { json.Unmarshal(req.Params[i], v) }
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if req.Method == "auth.login" {
+		var password string
+		param(0, &password)
+		if password != "deluge" {
+			reply(false, nil)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "_session_id", Value: "session"})
+		reply(true, nil)
+		return
+	}
+	if c, err := r.Cookie("_session_id"); err != nil || c.Value != "session" {
+		reply(nil, &delugeError{Code: 1, Message: "Not authenticated"})
+		return
+	}
+
+	switch req.Method {
+	case "web.connected":
+		reply(f.connected, nil)
+	case "web.get_hosts":
+		reply([][]any{{"host-1", "127.0.0.1", 58846, "Online"}}, nil)
+	case "web.connect":
+		f.connected = true
+		reply([]string{}, nil)
+	case "core.add_torrent_file", "core.add_torrent_magnet":
+		var options map[string]any
+		param(len(req.Params)-1, &options)
+		f.lastAdd = options
+		hash := "1317e47882474c771e29ed2271b282fbfb56e7d2"
+		f.torrents[hash] = &model.DelugeTorrentStatus{
+			Hash:     hash,
+			Name:     "[Lilith-Raws] Sousou no Frieren - 01",
+			State:    "Downloading",
+			SavePath: options["download_location"].(string),
+			Progress: 50,
+			Eta:      90,
+			Files:    []model.DelugeFile{{Index: 0, Path: "[Lilith-Raws] Sousou no Frieren - 01.mkv"}},
+		}
+		reply(hash, nil)
+	case "core.get_torrent_status":
+		var hash string
+		param(0, &hash)
+		if s, ok := f.torrents[hash]; ok {
+			reply(s, nil)
+			return
+		}
+		reply(map[string]any{}, nil)
+	case "core.get_torrents_status":
+		var filter map[string][]string
+		param(0, &filter)
+		result := map[string]*model.DelugeTorrentStatus{}
+		for _, hash := range filter["id"] {
+			if s, ok := f.torrents[hash]; ok {
+				result[hash] = s
+			}
+		}
+		reply(result, nil)
+	case "core.rename_files":
+		var hash string
+		var renames [][]any
+		param(0, &hash)
+		param(1, &renames)
+		for _, rn := range renames {
+			f.torrents[hash].Files[int(rn[0].(float64))].Path = rn[1].(string)
+		}
+		reply(nil, nil)
+	case "core.move_storage":
+		var hashes []string
+		var dest string
+		param(0, &hashes)
+		param(1, &dest)
+		for _, hash := range hashes {
+			f.torrents[hash].SavePath = dest
+		}
+		reply(nil, nil)
+	case "label.add":
+		reply(nil, nil)
+	case "label.set_torrent":
+		var hash, label string
+		param(0, &hash)
+		param(1, &label)
+		f.torrents[hash].Label = label
+		reply(nil, nil)
+	case "core.remove_torrent":
+		var hash string
+		param(0, &hash)
+		delete(f.torrents, hash)
+		reply(true, nil)
+	default:
+		reply(nil, &delugeError{Code: 2, Message: "Unknown method"})
+	}
+}
+
+func newTestDeluge(t *testing.T, host, password string) *DelugeDownloader {
+	t.Helper()
+	d := NewDelugeDownloader()
+	d.APIInterval = 0
+	err := d.Init(&model.DownloaderConfig{
+		Type:     "deluge",
+		SavePath: "/downloads/Bangumi",
+		Host:     host,
+		Password: password,
+	})
+	if err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	return d
+}
+
+func TestDeluge_AuthConnectsDaemon(t *testing.T) {
+	f, srv := newFakeDeluge(t)
+	ctx := context.Background()
+
+	_, err := newTestDeluge(t, srv.URL, "wrong").Auth(ctx)
+	if !apperrors.IsDownloadAuthenticationError(err) {
+		t.Fatalf("Auth() with wrong password error = %v, want DownloadAuthenticationError", err)
+	}
+
+	d := newTestDeluge(t, srv.URL, "deluge")
+	if _, err := d.GetTorrentInfo(ctx, "hash"); !apperrors.IsDownloadAuthenticationError(err) {
+		t.Fatalf("request before login error = %v, want DownloadAuthenticationError", err)
+	}
+	if ok, err := d.Auth(ctx); err != nil || !ok {
+		t.Fatalf("Auth() = %v, %v, want true", ok, err)
+	}
+	if !f.connected {
+		t.Error("Auth() did not connect the web UI to the daemon")
+	}
+}
+
+func TestDeluge_TorrentLifecycle(t *testing.T) {
+	f, srv := newFakeDeluge(t)
+	d := newTestDeluge(t, srv.URL, "deluge")
+	ctx := context.Background()
+	if _, err := d.Auth(ctx); err != nil {
+		t.Fatalf("Auth error: %v", err)
+	}
+	hash := "1317e47882474c771e29ed2271b282fbfb56e7d2"
+	d.config.Category = "GotoBangumi"
+
+	hashes, err := d.Add(ctx, &model.TorrentInfo{Name: "Frieren", InfoHashV1: hash, File: []byte("d4:infoe")}, "Frieren/Season 1")
+	if err != nil {
+		t.Fatalf("Add error: %v", err)
+	}
+	if len(hashes) != 1 || hashes[0] != hash {
+		t.Fatalf("Add hashes = %v", hashes)
+	}
+	if loc := f.lastAdd["download_location"]; loc != "/downloads/Bangumi/Frieren/Season 1" {
+		t.Errorf("download_location = %v", loc)
+	}
+
+	info, err := d.GetTorrentInfo(ctx, hash)
+	if err != nil || info.ETA != 90 || info.Completed != 0 || info.Category != "gotobangumi" {
+		t.Fatalf("GetTorrentInfo = %+v, %v", info, err)
+	}
+	if _, err := d.GetTorrentInfo(ctx, "missing"); !apperrors.IsKeyError(err) {
+		t.Fatalf("GetTorrentInfo(missing) error = %v, want key error", err)
+	}
+
+	f.mu.Lock()
+	f.torrents[hash].IsFinished = true
+	f.torrents[hash].CompletedTime = 1700000000
+	f.mu.Unlock()
+	infos, err := d.GetTorrentsInfo(ctx, []string{hash, "missing"})
+	if err != nil || len(infos) != 1 || infos[hash].Completed != 1700000000 {
+		t.Fatalf("GetTorrentsInfo = %+v, %v", infos, err)
+	}
+
+	files, _ := d.GetTorrentFiles(ctx, hash)
+	if _, err := d.Rename(ctx, hash, files[0], "Frieren S01E01.mkv"); err != nil {
+		t.Fatalf("Rename error: %v", err)
+	}
+	if files, _ := d.GetTorrentFiles(ctx, hash); files[0] != "Frieren S01E01.mkv" {
+		t.Errorf("files after rename = %v", files)
+	}
+	if _, err := d.Rename(ctx, hash, "not-exist.mkv", "x.mkv"); !apperrors.IsKeyError(err) {
+		t.Errorf("Rename of unknown file error = %v, want key error", err)
+	}
+
+	if _, err := d.Move(ctx, []string{hash}, "/media/Bangumi"); err != nil {
+		t.Fatalf("Move error: %v", err)
+	}
+	if info, _ := d.GetTorrentInfo(ctx, hash); info.SavePath != "/media/Bangumi" {
+		t.Errorf("SavePath after Move = %q", info.SavePath)
+	}
+
+	if _, err := d.Delete(ctx, []string{hash}, true); err != nil {
+		t.Fatalf("Delete error: %v", err)
+	}
+	if _, err := d.CheckHash(ctx, hash); !apperrors.IsKeyError(err) {
+		t.Fatalf("CheckHash after Delete error = %v, want key error", err)
+	}
+}
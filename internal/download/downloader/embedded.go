@@ -0,0 +1,711 @@
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"goto-bangumi/internal/apperrors"
+	"goto-bangumi/internal/model"
+
+	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/anacrolix/torrent/storage"
+)
+
+/*
+内置下载器的几点说明
+1: 在进程内运行 anacrolix/torrent 的 Client，数据直接写到 SavePath 下，不需要外部下载器
+2: 每个种子单独一个文件存储，保存目录和重命名记录在 StateDir 下的 json 里，重启后重新加入继续做种
+3: 重命名和移动直接操作本地文件，操作前先把种子从 Client 中移除，操作后按新的路径重新加入，
+   未完成的文件带 .part 后缀，重新加入时根据文件名判断完成情况，不需要重新校验
+4: 做种达到配置的分享率后把种子从 Client 中移除，只保留状态，文件不动
+*/
+
+// embeddedSeedCheckInterval 检查做种分享率的间隔
+const embeddedSeedCheckInterval = 30 * time.Second
+
+// EmbeddedDownloader 基于 anacrolix/torrent 的内置下载器
+type EmbeddedDownloader struct {
+	// StateDir 保存种子状态的目录
+	StateDir string
+
+	config *model.DownloaderConfig
+	client *torrent.Client
+	// configure 创建 Client 前调整配置，测试中用来关闭 DHT 等网络功能
+	configure func(*torrent.ClientConfig)
+
+	mu       sync.Mutex
+	torrents map[string]*embeddedTorrent // key 为小写的 info hash
+	stop     chan struct{}
+}
+
+// embeddedState 持久化的种子状态
+type embeddedState struct {
+	Hash      string            `json:"hash"`
+	Dir       string            `json:"dir"`
+	Torrent   []byte            `json:"torrent,omitempty"` // 种子文件，磁力链接在拿到元数据后补上
+	Magnet    string            `json:"magnet,omitempty"`
+	Files     []string          `json:"files,omitempty"`   // 种子内的原始相对路径
+	Renames   map[string]string `json:"renames,omitempty"` // 原始相对路径 -> 当前相对路径
+	Skip      []string          `json:"skip,omitempty"`    // 不下载的文件的原始相对路径
+	Added     int64             `json:"added"`
+	Completed int64             `json:"completed"`
+	Uploaded  int64             `json:"uploaded"` // 之前的 Client 会话中已上传的字节数
+	Stopped   bool              `json:"stopped"`  // 已停止做种
+}
+
+type embeddedTorrent struct {
+	embeddedState
+	t *torrent.Torrent
+}
+
+// NewEmbeddedDownloader 创建新的内置下载器
+func NewEmbeddedDownloader() *EmbeddedDownloader {
+	return &EmbeddedDownloader{
+		StateDir: filepath.Join("data", "torrents"),
+		torrents: make(map[string]*embeddedTorrent),
+	}
+}
+
+// Init 启动 torrent Client 并恢复之前的种子
+func (d *EmbeddedDownloader) Init(config *model.DownloaderConfig) error {
+	if config == nil {
+		return fmt.Errorf("配置不能为空")
+	}
+	d.close()
+	d.config = config
+
+	cfg := torrent.NewDefaultClientConfig()
+	cfg.DataDir = config.SavePath
+	cfg.ListenPort = config.ListenPort
+	cfg.Seed = true
+	if d.configure != nil {
+		d.configure(cfg)
+	}
+	client, err := torrent.NewClient(cfg)
+	if err != nil {
+		return fmt.Errorf("[embedded] 启动 torrent Client 失败: %w", err)
+	}
+	d.client = client
+	d.stop = make(chan struct{})
+
+	if err := os.MkdirAll(d.StateDir, 0o755); err != nil {
+		return err
+	}
+	d.restore()
+	go d.seedLoop(d.stop)
+	return nil
+}
+
+// close 关闭当前的 Client
+func (d *EmbeddedDownloader) close() {
+	if d.client == nil {
+		return
+	}
+	close(d.stop)
+	d.client.Close()
+	d.client = nil
+	d.mu.Lock()
+	d.torrents = make(map[string]*embeddedTorrent)
+	d.mu.Unlock()
+}
+
+// restore 从状态目录恢复种子
+func (d *EmbeddedDownloader) restore() {
+	entries, err := os.ReadDir(d.StateDir)
+	if err != nil {
+		slog.Warn("[embedded] 读取状态目录失败", "dir", d.StateDir, "error", err)
+		return
+	}
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(d.StateDir, e.Name()))
+		if err != nil {
+			continue
+		}
+		et := &embeddedTorrent{}
+		if err := json.Unmarshal(data, &et.embeddedState); err != nil {
+			slog.Warn("[embedded] 解析种子状态失败", "file", e.Name(), "error", err)
+			continue
+		}
+		d.mu.Lock()
+		d.torrents[et.Hash] = et
+		d.mu.Unlock()
+		if et.Stopped {
+			continue
+		}
+		if err := d.start(et); err != nil {
+			slog.Warn("[embedded] 恢复种子失败", "hash", et.Hash, "error", err)
+		}
+	}
+}
+
+// saveState 写入种子状态，调用方持有 d.mu
+func (d *EmbeddedDownloader) saveState(et *embeddedTorrent) {
+	data, err := json.Marshal(&et.embeddedState)
+	if err != nil {
+		return
+	}
+	if err := os.WriteFile(filepath.Join(d.StateDir, et.Hash+".json"), data, 0o644); err != nil {
+		slog.Warn("[embedded] 保存种子状态失败", "hash", et.Hash, "error", err)
+	}
+}
+
+// spec 根据保存的种子文件或磁力链接生成 TorrentSpec
+func (et *embeddedTorrent) spec() (*torrent.TorrentSpec, error) {
+	if len(et.Torrent) > 0 {
+		mi, err := metainfo.Load(bytes.NewReader(et.Torrent))
+		if err != nil {
+			return nil, fmt.Errorf("解析种子文件失败: %w", err)
+		}
+		return torrent.TorrentSpecFromMetaInfoErr(mi)
+	}
+	return torrent.TorrentSpecFromMagnetUri(et.Magnet)
+}
+
+// start 把种子加入 Client，文件路径按重命名记录生成
+func (d *EmbeddedDownloader) start(et *embeddedTorrent) error {
+	spec, err := et.spec()
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	dir := et.Dir
+	renames := make(map[string]string, len(et.Renames))
+	for k, v := range et.Renames {
+		renames[k] = v
+	}
+	d.mu.Unlock()
+
+	spec.Storage = storage.NewFileOpts(storage.NewFileClientOpts{
+		ClientBaseDir: dir,
+		FilePathMaker: func(opts storage.FilePathMakerOpts) string {
+			orig := originalPath(opts.Info, opts.File)
+			if cur, ok := renames[orig]; ok {
+				return filepath.FromSlash(cur)
+			}
+			return filepath.FromSlash(orig)
+		},
+	})
+	t, _, err := d.client.AddTorrentSpec(spec)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	et.t = t
+	d.mu.Unlock()
+
+	go func() {
+		select {
+		case <-t.GotInfo():
+		case <-t.Closed():
+			return
+		}
+		d.mu.Lock()
+		if len(et.Files) == 0 {
+			for _, f := range t.Files() {
+				et.Files = append(et.Files, f.Path())
+			}
+		}
+		if len(et.Torrent) == 0 {
+			var buf bytes.Buffer
+			mi := t.Metainfo()
+			if err := mi.Write(&buf); err == nil {
+				et.Torrent = buf.Bytes()
+			}
+		}
+		skip := et.skipped()
+		d.saveState(et)
+		d.mu.Unlock()
+		for _, f := range t.Files() {
+			if skip[f.Path()] {
+				f.SetPriority(torrent.PiecePriorityNone)
+				continue
+			}
+			f.Download()
+		}
+	}()
+	return nil
+}
+
+// skipped 不下载的文件集合，调用方持有 d.mu
+func (et *embeddedTorrent) skipped() map[string]bool {
+	skip := make(map[string]bool, len(et.Skip))
+	for _, f := range et.Skip {
+		skip[f] = true
+	}
+	return skip
+}
+
+// wantedComplete 需要下载的文件是否都已完成，跳过的文件所在的分片不会下载，不能用 Complete 判断
+func wantedComplete(t *torrent.Torrent, skip map[string]bool) bool {
+	if len(skip) == 0 {
+		return t.Complete().Bool()
+	}
+	for _, f := range t.Files() {
+		if !skip[f.Path()] && f.BytesCompleted() < f.Length() {
+			return false
+		}
+	}
+	return true
+}
+
+// originalPath 种子内文件相对保存目录的路径，和 File.Path 一致
+func originalPath(info *metainfo.Info, file *metainfo.FileInfo) string {
+	var parts []string
+	if info.BestName() != metainfo.NoName {
+		parts = append(parts, info.BestName())
+	}
+	return path.Join(append(parts, file.BestPath()...)...)
+}
+
+// pause 把种子从 Client 中移除并累计上传量，调用方持有 d.mu
+func (d *EmbeddedDownloader) pause(et *embeddedTorrent) *torrent.Torrent {
+	t := et.t
+	if t == nil {
+		return nil
+	}
+	et.Uploaded += uploadedBytes(t)
+	et.t = nil
+	return t
+}
+
+// uploadedBytes 当前会话中已上传的数据量
+func uploadedBytes(t *torrent.Torrent) int64 {
+	stats := t.Stats()
+	return stats.BytesWrittenData.Int64()
+}
+
+// refresh 更新完成时间，调用方持有 d.mu
+func (d *EmbeddedDownloader) refresh(et *embeddedTorrent) {
+	if et.Completed != 0 || et.t == nil || et.t.Info() == nil {
+		return
+	}
+	if wantedComplete(et.t, et.skipped()) {
+		et.Completed = time.Now().Unix()
+		d.saveState(et)
+	}
+}
+
+// seedLoop 定时检查分享率
+func (d *EmbeddedDownloader) seedLoop(stop chan struct{}) {
+	ticker := time.NewTicker(embeddedSeedCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			d.checkSeeding()
+		}
+	}
+}
+
+// checkSeeding 停止分享率已达标的种子
+func (d *EmbeddedDownloader) checkSeeding() {
+	ratio := d.config.SeedRatio
+	if ratio < 0 {
+		return
+	}
+	var drops []*torrent.Torrent
+	d.mu.Lock()
+	for _, et := range d.torrents {
+		d.refresh(et)
+		if et.Completed == 0 || et.t == nil {
+			continue
+		}
+		uploaded := et.Uploaded + uploadedBytes(et.t)
+		if float64(uploaded) < ratio*float64(et.t.Length()) {
+			continue
+		}
+		slog.Info("[embedded] 达到分享率，停止做种", "hash", et.Hash, "uploaded", uploaded)
+		drops = append(drops, d.pause(et))
+		et.Stopped = true
+		d.saveState(et)
+	}
+	d.mu.Unlock()
+	for _, t := range drops {
+		t.Drop()
+	}
+}
+
+// Auth 内置下载器不需要认证，只检查 Client 是否已启动
+func (d *EmbeddedDownloader) Auth(ctx context.Context) (bool, error) {
+	if d.client == nil {
+		return false, fmt.Errorf("[embedded] 下载器未初始化")
+	}
+	return true, nil
+}
+
+// Logout 内置下载器不需要登出
+func (d *EmbeddedDownloader) Logout(ctx context.Context) (bool, error) {
+	return true, nil
+}
+
+// lookup 按 hash 查找种子，调用方持有 d.mu
+func (d *EmbeddedDownloader) lookup(hash string) (*embeddedTorrent, error) {
+	et, ok := d.torrents[strings.ToLower(hash)]
+	if !ok {
+		return nil, &apperrors.DownloadKeyError{Err: fmt.Errorf("种子不存在"), Key: hash}
+	}
+	return et, nil
+}
+
+// info 转换为统一的种子状态，调用方持有 d.mu
+func (d *EmbeddedDownloader) info(et *embeddedTorrent) *model.TorrentStatus {
+	d.refresh(et)
+	info := &model.TorrentStatus{
+		Hash:        et.Hash,
+		State:       model.DownloadStatePaused,
+		ETA:         -1,
+		Ratio:       -1,
+		SeedingTime: -1,
+		Uploaded:    et.Uploaded,
+		SavePath:    et.Dir,
+		Completed:   int(et.Completed),
+	}
+	if et.t != nil {
+		info.Uploaded += uploadedBytes(et.t)
+		if et.t.Info() != nil {
+			info.Name = et.t.Name()
+			info.Size = et.t.Length()
+			info.Downloaded = info.Size - et.t.BytesMissing()
+			if info.Size > 0 {
+				info.Progress = float64(info.Downloaded) / float64(info.Size)
+				info.Ratio = float64(info.Uploaded) / float64(info.Size)
+			}
+		}
+	}
+	if et.Completed != 0 {
+		info.ETA = 0
+		info.Progress = 1
+		info.SeedingTime = time.Now().Unix() - et.Completed
+		info.State = model.DownloadStateCompleted
+		if et.t != nil {
+			info.State = model.DownloadStateSeeding
+		}
+		return info
+	}
+	if et.t == nil {
+		return info
+	}
+	if et.t.Info() == nil {
+		info.State = model.DownloadStateMetadata
+		return info
+	}
+	stats := et.t.Stats()
+	info.State = model.DownloadStateDownloading
+	if stats.ActivePeers == 0 {
+		info.State = model.DownloadStateStalled
+	}
+	// 按加入以来的平均速度估算剩余时间和速度
+	elapsed := time.Now().Unix() - et.Added
+	read := stats.BytesReadUsefulData.Int64()
+	if elapsed > 0 && read > 0 {
+		info.ETA = int(et.t.BytesMissing() * elapsed / read)
+		info.DownloadSpeed = read / elapsed
+	}
+	return info
+}
+
+// GetTorrentInfo 获取种子的下载进度
+func (d *EmbeddedDownloader) GetTorrentInfo(ctx context.Context, hash string) (*model.TorrentStatus, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	et, err := d.lookup(hash)
+	if err != nil {
+		return nil, err
+	}
+	return d.info(et), nil
+}
+
+// GetTorrentsInfo 批量获取种子的下载进度
+func (d *EmbeddedDownloader) GetTorrentsInfo(ctx context.Context, hashes []string) (map[string]*model.TorrentStatus, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	result := make(map[string]*model.TorrentStatus, len(hashes))
+	for _, hash := range hashes {
+		if et, err := d.lookup(hash); err == nil {
+			result[hash] = d.info(et)
+		}
+	}
+	return result, nil
+}
+
+// TorrentsInfo 获取种子列表，内置下载器不支持分类和标签
+func (d *EmbeddedDownloader) TorrentsInfo(ctx context.Context, statusFilter, category string, tag *string, limit int) ([]*model.TorrentStatus, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	result := make([]*model.TorrentStatus, 0, len(d.torrents))
+	for _, et := range d.torrents {
+		info := d.info(et)
+		if (statusFilter == "completed" && !info.Done()) || (statusFilter == "downloading" && info.Done()) {
+			continue
+		}
+		result = append(result, info)
+		if limit > 0 && len(result) >= limit {
+			break
+		}
+	}
+	return result, nil
+}
+
+// CheckHash 检查种子是否存在
+func (d *EmbeddedDownloader) CheckHash(ctx context.Context, hash string) (string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, err := d.lookup(hash); err != nil {
+		return "", err
+	}
+	return hash, nil
+}
+
+// Add 添加种子，优先使用种子文件，没有时使用磁力链接
+func (d *EmbeddedDownloader) Add(ctx context.Context, torrentInfo *model.TorrentInfo, savePath string) ([]string, error) {
+	if d.client == nil {
+		return nil, fmt.Errorf("[embedded] 下载器未初始化")
+	}
+	hash := strings.ToLower(torrentInfo.InfoHashV1)
+	if hash == "" {
+		return nil, fmt.Errorf("[embedded] 缺少 v1 info hash")
+	}
+	if len(torrentInfo.File) == 0 && torrentInfo.MagnetURI == "" {
+		return nil, fmt.Errorf("[embedded] 没有种子文件或磁力链接")
+	}
+
+	d.mu.Lock()
+	if _, ok := d.torrents[hash]; ok {
+		d.mu.Unlock()
+		slog.Debug("[embedded] 种子已存在", "hash", hash)
+		return []string{hash}, nil
+	}
+	dir := savePath
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(d.config.SavePath, savePath)
+	}
+	et := &embeddedTorrent{embeddedState: embeddedState{
+		Hash:    hash,
+		Dir:     dir,
+		Torrent: torrentInfo.File,
+		Magnet:  torrentInfo.MagnetURI,
+		Added:   time.Now().Unix(),
+	}}
+	for f := range torrentInfo.SkipPaths() {
+		et.Skip = append(et.Skip, f)
+	}
+	sort.Strings(et.Skip)
+	d.torrents[hash] = et
+	d.saveState(et)
+	d.mu.Unlock()
+
+	if err := d.start(et); err != nil {
+		d.mu.Lock()
+		delete(d.torrents, hash)
+		os.Remove(filepath.Join(d.StateDir, hash+".json"))
+		d.mu.Unlock()
+		return nil, fmt.Errorf("[embedded] 添加种子失败: %w", err)
+	}
+	return []string{hash}, nil
+}
+
+// GetTorrentFiles 获取种子内需要下载的文件当前的相对路径
+func (d *EmbeddedDownloader) GetTorrentFiles(ctx context.Context, hash string) ([]string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	et, err := d.lookup(hash)
+	if err != nil {
+		return nil, err
+	}
+	return et.currentFiles(false), nil
+}
+
+// currentFiles 应用重命名后的文件列表，withSkipped 为 false 时不包含跳过的文件
+func (et *embeddedTorrent) currentFiles(withSkipped bool) []string {
+	skip := et.skipped()
+	files := make([]string, 0, len(et.Files))
+	for _, f := range et.Files {
+		if !withSkipped && skip[f] {
+			continue
+		}
+		if cur, ok := et.Renames[f]; ok {
+			f = cur
+		}
+		files = append(files, f)
+	}
+	return files
+}
+
+// completed 返回已完成的种子，未完成时不允许操作文件，调用方持有 d.mu
+func (d *EmbeddedDownloader) completed(hash string) (*embeddedTorrent, error) {
+	et, err := d.lookup(hash)
+	if err != nil {
+		return nil, err
+	}
+	d.refresh(et)
+	if et.Completed == 0 {
+		return nil, fmt.Errorf("[embedded] 种子未完成，不能操作文件: %s", hash)
+	}
+	return et, nil
+}
+
+// relocate 暂停种子后执行文件操作，再按新的路径重新加入继续做种
+func (d *EmbeddedDownloader) relocate(et *embeddedTorrent, op func() error) error {
+	d.mu.Lock()
+	t := d.pause(et)
+	d.mu.Unlock()
+	if t != nil {
+		t.Drop()
+	}
+
+	err := op()
+
+	d.mu.Lock()
+	d.saveState(et)
+	stopped := et.Stopped
+	d.mu.Unlock()
+	if !stopped {
+		if startErr := d.start(et); startErr != nil {
+			return errors.Join(err, startErr)
+		}
+	}
+	return err
+}
+
+// Rename 在本地文件系统上重命名文件
+func (d *EmbeddedDownloader) Rename(ctx context.Context, torrentHash, oldPath, newPath string) (bool, error) {
+	d.mu.Lock()
+	et, err := d.completed(torrentHash)
+	if err != nil {
+		d.mu.Unlock()
+		return false, err
+	}
+	orig := ""
+	for _, f := range et.Files {
+		cur := f
+		if r, ok := et.Renames[f]; ok {
+			cur = r
+		}
+		if cur == oldPath {
+			orig = f
+			break
+		}
+	}
+	dir := et.Dir
+	d.mu.Unlock()
+	if orig == "" {
+		return false, &apperrors.DownloadKeyError{Err: fmt.Errorf("文件不存在: %s", oldPath), Key: torrentHash}
+	}
+
+	dst := filepath.Join(dir, newPath)
+	if _, err := os.Stat(dst); err == nil {
+		slog.Error("[embedded] 重命名错误，文件已存在", "oldPath", oldPath, "newPath", newPath)
+		return false, fmt.Errorf("文件已存在")
+	}
+
+	err = d.relocate(et, func() error {
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			return err
+		}
+		if err := os.Rename(filepath.Join(dir, oldPath), dst); err != nil {
+			return fmt.Errorf("重命名失败: %w", err)
+		}
+		d.mu.Lock()
+		if et.Renames == nil {
+			et.Renames = make(map[string]string)
+		}
+		et.Renames[orig] = filepath.ToSlash(newPath)
+		d.mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		slog.Error("[embedded] 重命名错误", "oldPath", oldPath, "newPath", newPath, "error", err)
+		return false, err
+	}
+	return true, nil
+}
+
+// Move 在本地文件系统上把种子的文件移动到新目录，保持相对路径
+func (d *EmbeddedDownloader) Move(ctx context.Context, hashes []string, newLocation string) (bool, error) {
+	for _, hash := range hashes {
+		d.mu.Lock()
+		et, err := d.completed(hash)
+		if err != nil {
+			d.mu.Unlock()
+			return false, err
+		}
+		dir, files := et.Dir, et.currentFiles(false)
+		d.mu.Unlock()
+
+		err = d.relocate(et, func() error {
+			for _, f := range files {
+				dst := filepath.Join(newLocation, f)
+				if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+					return err
+				}
+				if err := os.Rename(filepath.Join(dir, f), dst); err != nil {
+					return fmt.Errorf("移动文件失败: %w", err)
+				}
+			}
+			d.mu.Lock()
+			et.Dir = newLocation
+			d.mu.Unlock()
+			return nil
+		})
+		if err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// Delete 移除种子，deleteFiles 为 true 时删除下载的文件
+func (d *EmbeddedDownloader) Delete(ctx context.Context, hashes []string, deleteFiles bool) (bool, error) {
+	for _, hash := range hashes {
+		d.mu.Lock()
+		et, err := d.lookup(hash)
+		if err != nil {
+			d.mu.Unlock()
+			continue
+		}
+		t := d.pause(et)
+		delete(d.torrents, et.Hash)
+		os.Remove(filepath.Join(d.StateDir, et.Hash+".json"))
+		// 跳过的文件可能因为分片跨文件留下 .part，一并删除
+		dir, files := et.Dir, et.currentFiles(true)
+		d.mu.Unlock()
+		if t != nil {
+			t.Drop()
+		}
+		if !deleteFiles {
+			continue
+		}
+
+		for _, f := range files {
+			p := filepath.Join(dir, f)
+			os.Remove(p)
+			os.Remove(p + ".part")
+			// 删除空的上级目录，不超出保存目录
+			for parent := filepath.Dir(p); parent != dir && strings.HasPrefix(parent, dir); parent = filepath.Dir(parent) {
+				if os.Remove(parent) != nil {
+					break
+				}
+			}
+		}
+	}
+	return true, nil
+}
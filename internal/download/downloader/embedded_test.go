@@ -0,0 +1,182 @@
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"goto-bangumi/internal/apperrors"
+	"goto-bangumi/internal/model"
+
+	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/bencode"
+	"github.com/anacrolix/torrent/metainfo"
+)
+
+// offlineClientConfig 只在本机回环地址上通信，不访问 DHT 和 tracker
+func offlineClientConfig(cfg *torrent.ClientConfig) {
+	cfg.ListenHost = torrent.LoopbackListenHost
+	cfg.ListenPort = 0
+	cfg.NoDHT = true
+	cfg.DisableTrackers = true
+	cfg.NoDefaultPortForwarding = true
+	cfg.DisableAcceptRateLimiting = true
+}
+
+// newLocalSeeder 生成一个多文件种子并在本地做种，返回种子文件和 Client
+func newLocalSeeder(t *testing.T) ([]byte, string, *torrent.Client) {
+	t.Helper()
+	root := t.TempDir()
+	dir := filepath.Join(root, "[Lilith-Raws] Frieren")
+	os.MkdirAll(dir, 0o755)
+	data := make([]byte, 300<<10)
+	rand.Read(data)
+	os.WriteFile(filepath.Join(dir, "Frieren - 01.mkv"), data, 0o644)
+
+	info := metainfo.Info{PieceLength: 64 << 10}
+	if err := info.BuildFromFilePath(dir); err != nil {
+		t.Fatalf("BuildFromFilePath: %v", err)
+	}
+	mi := metainfo.MetaInfo{}
+	var err error
+	if mi.InfoBytes, err = bencode.Marshal(info); err != nil {
+		t.Fatalf("Marshal info: %v", err)
+	}
+	var buf bytes.Buffer
+	mi.Write(&buf)
+
+	cfg := torrent.NewDefaultClientConfig()
+	offlineClientConfig(cfg)
+	cfg.DataDir = root
+	cfg.Seed = true
+	seeder, err := torrent.NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(func() { seeder.Close() })
+	st, err := seeder.AddTorrent(&mi)
+	if err != nil {
+		t.Fatalf("AddTorrent: %v", err)
+	}
+	<-st.GotInfo()
+	st.VerifyData()
+	if !st.Complete().Bool() {
+		t.Fatal("seeder is not complete")
+	}
+	return buf.Bytes(), mi.HashInfoBytes().HexString(), seeder
+}
+
+func newTestEmbedded(t *testing.T, stateDir, savePath string, ratio float64) *EmbeddedDownloader {
+	t.Helper()
+	d := NewEmbeddedDownloader()
+	d.StateDir = stateDir
+	d.configure = offlineClientConfig
+	err := d.Init(&model.DownloaderConfig{Type: "embedded", SavePath: savePath, SeedRatio: ratio})
+	if err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	t.Cleanup(d.close)
+	return d
+}
+
+func TestEmbedded_DownloadRenameMoveSeed(t *testing.T) {
+	file, hash, seeder := newLocalSeeder(t)
+	stateDir, savePath, mediaPath := t.TempDir(), t.TempDir(), t.TempDir()
+	d := newTestEmbedded(t, stateDir, savePath, -1)
+	ctx := context.Background()
+
+	hashes, err := d.Add(ctx, &model.TorrentInfo{InfoHashV1: hash, File: file}, "Frieren/Season 1")
+	if err != nil || len(hashes) != 1 || hashes[0] != hash {
+		t.Fatalf("Add = %v, %v", hashes, err)
+	}
+	d.mu.Lock()
+	d.torrents[hash].t.AddClientPeer(seeder)
+	d.mu.Unlock()
+
+	deadline := time.Now().Add(20 * time.Second)
+	for {
+		info, err := d.GetTorrentInfo(ctx, hash)
+		if err != nil {
+			t.Fatalf("GetTorrentInfo error: %v", err)
+		}
+		if info.Completed != 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("download did not complete")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	dir := filepath.Join(savePath, "Frieren", "Season 1")
+	files, _ := d.GetTorrentFiles(ctx, hash)
+	if len(files) != 1 || files[0] != "[Lilith-Raws] Frieren/Frieren - 01.mkv" {
+		t.Fatalf("GetTorrentFiles = %v", files)
+	}
+	if _, err := d.Rename(ctx, hash, files[0], "Frieren S01E01.mkv"); err != nil {
+		t.Fatalf("Rename error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Frieren S01E01.mkv")); err != nil {
+		t.Fatalf("renamed file missing: %v", err)
+	}
+	if _, err := d.Rename(ctx, hash, "not-exist.mkv", "x.mkv"); !apperrors.IsKeyError(err) {
+		t.Errorf("Rename of unknown file error = %v, want key error", err)
+	}
+
+	if _, err := d.Move(ctx, []string{hash}, mediaPath); err != nil {
+		t.Fatalf("Move error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(mediaPath, "Frieren S01E01.mkv")); err != nil {
+		t.Fatalf("moved file missing: %v", err)
+	}
+	// 重新加入后文件仍然完整，继续做种
+	if info, _ := d.GetTorrentInfo(ctx, hash); info.SavePath != mediaPath || info.Completed == 0 {
+		t.Errorf("GetTorrentInfo after Move = %+v", info)
+	}
+	d.mu.Lock()
+	et := d.torrents[hash]
+	seeding := et.t != nil && et.t.Complete().Bool()
+	d.mu.Unlock()
+	if !seeding {
+		t.Error("torrent is not seeding after Move")
+	}
+
+	// 分享率 0 表示完成后立即停止做种
+	d.config.SeedRatio = 0
+	d.checkSeeding()
+	d.mu.Lock()
+	stopped := et.Stopped && et.t == nil
+	d.mu.Unlock()
+	if !stopped {
+		t.Error("checkSeeding did not stop the torrent")
+	}
+
+	// 重启后从状态目录恢复
+	d.close()
+	d = newTestEmbedded(t, stateDir, savePath, 0)
+	if files, _ := d.GetTorrentFiles(ctx, hash); len(files) != 1 || files[0] != "Frieren S01E01.mkv" {
+		t.Errorf("GetTorrentFiles after restart = %v", files)
+	}
+
+	if _, err := d.Delete(ctx, []string{hash}, true); err != nil {
+		t.Fatalf("Delete error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(mediaPath, "Frieren S01E01.mkv")); !os.IsNotExist(err) {
+		t.Errorf("file still exists after Delete: %v", err)
+	}
+	if _, err := d.CheckHash(ctx, hash); !apperrors.IsKeyError(err) {
+		t.Fatalf("CheckHash after Delete error = %v, want key error", err)
+	}
+}
+
+func TestEmbedded_AddRequiresSource(t *testing.T) {
+	d := newTestEmbedded(t, t.TempDir(), t.TempDir(), 1)
+	_, err := d.Add(context.Background(), &model.TorrentInfo{InfoHashV1: "1317e47882474c771e29ed2271b282fbfb56e7d2"}, "x")
+	if err == nil {
+		t.Fatal("Add without torrent file or magnet should fail")
+	}
+}
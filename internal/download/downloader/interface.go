@@ -34,10 +34,13 @@ type BaseDownloader interface {
 	GetTorrentFiles(ctx context.Context, hash string) ([]string, error)
 
 	// GetTorrentInfo 获取单个种子的信息
-	GetTorrentInfo(ctx context.Context, hash string) (*model.TorrentDownloadInfo, error)
+	GetTorrentInfo(ctx context.Context, hash string) (*model.TorrentStatus, error)
+
+	// GetTorrentsInfo 一次请求批量获取多个种子的信息，下载器中不存在的 hash 不会出现在结果里
+	GetTorrentsInfo(ctx context.Context, hashes []string) (map[string]*model.TorrentStatus, error)
 
 	// TorrentsInfo 获取种子信息列表
-	TorrentsInfo(ctx context.Context, statusFilter, category string, tag *string, limit int) ([]map[string]any, error)
+	TorrentsInfo(ctx context.Context, statusFilter, category string, tag *string, limit int) ([]*model.TorrentStatus, error)
 
 	// Rename 重命名种子文件
 	Rename(ctx context.Context, torrentHash, oldPath, newPath string) (bool, error)
@@ -51,13 +54,19 @@ type BaseDownloader interface {
 	// CheckHash 检查种子是否存在，返回真实的hash
 	CheckHash(ctx context.Context, hash string) (string, error)
 
-	// Delete 删除种子
-	Delete(ctx context.Context, hashes []string) (bool, error)
+	// Delete 删除种子，deleteFiles 为 false 时只移除任务，保留已下载的文件
+	Delete(ctx context.Context, hashes []string, deleteFiles bool) (bool, error)
+}
+
+// Restarter 可以重新开始失败任务的下载器，比如网盘的离线下载
+type Restarter interface {
+	// Restart 重新开始下载失败的种子
+	Restart(ctx context.Context, hash string) error
 }
 
 // NewDownloader 创建下载器实例
 // 根据 downloaderType 动态选择具体的下载器实现
-// 支持的类型: "qbittorrent", "qb"
+// 支持的类型: "qbittorrent", "clouddrive", "transmission", "aria2", "deluge", "alist", "embedded", "mock"
 func NewDownloader(downloaderType string) BaseDownloader {
 
 	var d BaseDownloader
@@ -70,6 +79,16 @@ func NewDownloader(downloaderType string) BaseDownloader {
 		d = NewMockDownloader()
 	case "clouddrive", "clouddrive2":
 		d = NewCloudDriveDownloader()
+	case "transmission":
+		d = NewTransmissionDownloader()
+	case "aria2":
+		d = NewAria2Downloader()
+	case "deluge":
+		d = NewDelugeDownloader()
+	case "alist":
+		d = NewAlistDownloader()
+	case "embedded":
+		d = NewEmbeddedDownloader()
 	default:
 		slog.Warn("未知的下载器类型，使用默认的 qBittorrent 下载器", "type", downloaderType)
 		return NewQBittorrentDownloader()
@@ -3,7 +3,10 @@ package downloader
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 
 	"goto-bangumi/internal/apperrors"
 	"goto-bangumi/internal/model"
@@ -13,20 +16,31 @@ import (
 type mockTorrent struct {
 	hash       string
 	name       string
-	info       *model.TorrentDownloadInfo
+	info       *model.TorrentStatus
 	files      []string
 	queryCount int
 	category   string
 	tags       string
+	sim        *mockSim // 模拟模式下的下载状态，预置和手动添加的种子为 nil
+}
+
+// MockRename 模拟下载器记录的一次重命名
+type MockRename struct {
+	Hash    string
+	OldPath string
+	NewPath string
 }
 
 // MockDownloader 有状态的模拟下载器，用于测试
+// 默认每查询一次推进一次进度；配置了模拟模式时种子按虚拟时间下载，文件列表来自种子文件
 type MockDownloader struct {
 	config              *model.DownloaderConfig
 	mu                  sync.RWMutex
 	torrents            map[string]*mockTorrent
 	loggedIn            bool
 	completionThreshold int
+	sim                 *mockSimulator
+	renames             []MockRename
 }
 
 // NewMockDownloader 创建新的模拟下载器
@@ -42,6 +56,10 @@ func (d *MockDownloader) Init(config *model.DownloaderConfig) error {
 	defer d.mu.Unlock()
 	d.config = config
 	d.torrents = make(map[string]*mockTorrent)
+	d.sim = nil
+	if config.Mock.Simulate {
+		d.sim = newMockSimulator(config.Mock)
+	}
 
 	// 加载预置数据
 	for hash, info := range MockTorrentInfos {
@@ -49,7 +67,7 @@ func (d *MockDownloader) Init(config *model.DownloaderConfig) error {
 		d.torrents[hash] = &mockTorrent{
 			hash: hash,
 			name: hash,
-			info: &model.TorrentDownloadInfo{
+			info: &model.TorrentStatus{
 				ETA:       info.ETA,
 				SavePath:  info.SavePath,
 				Completed: info.Completed,
@@ -80,13 +98,23 @@ func (d *MockDownloader) Add(ctx context.Context, torrentInfo *model.TorrentInfo
 
 	mt := &mockTorrent{
 		name: torrentInfo.Name,
-		info: &model.TorrentDownloadInfo{
+		info: &model.TorrentStatus{
 			ETA:       300,
 			SavePath:  savePath,
 			Completed: 0,
 		},
 		files:      []string{fmt.Sprintf("[Mock] %s.mp4", torrentInfo.Name)},
 		queryCount: 0,
+		tags:       strings.Join(torrentInfo.Tags, ","),
+	}
+	if d.sim != nil {
+		mt.sim = d.sim.newSim(torrentInfo)
+		if len(torrentInfo.Files) > 0 {
+			mt.files = make([]string, 0, len(torrentInfo.Files))
+			for _, f := range torrentInfo.Files {
+				mt.files = append(mt.files, f.Path)
+			}
+		}
 	}
 
 	hashes := make([]string, 0, 2)
@@ -110,24 +138,28 @@ func (d *MockDownloader) Add(ctx context.Context, torrentInfo *model.TorrentInfo
 }
 
 // Delete 删除种子
-func (d *MockDownloader) Delete(ctx context.Context, hashes []string) (bool, error) {
+func (d *MockDownloader) Delete(ctx context.Context, hashes []string, deleteFiles bool) (bool, error) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 	for _, h := range hashes {
-		// 找到要删除的 torrent，同时清理所有指向同一对象的 key
 		if mt, ok := d.torrents[h]; ok {
-			for k, v := range d.torrents {
-				if v == mt {
-					delete(d.torrents, k)
-				}
-			}
+			d.removeLocked(mt)
 		}
 	}
 	return true, nil
 }
 
+// removeLocked 删除种子，同时清理所有指向同一对象的 key，调用方需持有写锁
+func (d *MockDownloader) removeLocked(mt *mockTorrent) {
+	for k, v := range d.torrents {
+		if v == mt {
+			delete(d.torrents, k)
+		}
+	}
+}
+
 // GetTorrentInfo 获取单个种子详细信息，自动推进下载进度
-func (d *MockDownloader) GetTorrentInfo(ctx context.Context, hash string) (*model.TorrentDownloadInfo, error) {
+func (d *MockDownloader) GetTorrentInfo(ctx context.Context, hash string) (*model.TorrentStatus, error) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
@@ -135,7 +167,34 @@ func (d *MockDownloader) GetTorrentInfo(ctx context.Context, hash string) (*mode
 	if !ok {
 		return nil, nil
 	}
+	return d.queryLocked(mt, hash), nil
+}
+
+// GetTorrentsInfo 批量获取种子信息，每个存在的种子同样推进一次下载进度
+func (d *MockDownloader) GetTorrentsInfo(ctx context.Context, hashes []string) (map[string]*model.TorrentStatus, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	result := make(map[string]*model.TorrentStatus, len(hashes))
+	for _, hash := range hashes {
+		if mt, ok := d.torrents[hash]; ok {
+			if info := d.queryLocked(mt, hash); info != nil {
+				result[hash] = info
+			}
+		}
+	}
+	return result, nil
+}
 
+// queryLocked 推进一次模拟下载进度并返回信息副本，模拟模式下种子消失时返回 nil，调用方需持有写锁
+func (d *MockDownloader) queryLocked(mt *mockTorrent, hash string) *model.TorrentStatus {
+	if mt.sim != nil {
+		info := d.sim.status(mt, hash)
+		if info == nil {
+			d.removeLocked(mt)
+		}
+		return info
+	}
 	mt.queryCount++
 	if mt.queryCount >= d.completionThreshold {
 		mt.info.Completed = 1
@@ -146,12 +205,30 @@ func (d *MockDownloader) GetTorrentInfo(ctx context.Context, hash string) (*mode
 		mt.info.ETA = eta
 	}
 
-	// 返回副本
-	return &model.TorrentDownloadInfo{
-		ETA:       mt.info.ETA,
-		SavePath:  mt.info.SavePath,
-		Completed: mt.info.Completed,
-	}, nil
+	return mt.status(hash)
+}
+
+// status 返回种子状态的副本，调用方需持有锁
+func (mt *mockTorrent) status(hash string) *model.TorrentStatus {
+	info := &model.TorrentStatus{
+		Hash:        hash,
+		Name:        mt.name,
+		State:       model.DownloadStateDownloading,
+		ETA:         mt.info.ETA,
+		Ratio:       -1,
+		SeedingTime: -1,
+		SavePath:    mt.info.SavePath,
+		Completed:   mt.info.Completed,
+		Category:    mt.category,
+		Tags:        model.SplitTags(mt.tags),
+	}
+	if info.Done() {
+		info.State = model.DownloadStateSeeding
+		info.Progress = 1
+	} else {
+		info.Progress = float64(300-info.ETA) / 300
+	}
+	return info
 }
 
 // GetTorrentFiles 获取种子文件列表
@@ -167,39 +244,33 @@ func (d *MockDownloader) GetTorrentFiles(ctx context.Context, hash string) ([]st
 }
 
 // TorrentsInfo 获取种子信息列表
-func (d *MockDownloader) TorrentsInfo(ctx context.Context, statusFilter, category string, tag *string, limit int) ([]map[string]any, error) {
-	d.mu.RLock()
-	defer d.mu.RUnlock()
+func (d *MockDownloader) TorrentsInfo(ctx context.Context, statusFilter, category string, tag *string, limit int) ([]*model.TorrentStatus, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
 
-	var result []map[string]any
+	var result []*model.TorrentStatus
 	for hash, mt := range d.torrents {
 		// 按 category 过滤
 		if category != "" && mt.category != category {
 			continue
 		}
 		// 按 tag 过滤
-		if tag != nil && mt.tags != *tag {
+		if tag != nil && !slices.Contains(model.SplitTags(mt.tags), *tag) {
 			continue
 		}
-		// 按 statusFilter 过滤
-		if statusFilter != "" {
-			if statusFilter == "completed" && mt.info.Completed != 1 {
-				continue
-			}
-			if statusFilter == "downloading" && mt.info.Completed != 0 {
+		info := mt.status(hash)
+		if mt.sim != nil {
+			if info = d.sim.status(mt, hash); info == nil {
+				d.removeLocked(mt)
 				continue
 			}
 		}
+		// 按 statusFilter 过滤
+		if (statusFilter == "completed" && !info.Done()) || (statusFilter == "downloading" && info.Done()) {
+			continue
+		}
 
-		result = append(result, map[string]any{
-			"hash":      hash,
-			"name":      mt.name,
-			"category":  mt.category,
-			"tags":      mt.tags,
-			"save_path": mt.info.SavePath,
-			"completed": mt.info.Completed,
-			"eta":       mt.info.ETA,
-		})
+		result = append(result, info)
 
 		if limit > 0 && len(result) >= limit {
 			break
@@ -234,12 +305,44 @@ func (d *MockDownloader) Rename(ctx context.Context, torrentHash, oldPath, newPa
 	for i, f := range mt.files {
 		if f == oldPath {
 			mt.files[i] = newPath
+			d.renames = append(d.renames, MockRename{Hash: torrentHash, OldPath: oldPath, NewPath: newPath})
 			break
 		}
 	}
 	return true, nil
 }
 
+// Renames 返回所有成功的重命名记录
+func (d *MockDownloader) Renames() []MockRename {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return slices.Clone(d.renames)
+}
+
+// Restart 重新开始下载，只有模拟模式支持
+func (d *MockDownloader) Restart(ctx context.Context, hash string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	mt, ok := d.torrents[hash]
+	if !ok {
+		return &apperrors.DownloadKeyError{Err: fmt.Errorf("种子不存在"), Key: hash}
+	}
+	if mt.sim == nil {
+		return fmt.Errorf("[mock] 只有模拟模式的种子可以重新开始")
+	}
+	d.sim.restart(mt)
+	return nil
+}
+
+// Advance 让模拟模式的虚拟时间前进 d，非模拟模式下不起作用
+func (d *MockDownloader) Advance(duration time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.sim != nil {
+		d.sim.offset += duration
+	}
+}
+
 // Move 移动种子到新位置
 func (d *MockDownloader) Move(ctx context.Context, hashes []string, newLocation string) (bool, error) {
 	d.mu.Lock()
@@ -254,13 +357,13 @@ func (d *MockDownloader) Move(ctx context.Context, hashes []string, newLocation
 }
 
 // AddMockTorrent 手动添加测试种子数据
-func (d *MockDownloader) AddMockTorrent(hash string, info *model.TorrentDownloadInfo, files []string) {
+func (d *MockDownloader) AddMockTorrent(hash string, info *model.TorrentStatus, files []string) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 	d.torrents[hash] = &mockTorrent{
 		hash: hash,
 		name: hash,
-		info: &model.TorrentDownloadInfo{
+		info: &model.TorrentStatus{
 			ETA:       info.ETA,
 			SavePath:  info.SavePath,
 			Completed: info.Completed,
@@ -2,7 +2,7 @@ package downloader
 
 import "goto-bangumi/internal/model"
 
-var MockTorrentInfos = map[string]*model.TorrentDownloadInfo{
+var MockTorrentInfos = map[string]*model.TorrentStatus{
 	"1317e47882474c771e29ed2271b282fbfb56e7d2": {
 		ETA:       0,
 		SavePath:  "我推的孩子/Season 2",
@@ -0,0 +1,140 @@
+package downloader
+
+import (
+	"math/rand/v2"
+	"time"
+
+	"goto-bangumi/internal/model"
+)
+
+// mockDefaultSize 磁力链接没有文件大小时使用的模拟大小
+const mockDefaultSize = 1 << 30
+
+// mockOutcome 模拟种子在下载中途出现的问题
+type mockOutcome int
+
+const (
+	mockOutcomeNone    mockOutcome = iota
+	mockOutcomeFail                // 下载失败
+	mockOutcomeStall               // 下载停滞，进度不再增长
+	mockOutcomeMissing             // 从下载器中消失
+)
+
+// mockSim 单个种子的模拟状态
+type mockSim struct {
+	added     time.Time // 添加时的虚拟时间
+	size      int64
+	outcome   mockOutcome
+	outcomeAt float64 // 进度达到这个值时出现问题
+}
+
+// mockSimulator 模拟模式的虚拟时钟和随机数，调用方持有 MockDownloader.mu
+type mockSimulator struct {
+	config   model.MockConfig
+	duration time.Duration
+	rand     *rand.Rand
+	start    time.Time     // 真实时间的起点
+	offset   time.Duration // Advance 累计前进的虚拟时间
+}
+
+func newMockSimulator(config model.MockConfig) *mockSimulator {
+	seed := uint64(config.Seed)
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+	duration := time.Duration(config.Duration) * time.Second
+	if duration <= 0 {
+		duration = time.Minute
+	}
+	return &mockSimulator{
+		config:   config,
+		duration: duration,
+		rand:     rand.New(rand.NewPCG(seed, seed)),
+		start:    time.Now(),
+	}
+}
+
+// now 当前的虚拟时间
+func (s *mockSimulator) now() time.Time {
+	elapsed := time.Duration(float64(time.Since(s.start)) * s.config.TimeScale)
+	return s.start.Add(elapsed + s.offset)
+}
+
+// newSim 为新添加的种子掷出它的结局
+func (s *mockSimulator) newSim(torrentInfo *model.TorrentInfo) *mockSim {
+	sim := &mockSim{added: s.now(), size: mockDefaultSize}
+	if len(torrentInfo.Files) > 0 {
+		sim.size = 0
+		for _, f := range torrentInfo.Files {
+			sim.size += f.Size
+		}
+	}
+	roll := s.rand.Float64()
+	switch {
+	case roll < s.config.FailRate:
+		sim.outcome = mockOutcomeFail
+	case roll < s.config.FailRate+s.config.StallRate:
+		sim.outcome = mockOutcomeStall
+	case roll < s.config.FailRate+s.config.StallRate+s.config.MissingRate:
+		sim.outcome = mockOutcomeMissing
+	}
+	// 问题出现在 10%-90% 之间，让任务有机会先看到正常的进度
+	sim.outcomeAt = 0.1 + 0.8*s.rand.Float64()
+	return sim
+}
+
+// restart 从头开始下载，重新开始后不再出问题
+func (s *mockSimulator) restart(mt *mockTorrent) {
+	mt.sim.added = s.now()
+	mt.sim.outcome = mockOutcomeNone
+	mt.info.Completed = 0
+}
+
+// status 按虚拟时间计算种子状态，种子已经消失时返回 nil
+func (s *mockSimulator) status(mt *mockTorrent, hash string) *model.TorrentStatus {
+	sim := mt.sim
+	now := s.now()
+	progress := min(max(now.Sub(sim.added).Seconds()/s.duration.Seconds(), 0), 1)
+	outcome := mockOutcomeNone
+	if sim.outcome != mockOutcomeNone && progress >= sim.outcomeAt {
+		outcome = sim.outcome
+		progress = sim.outcomeAt
+	}
+	if outcome == mockOutcomeMissing {
+		return nil
+	}
+
+	if progress >= 1 && mt.info.Completed == 0 {
+		mt.info.Completed = int(sim.added.Add(s.duration).Unix())
+	}
+	mt.info.ETA = -1
+	switch {
+	case progress >= 1:
+		mt.info.ETA = 0
+	case outcome == mockOutcomeNone:
+		// ETA 按真实时间报告，handler 用它决定多久后再查
+		remaining := time.Duration((1 - progress) * float64(s.duration))
+		if s.config.TimeScale > 0 {
+			remaining = time.Duration(float64(remaining) / s.config.TimeScale)
+		}
+		mt.info.ETA = int(remaining.Seconds())
+	}
+
+	info := mt.status(hash)
+	info.Progress = progress
+	info.Size = sim.size
+	info.Downloaded = int64(progress * float64(sim.size))
+	switch {
+	case info.Done():
+		info.Progress = 1
+		info.SeedingTime = int64(now.Sub(sim.added.Add(s.duration)).Seconds())
+	case outcome == mockOutcomeFail:
+		info.State = model.DownloadStateFailed
+		info.Message = "simulated failure"
+	case outcome == mockOutcomeStall:
+		info.State = model.DownloadStateStalled
+	default:
+		info.DownloadSpeed = int64(float64(sim.size) / s.duration.Seconds())
+	}
+	return info
+}
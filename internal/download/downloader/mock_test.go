@@ -3,6 +3,7 @@ package downloader
 import (
 	"context"
 	"testing"
+	"time"
 
 	"goto-bangumi/internal/apperrors"
 	"goto-bangumi/internal/model"
@@ -134,7 +135,7 @@ func TestMockDownloader_DeleteAndCheckHash(t *testing.T) {
 		t.Errorf("CheckHash = %q, want %q", got, hash)
 	}
 
-	ok, err := d.Delete(ctx, []string{hash})
+	ok, err := d.Delete(ctx, []string{hash}, true)
 	if err != nil || !ok {
 		t.Fatalf("Delete failed: ok=%v, err=%v", ok, err)
 	}
@@ -207,8 +208,8 @@ func TestMockDownloader_TorrentsInfo(t *testing.T) {
 
 	downloading, _ := d.TorrentsInfo(ctx, "downloading", "", nil, 0)
 	for _, t2 := range downloading {
-		if t2["completed"].(int) != 0 {
-			t.Errorf("downloading filter returned completed torrent: %v", t2["hash"])
+		if t2.Done() {
+			t.Errorf("downloading filter returned completed torrent: %v", t2.Hash)
 		}
 	}
 	if len(downloading) != 1 {
@@ -241,7 +242,7 @@ func TestMockDownloader_DeleteAliased(t *testing.T) {
 	}
 
 	// Delete by v1 hash only
-	d.Delete(ctx, []string{hashes[0]})
+	d.Delete(ctx, []string{hashes[0]}, true)
 
 	// v2 hash should also be gone
 	_, err := d.CheckHash(ctx, hashes[1])
@@ -300,7 +301,7 @@ func TestMockDownloader_InterfaceLifecycle(t *testing.T) {
 	}
 
 	// 5. 查询进度直到完成
-	var info *model.TorrentDownloadInfo
+	var info *model.TorrentStatus
 	for i := 0; i < 5; i++ {
 		info, err = d.GetTorrentInfo(ctx, hash)
 		if err != nil {
@@ -352,7 +353,7 @@ func TestMockDownloader_InterfaceLifecycle(t *testing.T) {
 	}
 	found := false
 	for _, item := range all {
-		if item["hash"] == hash {
+		if item.Hash == hash {
 			found = true
 			break
 		}
@@ -362,7 +363,7 @@ func TestMockDownloader_InterfaceLifecycle(t *testing.T) {
 	}
 
 	// 10. Delete
-	ok, err = d.Delete(ctx, []string{hash})
+	ok, err = d.Delete(ctx, []string{hash}, true)
 	if err != nil || !ok {
 		t.Fatalf("Delete failed: ok=%v, err=%v", ok, err)
 	}
@@ -420,7 +421,7 @@ func TestMockDownloader_NonExistentHash(t *testing.T) {
 	}
 
 	// Delete 对不存在的 hash 不报错
-	ok, err = d.Delete(ctx, []string{fakeHash})
+	ok, err = d.Delete(ctx, []string{fakeHash}, true)
 	if err != nil {
 		t.Errorf("Delete error: %v", err)
 	}
@@ -459,3 +460,123 @@ func TestMockDownloader_AuthLogout(t *testing.T) {
 		t.Error("loggedIn should be false after Logout")
 	}
 }
+
+func TestMockDownloader_GetTorrentsInfo(t *testing.T) {
+	d := newTestMock(t)
+	ctx := context.Background()
+
+	hash := "1317e47882474c771e29ed2271b282fbfb56e7d2"
+	infos, err := d.GetTorrentsInfo(ctx, []string{hash, "not-exist"})
+	if err != nil {
+		t.Fatalf("GetTorrentsInfo error: %v", err)
+	}
+	if len(infos) != 1 {
+		t.Fatalf("GetTorrentsInfo returned %d torrents, want 1", len(infos))
+	}
+	if infos[hash] == nil || infos[hash].Completed != 1 {
+		t.Errorf("infos[%s] = %+v, want completed", hash, infos[hash])
+	}
+}
+
+func newSimMock(t *testing.T, mock model.MockConfig) *MockDownloader {
+	t.Helper()
+	mock.Simulate = true
+	d := NewMockDownloader()
+	if err := d.Init(&model.DownloaderConfig{Type: "mock", SavePath: "/downloads/Bangumi", Mock: mock}); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	return d
+}
+
+func TestMockDownloader_SimulateProgress(t *testing.T) {
+	// 时间倍速为 0，虚拟时间只随 Advance 前进
+	d := newSimMock(t, model.MockConfig{Duration: 100, Seed: 1})
+	ctx := context.Background()
+
+	torrentInfo := &model.TorrentInfo{
+		Name:       "Frieren",
+		InfoHashV1: "aaaa1111bbbb2222cccc3333dddd4444eeee5555",
+		Files: []model.TorrentFile{
+			{Path: "Frieren/Frieren - 01.mkv", Size: 600},
+			{Path: "Frieren/Frieren - 01.ass", Size: 400},
+		},
+		Tags: []string{"gb:0123"},
+	}
+	hashes, _ := d.Add(ctx, torrentInfo, "/downloads/Frieren")
+	hash := hashes[0]
+
+	files, _ := d.GetTorrentFiles(ctx, hash)
+	if len(files) != 2 || files[0] != "Frieren/Frieren - 01.mkv" {
+		t.Fatalf("files = %v, want the files from the metainfo", files)
+	}
+
+	d.Advance(25 * time.Second)
+	info, _ := d.GetTorrentInfo(ctx, hash)
+	if info.Done() || info.State != model.DownloadStateDownloading || info.Progress != 0.25 || info.Size != 1000 || info.ETA != 75 {
+		t.Fatalf("after 25s: %+v", info)
+	}
+	if !info.HasTag("gb:") {
+		t.Errorf("tags = %v, want the tags passed to Add", info.Tags)
+	}
+
+	d.Advance(time.Minute + 15*time.Second)
+	info, _ = d.GetTorrentInfo(ctx, hash)
+	if !info.Done() || info.State != model.DownloadStateSeeding || info.ETA != 0 {
+		t.Fatalf("after 100s: %+v", info)
+	}
+
+	d.Rename(ctx, hash, "Frieren/Frieren - 01.mkv", "Frieren S01E01.mkv")
+	renames := d.Renames()
+	if len(renames) != 1 || renames[0].Hash != hash || renames[0].NewPath != "Frieren S01E01.mkv" {
+		t.Errorf("Renames() = %+v", renames)
+	}
+}
+
+func TestMockDownloader_SimulateOutcomes(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name   string
+		config model.MockConfig
+		check  func(t *testing.T, info *model.TorrentStatus)
+	}{
+		{"fail", model.MockConfig{FailRate: 1}, func(t *testing.T, info *model.TorrentStatus) {
+			if info == nil || !info.Failed() {
+				t.Errorf("info = %+v, want failed", info)
+			}
+		}},
+		{"stall", model.MockConfig{StallRate: 1}, func(t *testing.T, info *model.TorrentStatus) {
+			if info == nil || info.State != model.DownloadStateStalled || info.Done() || info.Progress >= 1 {
+				t.Errorf("info = %+v, want stalled", info)
+			}
+		}},
+		{"missing", model.MockConfig{MissingRate: 1}, func(t *testing.T, info *model.TorrentStatus) {
+			if info != nil {
+				t.Errorf("info = %+v, want missing", info)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newSimMock(t, tt.config)
+			hashes, _ := d.Add(ctx, &model.TorrentInfo{Name: "Frieren", InfoHashV1: "aaaa1111bbbb2222cccc3333dddd4444eeee5555"}, "/downloads")
+			d.Advance(time.Hour)
+			info, err := d.GetTorrentInfo(ctx, hashes[0])
+			if err != nil {
+				t.Fatalf("GetTorrentInfo error: %v", err)
+			}
+			tt.check(t, info)
+		})
+	}
+
+	// 重新开始后从头下载，不再出问题
+	d := newSimMock(t, model.MockConfig{FailRate: 1})
+	hashes, _ := d.Add(ctx, &model.TorrentInfo{Name: "Frieren", InfoHashV1: "aaaa1111bbbb2222cccc3333dddd4444eeee5555"}, "/downloads")
+	d.Advance(time.Hour)
+	if err := d.Restart(ctx, hashes[0]); err != nil {
+		t.Fatalf("Restart error: %v", err)
+	}
+	d.Advance(time.Hour)
+	if info, _ := d.GetTorrentInfo(ctx, hashes[0]); !info.Done() {
+		t.Errorf("after restart: %+v, want completed", info)
+	}
+}
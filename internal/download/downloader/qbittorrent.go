@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"strconv"
 	"strings"
 	"time"
 
@@ -20,8 +21,10 @@ import (
 var QBAPI = map[string]string{
 	"add":            "/api/v2/torrents/add",
 	"addTags":        "/api/v2/torrents/addTags",
+	"categories":     "/api/v2/torrents/categories",
 	"createCategory": "/api/v2/torrents/createCategory",
 	"delete":         "/api/v2/torrents/delete",
+	"filePrio":       "/api/v2/torrents/filePrio",
 	"getFiles":       "/api/v2/torrents/files",
 	"info":           "/api/v2/torrents/info",
 	"properties":     "/api/v2/torrents/properties",
@@ -34,6 +37,11 @@ var QBAPI = map[string]string{
 	"version":        "/api/v2/app/version",
 }
 
+// 添加种子后设置文件优先级的重试次数和间隔
+const qbSkipFilesRetry = 3
+
+var qbSkipFilesDelay = time.Second
+
 // QBittorrentDownloader qBittorrent 下载器实现
 type QBittorrentDownloader struct {
 	client      *resty.Client
@@ -141,6 +149,10 @@ func (d *QBittorrentDownloader) Auth(ctx context.Context) (bool, error) {
 				Name: d.config.Username,
 			}
 		}
+		// 分类不存在时创建，失败不影响登录
+		if err := d.ensureCategory(ctx); err != nil {
+			slog.Warn("[qBittorrent] 创建分类失败", "category", d.config.Category, "error", err)
+		}
 		return true, nil
 	}
 
@@ -167,13 +179,43 @@ func (d *QBittorrentDownloader) Logout(ctx context.Context) (bool, error) {
 	return false, &apperrors.NetworkError{Err: fmt.Errorf("登出失败：状态码 %d", resp.StatusCode()), StatusCode: resp.StatusCode()}
 }
 
+// ensureCategory 确保配置的分类存在
+func (d *QBittorrentDownloader) ensureCategory(ctx context.Context) error {
+	if d.config.Category == "" {
+		return nil
+	}
+	if err := d.wait(ctx); err != nil {
+		return err
+	}
+
+	var categories map[string]any
+	resp, err := d.client.R().
+		SetContext(ctx).
+		SetResult(&categories).
+		Get(QBAPI["categories"])
+	if err != nil {
+		return &apperrors.NetworkError{Err: fmt.Errorf("获取分类失败: %w", err)}
+	}
+	if resp.StatusCode() != 200 {
+		return fmt.Errorf("获取分类失败：状态码 %d", resp.StatusCode())
+	}
+	if _, ok := categories[d.config.Category]; ok {
+		return nil
+	}
+
+	slog.Info("[qBittorrent] 创建分类", "category", d.config.Category)
+	_, err = d.AddCategory(ctx, d.config.Category)
+	return err
+}
+
 // AddCategory 添加分类
-func (d *QBittorrentDownloader) AddCategory(category string) (bool, error) {
-	if err := d.wait(context.Background()); err != nil {
+func (d *QBittorrentDownloader) AddCategory(ctx context.Context, category string) (bool, error) {
+	if err := d.wait(ctx); err != nil {
 		return false, err
 	}
 
 	resp, err := d.client.R().
+		SetContext(ctx).
 		SetFormData(map[string]string{
 			"category": category,
 		}).
@@ -189,8 +231,24 @@ func (d *QBittorrentDownloader) AddCategory(category string) (bool, error) {
 	return false, fmt.Errorf("添加分类失败：状态码 %d", resp.StatusCode())
 }
 
-// GetTorrentFiles 获取种子文件列表
+// GetTorrentFiles 获取种子文件列表，优先级为 0 的文件不会下载，不包含在内
 func (d *QBittorrentDownloader) GetTorrentFiles(ctx context.Context, hash string) ([]string, error) {
+	files, err := d.torrentFiles(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	fileNames := make([]string, 0, len(files))
+	for _, file := range files {
+		if file.Priority == 0 {
+			continue
+		}
+		fileNames = append(fileNames, file.Name)
+	}
+	return fileNames, nil
+}
+
+// torrentFiles 获取种子文件详情，种子不存在时返回 nil
+func (d *QBittorrentDownloader) torrentFiles(ctx context.Context, hash string) ([]model.QBTorrentFile, error) {
 	if err := d.wait(ctx); err != nil {
 		return nil, err
 	}
@@ -220,118 +278,160 @@ func (d *QBittorrentDownloader) GetTorrentFiles(ctx context.Context, hash string
 		if err := json.Unmarshal(resp.Body(), &files); err != nil {
 			return nil, fmt.Errorf("解析文件列表失败: %w", err)
 		}
-
-		fileNames := make([]string, 0, len(files))
-		for _, file := range files {
-			fileNames = append(fileNames, file.Name)
-		}
-		return fileNames, nil
+		return files, nil
 	}
 
 	return nil, fmt.Errorf("获取文件列表失败：状态码 %d", resp.StatusCode())
 }
 
-// TorrentInfo 获取单个种子详细信息
-// 返回 (连接状态, 种子信息, error)
-// 种子信息: 成功时返回 TorrentDownloadInfo, 失败返回 nil
-func (d *QBittorrentDownloader) GetTorrentInfo(ctx context.Context, hash string) (*model.TorrentDownloadInfo, error) {
-	if err := d.wait(ctx); err != nil {
-		return nil, err
-	}
-
-	resp, err := d.client.R().
-		SetContext(ctx).
-		SetQueryParam("hash", hash).
-		Get(QBAPI["properties"])
+// GetTorrentInfo 获取单个种子的状态，种子不存在时返回 DownloadKeyError
+func (d *QBittorrentDownloader) GetTorrentInfo(ctx context.Context, hash string) (*model.TorrentStatus, error) {
+	infos, err := d.GetTorrentsInfo(ctx, []string{hash})
 	if err != nil {
-		// 连接错误
-		slog.Error("[qBittorrent] torrent_info 连接错误", "error", err)
 		return nil, err
 	}
-	if resp.StatusCode() == 403 {
-		slog.Error("[qBittorrent] 需要先登录", "function", "torrent_info")
-		return nil, &apperrors.DownloadAuthenticationError{Err: fmt.Errorf("需要先登录"), Name: d.config.Username}
-	}
-
-	// 404 表示种子不存在但连接正常
-	if resp.StatusCode() == 404 {
+	info, ok := infos[hash]
+	if !ok {
 		slog.Warn("[qBittorrent] 种子不存在", "hash", hash)
 		return nil, &apperrors.DownloadKeyError{Err: fmt.Errorf("种子不存在"), Key: hash}
 	}
+	slog.Debug("[qBittorrent] 种子信息", "hash", hash, "state", info.State, "eta", info.ETA, "save_path", info.SavePath)
+	return info, nil
+}
 
-	if resp.StatusCode() == 200 {
-		var props model.QBTorrentProperties
-		if err := json.Unmarshal(resp.Body(), &props); err != nil {
-			return nil, fmt.Errorf("解析种子信息失败: %w", err)
-		}
-
-		slog.Debug("[qBittorrent] 种子信息", "hash", hash, "eta", props.Eta, "save_path", props.SavePath, "completion_date", props.CompletionDate)
-
-		// 处理 completion_date
-		completionDate := 0
-		if props.CompletionDate != -1 {
-			completionDate = int(props.CompletionDate)
-		}
-
-		result := &model.TorrentDownloadInfo{
-			ETA:       int(props.Eta),
-			SavePath:  props.SavePath,
-			Completed: completionDate,
-		}
-
+// GetTorrentsInfo 通过 torrents/info 的 hashes 参数一次查询多个种子
+func (d *QBittorrentDownloader) GetTorrentsInfo(ctx context.Context, hashes []string) (map[string]*model.TorrentStatus, error) {
+	result := make(map[string]*model.TorrentStatus, len(hashes))
+	if len(hashes) == 0 {
 		return result, nil
 	}
-
-	return nil, fmt.Errorf("获取种子信息失败：状态码 %d", resp.StatusCode())
+	torrents, err := d.listTorrents(ctx, map[string]string{"hashes": strings.Join(hashes, "|")})
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[string]*model.TorrentStatus, len(torrents))
+	for _, t := range torrents {
+		found[strings.ToLower(t.Hash)] = t
+	}
+	// 按调用方传入的 hash 返回，避免大小写不一致
+	for _, hash := range hashes {
+		if info, ok := found[strings.ToLower(hash)]; ok {
+			result[hash] = info
+		}
+	}
+	return result, nil
 }
 
 // TorrentsInfo 获取种子信息列表
-func (d *QBittorrentDownloader) TorrentsInfo(ctx context.Context, statusFilter, category string, tag *string, limit int) ([]map[string]any, error) {
-	if err := d.wait(ctx); err != nil {
-		return nil, err
+func (d *QBittorrentDownloader) TorrentsInfo(ctx context.Context, statusFilter, category string, tag *string, limit int) ([]*model.TorrentStatus, error) {
+	params := map[string]string{
+		"filter":  statusFilter,
+		"sort":    "completion_on",
+		"reverse": "true",
+	}
+	// 空的 category 参数在 qBittorrent 中表示"无分类"，不限分类时不能带这个参数
+	if category != "" {
+		params["category"] = category
 	}
-
-	req := d.client.R().
-		SetContext(ctx).
-		SetQueryParams(map[string]string{
-			"filter":   statusFilter,
-			"category": category,
-			"sort":     "completion_on",
-			"reverse":  "true",
-		})
-
 	if tag != nil {
-		req.SetQueryParam("tag", *tag)
+		params["tag"] = *tag
 	}
-
 	if limit > 0 {
-		req.SetQueryParam("limit", fmt.Sprintf("%d", limit))
+		params["limit"] = strconv.Itoa(limit)
+	}
+	return d.listTorrents(ctx, params)
+}
+
+// listTorrents 调用 torrents/info 并转换成统一的种子状态
+func (d *QBittorrentDownloader) listTorrents(ctx context.Context, params map[string]string) ([]*model.TorrentStatus, error) {
+	if err := d.wait(ctx); err != nil {
+		return nil, err
 	}
 
-	resp, err := req.Get(QBAPI["info"])
+	resp, err := d.client.R().
+		SetContext(ctx).
+		SetQueryParams(params).
+		Get(QBAPI["info"])
 	if err != nil {
+		slog.Error("[qBittorrent] torrents_info 连接错误", "error", err)
 		return nil, err
 	}
+	if resp.StatusCode() == 403 {
+		slog.Error("[qBittorrent] 需要先登录", "function", "torrents_info")
+		return nil, &apperrors.DownloadAuthenticationError{Err: fmt.Errorf("需要先登录"), Name: d.config.Username}
+	}
+	if resp.StatusCode() != 200 {
+		return nil, fmt.Errorf("获取种子列表失败：状态码 %d", resp.StatusCode())
+	}
 
-	if resp.StatusCode() == 200 {
-		var torrents []model.QBTorrentInfo
-		if err := json.Unmarshal(resp.Body(), &torrents); err != nil {
-			return nil, fmt.Errorf("解析种子列表失败: %w", err)
-		}
+	var torrents []model.QBTorrentInfo
+	if err := json.Unmarshal(resp.Body(), &torrents); err != nil {
+		return nil, fmt.Errorf("解析种子列表失败: %w", err)
+	}
+	result := make([]*model.TorrentStatus, 0, len(torrents))
+	for i := range torrents {
+		result = append(result, qbStatus(&torrents[i]))
+	}
+	return result, nil
+}
 
-		// 转换回 map[string]interface{} 以保持返回值兼容性
-		result := make([]map[string]any, 0, len(torrents))
-		for _, t := range torrents {
-			data, _ := json.Marshal(t)
-			var m map[string]any
-			json.Unmarshal(data, &m)
-			result = append(result, m)
-		}
+// qbStatus 把 qBittorrent 的种子信息转换成统一的种子状态
+func qbStatus(t *model.QBTorrentInfo) *model.TorrentStatus {
+	status := &model.TorrentStatus{
+		Hash:          t.Hash,
+		Name:          t.Name,
+		State:         qbState(t.State),
+		Message:       t.State,
+		Progress:      t.Progress,
+		Size:          t.Size,
+		Downloaded:    t.Downloaded,
+		Uploaded:      t.Uploaded,
+		DownloadSpeed: t.Dlspeed,
+		UploadSpeed:   t.Upspeed,
+		ETA:           int(t.Eta),
+		Ratio:         t.Ratio,
+		SeedingTime:   t.SeedingTime,
+		SavePath:      t.SavePath,
+		Category:      t.Category,
+		Tags:          model.SplitTags(t.Tags),
+	}
+	// 未完成时 completion_on 为 -1
+	if t.CompletionOn > 0 {
+		status.Completed = int(t.CompletionOn)
+	}
+	// qBittorrent 用 8640000 表示无穷大
+	if status.ETA >= 8640000 {
+		status.ETA = -1
+	}
+	return status
+}
 
-		return result, nil
+// qbState 归一化 qBittorrent 的种子状态，4.x 的 paused 和 5.x 的 stopped 都视为暂停
+func qbState(state string) model.DownloadState {
+	switch state {
+	case "downloading", "forcedDL":
+		return model.DownloadStateDownloading
+	case "metaDL", "forcedMetaDL":
+		return model.DownloadStateMetadata
+	case "stalledDL":
+		return model.DownloadStateStalled
+	case "queuedDL", "allocating":
+		return model.DownloadStateWaiting
+	case "checkingDL", "checkingUP", "checkingResumeData", "moving":
+		return model.DownloadStateChecking
+	case "pausedDL", "stoppedDL":
+		return model.DownloadStatePaused
+	case "uploading", "stalledUP", "forcedUP", "queuedUP":
+		return model.DownloadStateSeeding
+	case "pausedUP", "stoppedUP":
+		return model.DownloadStateCompleted
+	case "missingFiles":
+		return model.DownloadStateMissingFiles
+	case "error":
+		return model.DownloadStateFailed
+	default:
+		return model.DownloadStateUnknown
 	}
-
-	return nil, fmt.Errorf("获取种子列表失败：状态码 %d", resp.StatusCode())
 }
 
 func (d *QBittorrentDownloader) CheckHash(ctx context.Context, hash string) (string, error) {
@@ -351,7 +451,10 @@ func (d *QBittorrentDownloader) Add(ctx context.Context, torrentInfo *model.Torr
 	// 准备基础表单数据
 	data := make(map[string]string)
 	data["savepath"] = savePath
-	data["category"] = "GotoBangumi"
+	data["category"] = d.config.Category
+	if len(torrentInfo.Tags) > 0 {
+		data["tags"] = strings.Join(torrentInfo.Tags, ",")
+	}
 	data["paused"] = "false"
 	data["autoTMM"] = "false"
 
@@ -389,6 +492,12 @@ func (d *QBittorrentDownloader) Add(ctx context.Context, torrentInfo *model.Torr
 			return nil, fmt.Errorf("[qBittorrent]添加种子失败: %s", respText)
 		}
 
+		if len(torrentInfo.SkipFiles) > 0 && torrentInfo.InfoHashV1 != "" {
+			if err := d.skipFiles(ctx, torrentInfo.InfoHashV1, torrentInfo.SkipPaths()); err != nil {
+				slog.Warn("[qBittorrent] 设置文件不下载失败", "hash", torrentInfo.InfoHashV1, "error", err)
+			}
+		}
+
 		// 返回 v1 和 v2 hash 列表
 		hashes := make([]string, 0, 2)
 		if torrentInfo.InfoHashV1 != "" {
@@ -408,8 +517,61 @@ func (d *QBittorrentDownloader) Add(ctx context.Context, torrentInfo *model.Torr
 	return nil, fmt.Errorf("[qBittorrent]添加种子失败：状态码 %d", resp.StatusCode())
 }
 
+// skipFiles 把指定路径的文件优先级设为 0，qBittorrent 添加种子是异步的，种子未出现时稍后重试
+// 按文件名匹配下标，qBittorrent 的文件列表不包含填充文件
+func (d *QBittorrentDownloader) skipFiles(ctx context.Context, hash string, paths map[string]bool) error {
+	var files []model.QBTorrentFile
+	for i := range qbSkipFilesRetry {
+		var err error
+		if files, err = d.torrentFiles(ctx, hash); err != nil {
+			return err
+		}
+		if len(files) > 0 {
+			break
+		}
+		if i == qbSkipFilesRetry-1 {
+			return fmt.Errorf("种子未出现在下载列表中")
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(qbSkipFilesDelay):
+		}
+	}
+
+	ids := make([]string, 0, len(paths))
+	for _, file := range files {
+		if paths[file.Name] {
+			ids = append(ids, strconv.Itoa(file.Index))
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	if err := d.wait(ctx); err != nil {
+		return err
+	}
+	resp, err := d.client.R().
+		SetContext(ctx).
+		SetFormData(map[string]string{
+			"hash":     hash,
+			"id":       strings.Join(ids, "|"),
+			"priority": "0",
+		}).
+		Post(QBAPI["filePrio"])
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode() != 200 {
+		return fmt.Errorf("设置文件优先级失败：状态码 %d", resp.StatusCode())
+	}
+	slog.Debug("[qBittorrent] 已跳过不需要的文件", "hash", hash, "count", len(ids))
+	return nil
+}
+
 // Delete 删除种子
-func (d *QBittorrentDownloader) Delete(ctx context.Context, hashes []string) (bool, error) {
+func (d *QBittorrentDownloader) Delete(ctx context.Context, hashes []string, deleteFiles bool) (bool, error) {
 	if err := d.wait(ctx); err != nil {
 		return false, err
 	}
@@ -420,7 +582,7 @@ func (d *QBittorrentDownloader) Delete(ctx context.Context, hashes []string) (bo
 		SetContext(ctx).
 		SetFormData(map[string]string{
 			"hashes":      hashesStr,
-			"deleteFiles": "true",
+			"deleteFiles": strconv.FormatBool(deleteFiles),
 		}).
 		Post(QBAPI["delete"])
 	if err != nil {
@@ -492,12 +654,13 @@ func (d *QBittorrentDownloader) Move(ctx context.Context, hashes []string, newLo
 }
 
 // SetCategory 设置种子分类
-func (d *QBittorrentDownloader) SetCategory(hash, category string) (bool, error) {
-	if err := d.wait(context.Background()); err != nil {
+func (d *QBittorrentDownloader) SetCategory(ctx context.Context, hash, category string) (bool, error) {
+	if err := d.wait(ctx); err != nil {
 		return false, err
 	}
 
 	resp, err := d.client.R().
+		SetContext(ctx).
 		SetFormData(map[string]string{
 			"hashes":   hash,
 			"category": category,
@@ -514,13 +677,14 @@ func (d *QBittorrentDownloader) SetCategory(hash, category string) (bool, error)
 	return false, fmt.Errorf("设置分类失败：状态码 %d", resp.StatusCode())
 }
 
-// AddTag 添加标签
-func (d *QBittorrentDownloader) AddTag(hash, tag string) (bool, error) {
-	if err := d.wait(context.Background()); err != nil {
+// AddTag 添加标签，多个标签用逗号分隔
+func (d *QBittorrentDownloader) AddTag(ctx context.Context, hash, tag string) (bool, error) {
+	if err := d.wait(ctx); err != nil {
 		return false, err
 	}
 
 	resp, err := d.client.R().
+		SetContext(ctx).
 		SetFormData(map[string]string{
 			"hashes": hash,
 			"tags":   tag,
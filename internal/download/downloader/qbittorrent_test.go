@@ -0,0 +1,135 @@
+package downloader
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"goto-bangumi/internal/model"
+)
+
+// fakeQBittorrent 进程内的 qBittorrent WebUI，只实现登录、分类、添加种子和文件优先级
+type fakeQBittorrent struct {
+	mu         sync.Mutex
+	categories map[string]bool
+	lastAdd    map[string]string
+	files      []model.QBTorrentFile
+}
+
+func newFakeQBittorrent(t *testing.T) (*fakeQBittorrent, *httptest.Server) {
+	t.Helper()
+	f := &fakeQBittorrent{categories: map[string]bool{"Movies": true}}
+	srv := httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func (f *fakeQBittorrent) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.URL.Path {
+	case QBAPI["login"]:
+		w.Write([]byte("Ok."))
+	case QBAPI["categories"]:
+		result := map[string]any{}
+		for name := range f.categories {
+			result[name] = map[string]string{"name": name, "savePath": ""}
+		}
+		json.NewEncoder(w).Encode(result)
+	case QBAPI["createCategory"]:
+		r.ParseForm()
+		f.categories[r.PostForm.Get("category")] = true
+	case QBAPI["add"]:
+		r.ParseMultipartForm(1 << 20)
+		f.lastAdd = map[string]string{}
+		for k, v := range r.MultipartForm.Value {
+			f.lastAdd[k] = v[0]
+		}
+		w.Write([]byte("Ok."))
+	case QBAPI["getFiles"]:
+		if f.files == nil {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(f.files)
+	case QBAPI["filePrio"]:
+		r.ParseForm()
+		priority, _ := strconv.Atoi(r.PostForm.Get("priority"))
+		for _, id := range strings.Split(r.PostForm.Get("id"), "|") {
+			i, _ := strconv.Atoi(id)
+			f.files[i].Priority = priority
+		}
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestQBittorrent_CategoryAndTags(t *testing.T) {
+	f, srv := newFakeQBittorrent(t)
+	d := NewQBittorrentDownloader()
+	d.APIInterval = 0
+	d.Init(&model.DownloaderConfig{Type: "qbittorrent", Host: srv.URL, Category: "Bangumi"})
+	ctx := context.Background()
+
+	if ok, err := d.Auth(ctx); err != nil || !ok {
+		t.Fatalf("Auth() = %v, %v", ok, err)
+	}
+	if !f.categories["Bangumi"] {
+		t.Fatal("Auth() did not create the missing category")
+	}
+
+	_, err := d.Add(ctx, &model.TorrentInfo{
+		InfoHashV1: "1317e47882474c771e29ed2271b282fbfb56e7d2",
+		File:       []byte("d4:infoe"),
+		Tags:       []string{"葬送的芙莉莲", "S01", "Lilith-Raws"},
+	}, "Frieren/Season 1")
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if f.lastAdd["category"] != "Bangumi" || f.lastAdd["tags"] != "葬送的芙莉莲,S01,Lilith-Raws" {
+		t.Errorf("add form = %v", f.lastAdd)
+	}
+}
+
+func TestQBittorrent_SkipFiles(t *testing.T) {
+	f, srv := newFakeQBittorrent(t)
+	f.files = []model.QBTorrentFile{
+		{Index: 0, Name: "Frieren/Frieren - 01.mkv", Priority: 1},
+		{Index: 1, Name: "Frieren/NCOP.mkv", Priority: 1},
+		{Index: 2, Name: "Frieren/Scans/01.jpg", Priority: 1},
+	}
+	d := NewQBittorrentDownloader()
+	d.APIInterval = 0
+	d.Init(&model.DownloaderConfig{Type: "qbittorrent", Host: srv.URL})
+	ctx := context.Background()
+	d.Auth(ctx)
+
+	_, err := d.Add(ctx, &model.TorrentInfo{
+		InfoHashV1: "1317e47882474c771e29ed2271b282fbfb56e7d2",
+		File:       []byte("d4:infoe"),
+		Files: []model.TorrentFile{
+			{Path: "Frieren/Frieren - 01.mkv"},
+			{Path: "Frieren/NCOP.mkv"},
+			{Path: "Frieren/Scans/01.jpg"},
+		},
+		SkipFiles: []int{1, 2},
+	}, "Frieren/Season 1")
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	for i, want := range []int{1, 0, 0} {
+		if f.files[i].Priority != want {
+			t.Errorf("file %d priority = %d, want %d", i, f.files[i].Priority, want)
+		}
+	}
+
+	files, err := d.GetTorrentFiles(ctx, "1317e47882474c771e29ed2271b282fbfb56e7d2")
+	if err != nil || len(files) != 1 || files[0] != "Frieren/Frieren - 01.mkv" {
+		t.Errorf("GetTorrentFiles() = %v, %v", files, err)
+	}
+}
@@ -0,0 +1,448 @@
+package downloader
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"goto-bangumi/internal/apperrors"
+	"goto-bangumi/internal/model"
+
+	"github.com/go-resty/resty/v2"
+)
+
+const (
+	transmissionRPCPath   = "/transmission/rpc"
+	transmissionSessionID = "X-Transmission-Session-Id"
+)
+
+// torrent-get 需要的字段
+var transmissionFields = []string{
+	"id", "hashString", "name", "downloadDir", "eta", "percentDone", "doneDate", "leftUntilDone", "labels", "files", "fileStats", "uploadRatio", "secondsSeeding",
+	"status", "error", "errorString", "sizeWhenDone", "downloadedEver", "uploadedEver", "rateDownload", "rateUpload", "peersSendingToUs", "metadataPercentComplete",
+}
+
+// TransmissionDownloader Transmission RPC 下载器实现
+// Transmission 用 409 返回 X-Transmission-Session-Id，之后的请求都要带上，过期后会再次返回 409
+type TransmissionDownloader struct {
+	client      *resty.Client
+	config      *model.DownloaderConfig
+	APIInterval int // API 调用间隔（毫秒）
+	limiter     *apiLimiter
+
+	mu        sync.RWMutex
+	sessionID string
+}
+
+// NewTransmissionDownloader 创建新的 Transmission 下载器
+func NewTransmissionDownloader() *TransmissionDownloader {
+	return &TransmissionDownloader{
+		client:      resty.New(),
+		APIInterval: 200,
+		limiter:     newAPILimiter(200 * time.Millisecond),
+	}
+}
+
+// Init 初始化下载器
+func (d *TransmissionDownloader) Init(config *model.DownloaderConfig) error {
+	if config == nil {
+		return fmt.Errorf("配置不能为空")
+	}
+	d.config = config
+	if d.limiter == nil {
+		d.limiter = newAPILimiter(time.Duration(d.APIInterval) * time.Millisecond)
+	} else {
+		d.limiter.SetInterval(time.Duration(d.APIInterval) * time.Millisecond)
+	}
+
+	d.client.SetBaseURL(hostURL(config))
+	d.client.SetTimeout(10 * time.Second)
+	d.client.SetHeader("User-Agent", "goto-bangumi")
+	if config.Username != "" {
+		d.client.SetBasicAuth(config.Username, config.Password)
+	}
+	if !config.Ssl {
+		d.client.SetTLSClientConfig(&tls.Config{
+			InsecureSkipVerify: true,
+		})
+	}
+	return nil
+}
+
+func (d *TransmissionDownloader) wait(ctx context.Context) error {
+	if d.limiter == nil {
+		return nil
+	}
+	return d.limiter.Wait(ctx, "Transmission")
+}
+
+// call 调用 RPC 方法，session id 过期时自动更新后重试一次
+func (d *TransmissionDownloader) call(ctx context.Context, method string, args any, result any) error {
+	if err := d.wait(ctx); err != nil {
+		return err
+	}
+
+	var resp *resty.Response
+	var err error
+	for range 2 {
+		d.mu.RLock()
+		sessionID := d.sessionID
+		d.mu.RUnlock()
+
+		resp, err = d.client.R().
+			SetContext(ctx).
+			SetHeader(transmissionSessionID, sessionID).
+			SetBody(model.TransmissionRequest{Method: method, Arguments: args}).
+			Post(transmissionRPCPath)
+		if err != nil {
+			slog.Error("[Transmission] 连接错误", "method", method, "error", err)
+			return &apperrors.NetworkError{Err: fmt.Errorf("连接到Transmission时出错: %w", err)}
+		}
+		if resp.StatusCode() != 409 {
+			break
+		}
+		d.mu.Lock()
+		d.sessionID = resp.Header().Get(transmissionSessionID)
+		d.mu.Unlock()
+		slog.Debug("[Transmission] 更新 session id", "method", method)
+	}
+
+	switch resp.StatusCode() {
+	case 200:
+	case 401:
+		slog.Error("[Transmission] 登录失败，请检查用户名/密码", "username", d.config.Username)
+		return &apperrors.DownloadAuthenticationError{Err: fmt.Errorf("用户名或密码错误"), Name: d.config.Username}
+	case 403:
+		slog.Error("[Transmission] 访问被拒绝，请检查 rpc-whitelist 配置")
+		return &apperrors.DownloadForbiddenError{Err: fmt.Errorf("IP不在白名单中")}
+	default:
+		return &apperrors.NetworkError{Err: fmt.Errorf("%s 失败：状态码 %d", method, resp.StatusCode()), StatusCode: resp.StatusCode()}
+	}
+
+	var body struct {
+		Result    string          `json:"result"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal(resp.Body(), &body); err != nil {
+		return fmt.Errorf("解析 %s 响应失败: %w", method, err)
+	}
+	if body.Result != "success" {
+		return fmt.Errorf("[Transmission] %s 失败: %s", method, body.Result)
+	}
+	if result != nil && len(body.Arguments) > 0 {
+		if err := json.Unmarshal(body.Arguments, result); err != nil {
+			return fmt.Errorf("解析 %s 响应失败: %w", method, err)
+		}
+	}
+	return nil
+}
+
+// Auth 通过 session-get 完成 session id 握手，同时验证账号密码
+func (d *TransmissionDownloader) Auth(ctx context.Context) (bool, error) {
+	if err := d.call(ctx, "session-get", nil, nil); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Logout Transmission 没有登出接口，清掉 session id 即可
+func (d *TransmissionDownloader) Logout(ctx context.Context) (bool, error) {
+	d.mu.Lock()
+	d.sessionID = ""
+	d.mu.Unlock()
+	return true, nil
+}
+
+// getTorrents 按 hash 获取种子
+func (d *TransmissionDownloader) getTorrents(ctx context.Context, hashes []string) ([]model.TransmissionTorrent, error) {
+	args := map[string]any{"fields": transmissionFields}
+	if hashes != nil {
+		args["ids"] = hashes
+	}
+	var result struct {
+		Torrents []model.TransmissionTorrent `json:"torrents"`
+	}
+	if err := d.call(ctx, "torrent-get", args, &result); err != nil {
+		return nil, err
+	}
+	return result.Torrents, nil
+}
+
+// GetTorrentFiles 获取种子文件列表
+func (d *TransmissionDownloader) GetTorrentFiles(ctx context.Context, hash string) ([]string, error) {
+	torrents, err := d.getTorrents(ctx, []string{hash})
+	if err != nil {
+		return nil, err
+	}
+	if len(torrents) == 0 {
+		slog.Warn("[Transmission] 找不到种子", "hash", hash)
+		return nil, nil
+	}
+	t := &torrents[0]
+	files := make([]string, 0, len(t.Files))
+	for i, f := range t.Files {
+		// 未勾选下载的文件不返回
+		if i < len(t.FileStats) && !t.FileStats[i].Wanted {
+			continue
+		}
+		files = append(files, f.Name)
+	}
+	return files, nil
+}
+
+// GetTorrentInfo 获取单个种子信息
+func (d *TransmissionDownloader) GetTorrentInfo(ctx context.Context, hash string) (*model.TorrentStatus, error) {
+	torrents, err := d.getTorrents(ctx, []string{hash})
+	if err != nil {
+		return nil, err
+	}
+	if len(torrents) == 0 {
+		slog.Warn("[Transmission] 种子不存在", "hash", hash)
+		return nil, &apperrors.DownloadKeyError{Err: fmt.Errorf("种子不存在"), Key: hash}
+	}
+	return transmissionInfo(&torrents[0]), nil
+}
+
+// GetTorrentsInfo 一次 torrent-get 查询多个种子
+func (d *TransmissionDownloader) GetTorrentsInfo(ctx context.Context, hashes []string) (map[string]*model.TorrentStatus, error) {
+	result := make(map[string]*model.TorrentStatus, len(hashes))
+	if len(hashes) == 0 {
+		return result, nil
+	}
+	torrents, err := d.getTorrents(ctx, hashes)
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[string]*model.TorrentStatus, len(torrents))
+	for i := range torrents {
+		found[strings.ToLower(torrents[i].HashString)] = transmissionInfo(&torrents[i])
+	}
+	for _, hash := range hashes {
+		if info, ok := found[strings.ToLower(hash)]; ok {
+			result[hash] = info
+		}
+	}
+	return result, nil
+}
+
+// transmissionInfo 转换为统一的种子状态
+func transmissionInfo(t *model.TransmissionTorrent) *model.TorrentStatus {
+	info := &model.TorrentStatus{
+		Hash:          t.HashString,
+		Name:          t.Name,
+		State:         transmissionState(t),
+		Message:       t.ErrorString,
+		Progress:      t.PercentDone,
+		Size:          t.SizeWhenDone,
+		Downloaded:    t.DownloadedEver,
+		Uploaded:      t.UploadedEver,
+		DownloadSpeed: t.RateDownload,
+		UploadSpeed:   t.RateUpload,
+		ETA:           int(t.Eta),
+		Ratio:         t.UploadRatio,
+		SeedingTime:   t.SecondsSeeding,
+		SavePath:      t.DownloadDir,
+		Tags:          t.Labels,
+	}
+	if info.Tags == nil {
+		info.Tags = []string{}
+	}
+	// -2 表示无限
+	if info.ETA < 0 {
+		info.ETA = -1
+	}
+	if t.PercentDone >= 1 {
+		info.ETA = 0
+		info.Completed = int(t.DoneDate)
+		// 添加时已经存在的数据校验完成后 doneDate 可能为 0
+		if info.Completed <= 0 {
+			info.Completed = int(time.Now().Unix())
+		}
+	}
+	return info
+}
+
+// transmissionState 归一化 Transmission 的种子状态
+// 本地错误一般是文件丢失或磁盘问题，tracker 错误不影响从其他来源下载，不当作失败
+func transmissionState(t *model.TransmissionTorrent) model.DownloadState {
+	done := t.PercentDone >= 1
+	if t.Error == 3 {
+		if strings.Contains(strings.ToLower(t.ErrorString), "no data found") {
+			return model.DownloadStateMissingFiles
+		}
+		return model.DownloadStateFailed
+	}
+	switch t.Status {
+	case 0:
+		if done {
+			return model.DownloadStateCompleted
+		}
+		return model.DownloadStatePaused
+	case 1, 2:
+		return model.DownloadStateChecking
+	case 3:
+		return model.DownloadStateWaiting
+	case 4:
+		if t.MetadataPercentComplete < 1 {
+			return model.DownloadStateMetadata
+		}
+		if t.PeersSendingToUs == 0 && t.RateDownload == 0 {
+			return model.DownloadStateStalled
+		}
+		return model.DownloadStateDownloading
+	case 5, 6:
+		return model.DownloadStateSeeding
+	default:
+		return model.DownloadStateUnknown
+	}
+}
+
+// TorrentsInfo 获取种子信息列表
+// Transmission 没有分类，category 和 tag 都按 labels 过滤
+func (d *TransmissionDownloader) TorrentsInfo(ctx context.Context, statusFilter, category string, tag *string, limit int) ([]*model.TorrentStatus, error) {
+	torrents, err := d.getTorrents(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.TorrentStatus, 0, len(torrents))
+	for i := range torrents {
+		t := &torrents[i]
+		if category != "" && !hasLabel(t.Labels, category) {
+			continue
+		}
+		if tag != nil && !hasLabel(t.Labels, *tag) {
+			continue
+		}
+		done := t.PercentDone >= 1
+		if (statusFilter == "completed" && !done) || (statusFilter == "downloading" && done) {
+			continue
+		}
+		result = append(result, transmissionInfo(t))
+		if limit > 0 && len(result) >= limit {
+			break
+		}
+	}
+	return result, nil
+}
+
+func hasLabel(labels []string, label string) bool {
+	for _, l := range labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckHash 检查种子是否存在
+func (d *TransmissionDownloader) CheckHash(ctx context.Context, hash string) (string, error) {
+	info, err := d.GetTorrentInfo(ctx, hash)
+	if info != nil {
+		return hash, nil
+	}
+	return hash, err
+}
+
+// Add 添加种子，savePath 为相对路径时放在配置的下载目录下
+func (d *TransmissionDownloader) Add(ctx context.Context, torrentInfo *model.TorrentInfo, savePath string) ([]string, error) {
+	args := map[string]any{
+		"download-dir": d.downloadDir(savePath),
+		"paused":       false,
+	}
+	if len(torrentInfo.File) > 0 {
+		args["metainfo"] = base64.StdEncoding.EncodeToString(torrentInfo.File)
+	} else {
+		args["filename"] = torrentInfo.MagnetURI
+	}
+	if len(torrentInfo.SkipFiles) > 0 {
+		args["files-unwanted"] = torrentInfo.SkipFiles
+	}
+	// Transmission 没有分类，分类和标签都作为 labels，对账时按分类筛选
+	labels := slices.Clone(torrentInfo.Tags)
+	if d.config.Category != "" {
+		labels = append(labels, d.config.Category)
+	}
+	if len(labels) > 0 {
+		args["labels"] = labels
+	}
+
+	var result struct {
+		Added     *model.TransmissionTorrent `json:"torrent-added"`
+		Duplicate *model.TransmissionTorrent `json:"torrent-duplicate"`
+	}
+	if err := d.call(ctx, "torrent-add", args, &result); err != nil {
+		return nil, err
+	}
+
+	added := result.Added
+	if added == nil {
+		added = result.Duplicate
+		slog.Debug("[Transmission] 种子已存在", "name", torrentInfo.Name)
+	}
+	if added == nil || added.HashString == "" {
+		return nil, fmt.Errorf("[Transmission]添加种子失败: 没有返回种子信息")
+	}
+
+	// Transmission 直接返回真实的 hash，v1 hash 不同时也一并返回方便 Check 阶段比对
+	hashes := []string{added.HashString}
+	if torrentInfo.InfoHashV1 != "" && !strings.EqualFold(torrentInfo.InfoHashV1, added.HashString) {
+		hashes = append(hashes, torrentInfo.InfoHashV1)
+	}
+	return hashes, nil
+}
+
+func (d *TransmissionDownloader) downloadDir(savePath string) string {
+	if savePath == "" || path.IsAbs(savePath) {
+		return savePath
+	}
+	return path.Join(d.config.SavePath, savePath)
+}
+
+// Delete 删除种子，deleteFiles 为 true 时同时删除数据
+func (d *TransmissionDownloader) Delete(ctx context.Context, hashes []string, deleteFiles bool) (bool, error) {
+	args := map[string]any{
+		"ids":               hashes,
+		"delete-local-data": deleteFiles,
+	}
+	if err := d.call(ctx, "torrent-remove", args, nil); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Rename 重命名种子文件
+// torrent-rename-path 只能修改路径的最后一段，所以文件只会在原目录内改名
+func (d *TransmissionDownloader) Rename(ctx context.Context, torrentHash, oldPath, newPath string) (bool, error) {
+	args := map[string]any{
+		"ids":  []string{torrentHash},
+		"path": oldPath,
+		"name": path.Base(newPath),
+	}
+	if err := d.call(ctx, "torrent-rename-path", args, nil); err != nil {
+		slog.Error("[Transmission] 重命名错误", "oldPath", oldPath, "newPath", newPath, "error", err)
+		return false, err
+	}
+	return true, nil
+}
+
+// Move 移动种子到新位置
+func (d *TransmissionDownloader) Move(ctx context.Context, hashes []string, newLocation string) (bool, error) {
+	args := map[string]any{
+		"ids":      hashes,
+		"location": newLocation,
+		"move":     true,
+	}
+	if err := d.call(ctx, "torrent-set-location", args, nil); err != nil {
+		return false, err
+	}
+	return true, nil
+}
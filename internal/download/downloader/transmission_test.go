@@ -0,0 +1,254 @@
+package downloader
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+
+	"goto-bangumi/internal/apperrors"
+	"goto-bangumi/internal/model"
+)
+
+// fakeTransmission 进程内的 Transmission RPC 服务
+type fakeTransmission struct {
+	mu        sync.Mutex
+	sessionID string
+	torrents  map[string]*model.TransmissionTorrent
+	calls     []string
+	lastArgs  map[string]map[string]any
+}
+
+func newFakeTransmission(t *testing.T) (*fakeTransmission, *httptest.Server) {
+	t.Helper()
+	f := &fakeTransmission{
+		sessionID: "session-1",
+		torrents:  make(map[string]*model.TransmissionTorrent),
+		lastArgs:  make(map[string]map[string]any),
+	}
+	srv := httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func (f *fakeTransmission) serve(w http.ResponseWriter, r *http.Request) {
+	user, pass, _ := r.BasicAuth()
+	if user != "admin" || pass != "adminadmin" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.Header.Get(transmissionSessionID) != f.sessionID {
+		w.Header().Set(transmissionSessionID, f.sessionID)
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+
+	var req struct {
+		Method    string         `json:"method"`
+		Arguments map[string]any `json:"arguments"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+	f.calls = append(f.calls, req.Method)
+	f.lastArgs[req.Method] = req.Arguments
+
+	args := map[string]any{}
+	switch req.Method {
+	case "session-get":
+		args["version"] = "4.0.5"
+	case "torrent-add":
+		hash := "1317e47882474c771e29ed2271b282fbfb56e7d2"
+		if metainfo, _ := req.Arguments["metainfo"].(string); metainfo != "" {
+			data, _ := base64.StdEncoding.DecodeString(metainfo)
+			hash = strings.TrimSpace(string(data))
+		}
+		if t, ok := f.torrents[hash]; ok {
+			args["torrent-duplicate"] = t
+			break
+		}
+		t := &model.TransmissionTorrent{
+			ID:          len(f.torrents) + 1,
+			HashString:  hash,
+			Name:        "[Lilith-Raws] Sousou no Frieren - 01",
+			DownloadDir: req.Arguments["download-dir"].(string),
+			Eta:         120,
+			PercentDone: 0.5,
+			Files: []model.TransmissionTorrentFile{
+				{Name: "[Lilith-Raws] Sousou no Frieren - 01.mkv"},
+			},
+		}
+		f.torrents[hash] = t
+		args["torrent-added"] = t
+	case "torrent-get":
+		var list []*model.TransmissionTorrent
+		ids, hasIDs := req.Arguments["ids"].([]any)
+		for hash, t := range f.torrents {
+			if hasIDs && !containsAny(ids, hash) {
+				continue
+			}
+			list = append(list, t)
+		}
+		args["torrents"] = list
+	case "torrent-rename-path":
+		for _, id := range req.Arguments["ids"].([]any) {
+			t := f.torrents[id.(string)]
+			for i := range t.Files {
+				if t.Files[i].Name == req.Arguments["path"] {
+					t.Files[i].Name = path.Join(path.Dir(t.Files[i].Name), req.Arguments["name"].(string))
+				}
+			}
+		}
+	case "torrent-set-location":
+		for _, id := range req.Arguments["ids"].([]any) {
+			f.torrents[id.(string)].DownloadDir = req.Arguments["location"].(string)
+		}
+	case "torrent-remove":
+		for _, id := range req.Arguments["ids"].([]any) {
+			delete(f.torrents, id.(string))
+		}
+	default:
+		json.NewEncoder(w).Encode(map[string]any{"result": "method name not recognized"})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]any{"result": "success", "arguments": args})
+}
+
+func containsAny(ids []any, hash string) bool {
+	for _, id := range ids {
+		if id == hash {
+			return true
+		}
+	}
+	return false
+}
+
+func newTestTransmission(t *testing.T, host, password string) *TransmissionDownloader {
+	t.Helper()
+	d := NewTransmissionDownloader()
+	d.APIInterval = 0
+	err := d.Init(&model.DownloaderConfig{
+		Type:     "transmission",
+		SavePath: "/downloads/Bangumi",
+		Host:     host,
+		Username: "admin",
+		Password: password,
+	})
+	if err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	return d
+}
+
+func TestTransmission_AuthSessionHandshake(t *testing.T) {
+	f, srv := newFakeTransmission(t)
+	d := newTestTransmission(t, srv.URL, "adminadmin")
+	ctx := context.Background()
+
+	ok, err := d.Auth(ctx)
+	if err != nil || !ok {
+		t.Fatalf("Auth() = %v, %v, want true", ok, err)
+	}
+
+	// session id 过期后自动重新握手
+	f.mu.Lock()
+	f.sessionID = "session-2"
+	f.mu.Unlock()
+	if _, err := d.Auth(ctx); err != nil {
+		t.Fatalf("Auth() after session rotation error: %v", err)
+	}
+	if d.sessionID != "session-2" {
+		t.Errorf("sessionID = %q, want session-2", d.sessionID)
+	}
+}
+
+func TestTransmission_AuthWrongPassword(t *testing.T) {
+	_, srv := newFakeTransmission(t)
+	d := newTestTransmission(t, srv.URL, "wrong")
+
+	_, err := d.Auth(context.Background())
+	if !apperrors.IsDownloadAuthenticationError(err) {
+		t.Fatalf("Auth() error = %v, want DownloadAuthenticationError", err)
+	}
+}
+
+func TestTransmission_TorrentLifecycle(t *testing.T) {
+	f, srv := newFakeTransmission(t)
+	d := newTestTransmission(t, srv.URL, "adminadmin")
+	ctx := context.Background()
+	hash := "aaaabbbbccccddddeeeeffff0000111122223333"
+
+	hashes, err := d.Add(ctx, &model.TorrentInfo{
+		Name:       "Frieren",
+		InfoHashV1: hash,
+		File:       []byte(hash),
+	}, "Sousou no Frieren (2023)/Season 1")
+	if err != nil {
+		t.Fatalf("Add error: %v", err)
+	}
+	if len(hashes) != 1 || hashes[0] != hash {
+		t.Fatalf("Add hashes = %v, want [%s]", hashes, hash)
+	}
+	if dir := f.lastArgs["torrent-add"]["download-dir"]; dir != "/downloads/Bangumi/Sousou no Frieren (2023)/Season 1" {
+		t.Errorf("download-dir = %v, want joined with SavePath", dir)
+	}
+
+	// 重复添加返回已有种子的 hash
+	if hashes, err := d.Add(ctx, &model.TorrentInfo{InfoHashV1: hash, File: []byte(hash)}, "x"); err != nil || hashes[0] != hash {
+		t.Fatalf("duplicate Add = %v, %v", hashes, err)
+	}
+
+	if got, err := d.CheckHash(ctx, hash); err != nil || got != hash {
+		t.Fatalf("CheckHash = %q, %v", got, err)
+	}
+	info, err := d.GetTorrentInfo(ctx, hash)
+	if err != nil {
+		t.Fatalf("GetTorrentInfo error: %v", err)
+	}
+	if info.ETA != 120 || info.Completed != 0 {
+		t.Errorf("info = %+v, want eta 120 and not completed", info)
+	}
+
+	f.mu.Lock()
+	f.torrents[hash].PercentDone = 1
+	f.torrents[hash].DoneDate = 1700000000
+	f.mu.Unlock()
+	infos, err := d.GetTorrentsInfo(ctx, []string{hash, "missing"})
+	if err != nil {
+		t.Fatalf("GetTorrentsInfo error: %v", err)
+	}
+	if len(infos) != 1 || infos[hash].Completed != 1700000000 {
+		t.Fatalf("GetTorrentsInfo = %+v, want completed torrent only", infos)
+	}
+
+	files, err := d.GetTorrentFiles(ctx, hash)
+	if err != nil || len(files) != 1 {
+		t.Fatalf("GetTorrentFiles = %v, %v", files, err)
+	}
+	if _, err := d.Rename(ctx, hash, files[0], "Sousou no Frieren S01E01.mkv"); err != nil {
+		t.Fatalf("Rename error: %v", err)
+	}
+	files, _ = d.GetTorrentFiles(ctx, hash)
+	if files[0] != "Sousou no Frieren S01E01.mkv" {
+		t.Errorf("renamed file = %q", files[0])
+	}
+
+	if _, err := d.Move(ctx, []string{hash}, "/media/Bangumi"); err != nil {
+		t.Fatalf("Move error: %v", err)
+	}
+	if info, _ := d.GetTorrentInfo(ctx, hash); info.SavePath != "/media/Bangumi" {
+		t.Errorf("SavePath after Move = %q", info.SavePath)
+	}
+
+	if _, err := d.Delete(ctx, []string{hash}, true); err != nil {
+		t.Fatalf("Delete error: %v", err)
+	}
+	if _, err := d.GetTorrentInfo(ctx, hash); !apperrors.IsKeyError(err) {
+		t.Fatalf("GetTorrentInfo after Delete error = %v, want key error", err)
+	}
+}
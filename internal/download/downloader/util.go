@@ -0,0 +1,64 @@
+package downloader
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"syscall"
+
+	"goto-bangumi/internal/model"
+)
+
+// hostURL 根据配置补全下载器地址的协议前缀
+func hostURL(config *model.DownloaderConfig) string {
+	baseURL := config.Host
+	if !strings.HasPrefix(baseURL, "http") {
+		if config.Ssl {
+			baseURL = "https://" + baseURL
+		} else {
+			baseURL = "http://" + baseURL
+		}
+	}
+	return baseURL
+}
+
+// moveFile 移动本地文件，跨文件系统时 os.Rename 会返回 EXDEV，改为复制后删除源文件
+func moveFile(src, dst string) error {
+	err := os.Rename(src, dst)
+	if err == nil || !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+	if err := copyFile(src, dst); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+// copyFile 复制文件内容和权限，目标已存在时失败，复制失败时删除不完整的目标文件
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err == nil {
+		err = out.Sync()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dst)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package download
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"goto-bangumi/internal/model"
+)
+
+// DefaultDownloaderName 默认下载器未配置名称时使用的名称
+const DefaultDownloaderName = "default"
+
+// Manager 管理多个命名的下载客户端，按名称路由到对应的下载器
+type Manager struct {
+	mu          sync.RWMutex
+	clients     map[string]*DownloadClient
+	order       []string
+	defaultName string
+	listeners   []SessionListener
+}
+
+// NewManager 创建下载器管理器
+func NewManager() *Manager {
+	return &Manager{clients: make(map[string]*DownloadClient)}
+}
+
+// NewManagerWith 用已有的下载客户端创建管理器，第一个为默认下载器，名称为空时使用默认名称
+func NewManagerWith(clients ...*DownloadClient) *Manager {
+	m := NewManager()
+	for i, client := range clients {
+		if client.Name == "" && i == 0 {
+			client.Name = DefaultDownloaderName
+		}
+		m.clients[client.Name] = client
+		m.order = append(m.order, client.Name)
+	}
+	if len(m.order) > 0 {
+		m.defaultName = m.order[0]
+	}
+	return m
+}
+
+// Init 根据配置创建下载客户端，第一个配置为默认下载器
+// 已存在的同名客户端会复用并重新初始化，不再出现在配置中的客户端会被移除
+func (m *Manager) Init(configs []model.DownloaderConfig) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	clients := make(map[string]*DownloadClient, len(configs))
+	order := make([]string, 0, len(configs))
+	for i := range configs {
+		cfg := &configs[i]
+		name := cfg.Name
+		if name == "" {
+			if i > 0 {
+				slog.Warn("[download manager] 下载器缺少名称，已忽略", "type", cfg.Type)
+				continue
+			}
+			name = DefaultDownloaderName
+		}
+		if _, ok := clients[name]; ok {
+			slog.Warn("[download manager] 下载器名称重复，已忽略", "name", name)
+			continue
+		}
+
+		client, ok := m.clients[name]
+		if !ok {
+			client = NewDownloadClient()
+			for _, fn := range m.listeners {
+				client.OnStateChange(fn)
+			}
+		}
+		client.Name = name
+		client.Init(cfg)
+		clients[name] = client
+		order = append(order, name)
+	}
+	m.clients = clients
+	m.order = order
+	if len(order) > 0 {
+		m.defaultName = order[0]
+	}
+}
+
+// Get 按名称获取下载客户端，名称为空或不存在时返回默认下载器
+func (m *Manager) Get(name string) *DownloadClient {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if name != "" {
+		if client, ok := m.clients[name]; ok {
+			return client
+		}
+		slog.Warn("[download manager] 未找到下载器，使用默认下载器", "name", name, "default", m.defaultName)
+	}
+	return m.clients[m.defaultName]
+}
+
+// Default 返回默认下载客户端
+func (m *Manager) Default() *DownloadClient {
+	return m.Get("")
+}
+
+// Clients 按配置顺序返回所有下载客户端
+func (m *Manager) Clients() []*DownloadClient {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	clients := make([]*DownloadClient, 0, len(m.order))
+	for _, name := range m.order {
+		clients = append(clients, m.clients[name])
+	}
+	return clients
+}
+
+// OnStateChange 注册所有下载器的连接状态变化回调，之后 Init 新建的下载器同样生效
+func (m *Manager) OnStateChange(fn SessionListener) {
+	m.mu.Lock()
+	m.listeners = append(m.listeners, fn)
+	clients := make([]*DownloadClient, 0, len(m.clients))
+	for _, client := range m.clients {
+		clients = append(clients, client)
+	}
+	m.mu.Unlock()
+	for _, client := range clients {
+		client.OnStateChange(fn)
+	}
+}
+
+// LoginAll 登录所有下载器
+func (m *Manager) LoginAll(ctx context.Context) {
+	for _, client := range m.Clients() {
+		go func() {
+			if err := client.Login(ctx); err != nil {
+				slog.Warn("[download manager] 下载器登录失败", "name", client.Name, "error", err)
+			}
+		}()
+	}
+}
@@ -0,0 +1,71 @@
+package download
+
+import (
+	"context"
+	"testing"
+
+	"goto-bangumi/internal/download/downloader"
+	"goto-bangumi/internal/model"
+)
+
+func TestManagerRoutesByName(t *testing.T) {
+	m := NewManager()
+	m.Init([]model.DownloaderConfig{
+		{Type: "mock", SavePath: "/downloads/Bangumi"},
+		{Name: "cloud", Type: "mock", SavePath: "/115/Bangumi"},
+		{Type: "mock"}, // 缺少名称，忽略
+		{Name: "cloud", Type: "mock"},
+	})
+
+	if got := len(m.Clients()); got != 2 {
+		t.Fatalf("Clients() = %d, want 2", got)
+	}
+	if got := m.Default().Name; got != DefaultDownloaderName {
+		t.Errorf("Default().Name = %q, want %q", got, DefaultDownloaderName)
+	}
+	if got := m.Get("cloud").SavePath; got != "/115/Bangumi" {
+		t.Errorf("Get(cloud).SavePath = %q", got)
+	}
+	if m.Get("unknown") != m.Default() || m.Get("") != m.Default() {
+		t.Error("unknown or empty name should fall back to the default downloader")
+	}
+
+	// 重新初始化时复用同名客户端
+	cloud := m.Get("cloud")
+	m.Init([]model.DownloaderConfig{
+		{Name: "home", Type: "mock"},
+		{Name: "cloud", Type: "mock", SavePath: "/115/Anime"},
+	})
+	if m.Get("cloud") != cloud || cloud.SavePath != "/115/Anime" {
+		t.Error("Init did not reuse the existing client")
+	}
+	if m.Default().Name != "home" {
+		t.Errorf("Default().Name = %q, want home", m.Default().Name)
+	}
+}
+
+func TestStatusPollerSweepsEachDownloader(t *testing.T) {
+	ctx := context.Background()
+	m := NewManager()
+	m.Init([]model.DownloaderConfig{
+		{Type: "mock", SavePath: "/downloads/Bangumi"},
+		{Name: "cloud", Type: "mock", SavePath: "/115/Bangumi"},
+	})
+	cloud := m.Get("cloud").Downloader.(*downloader.MockDownloader)
+	if _, err := cloud.Add(ctx, &model.TorrentInfo{Name: "cloud-only", InfoHashV1: "cloudonly"}, "/115/Bangumi"); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	poller := NewStatusPoller(m, 0, nil)
+	poller.Track("cloudonly", "cloud-link", "cloud")
+	poller.Track("cloudonly-home", "home-link", "")
+	if err := poller.Sweep(ctx); err != nil {
+		t.Fatalf("Sweep() error = %v", err)
+	}
+	if info, swept := poller.Status("cloudonly"); !swept || info == nil {
+		t.Fatalf("cloud torrent status = %v/%v, want found in the cloud downloader", info, swept)
+	}
+	if info, swept := poller.Status("cloudonly-home"); !swept || info != nil {
+		t.Fatalf("home torrent status = %v/%v, want missing from the default downloader", info, swept)
+	}
+}
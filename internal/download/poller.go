@@ -0,0 +1,169 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"goto-bangumi/internal/model"
+)
+
+// 超过这个时间没有被 Track 的种子视为任务已经不在了，从轮询列表移除
+const pollerStaleAfter = 30 * time.Minute
+
+// StatusPoller 批量轮询下载状态
+// Downloading 阶段的任务只登记自己的 hash 并读取缓存，真正的查询由 poller 每个周期用一次请求完成，
+// 种子完成、失败或从下载器消失时通过 wake 立即唤醒对应任务。多个下载器时按下载器分组，每个下载器一次请求。
+type StatusPoller struct {
+	clients  *Manager
+	interval time.Duration
+	wake     func(link string)
+
+	mu      sync.Mutex
+	tracked map[string]*polledTorrent // key 为 hash
+	signal  chan struct{}
+}
+
+type polledTorrent struct {
+	link       string
+	downloader string
+	info       *model.TorrentStatus
+	swept      bool // 是否已经被轮询过，swept 且 info 为 nil 表示下载器中不存在
+	lastSeen   time.Time
+}
+
+// NewStatusPoller 创建状态轮询器，wake 用于唤醒种子对应的任务
+func NewStatusPoller(clients *Manager, interval time.Duration, wake func(link string)) *StatusPoller {
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	return &StatusPoller{
+		clients:  clients,
+		interval: interval,
+		wake:     wake,
+		tracked:  make(map[string]*polledTorrent),
+		signal:   make(chan struct{}, 1),
+	}
+}
+
+// Interval 返回轮询间隔
+func (p *StatusPoller) Interval() time.Duration {
+	return p.interval
+}
+
+// Track 登记需要轮询的种子和所在的下载器，新登记的种子会触发一次立即轮询
+func (p *StatusPoller) Track(hash, link, downloader string) {
+	p.mu.Lock()
+	pt, ok := p.tracked[hash]
+	if !ok {
+		pt = &polledTorrent{}
+		p.tracked[hash] = pt
+	}
+	pt.link = link
+	pt.downloader = downloader
+	pt.lastSeen = time.Now()
+	p.mu.Unlock()
+
+	if !ok {
+		select {
+		case p.signal <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Untrack 移除种子
+func (p *StatusPoller) Untrack(hash string) {
+	p.mu.Lock()
+	delete(p.tracked, hash)
+	p.mu.Unlock()
+}
+
+// Status 返回最近一次轮询的结果，swept 为 false 表示还没有轮询过
+func (p *StatusPoller) Status(hash string) (info *model.TorrentStatus, swept bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pt, ok := p.tracked[hash]
+	if !ok {
+		return nil, false
+	}
+	return pt.info, pt.swept
+}
+
+// Start 启动轮询循环
+func (p *StatusPoller) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-p.signal:
+			}
+			if err := p.Sweep(ctx); err != nil {
+				slog.Warn("[download poller] 批量获取种子信息失败", "error", err)
+			}
+		}
+	}()
+}
+
+// Sweep 每个下载器用一次请求获取登记种子的状态，单个下载器失败不影响其他下载器
+func (p *StatusPoller) Sweep(ctx context.Context) error {
+	p.mu.Lock()
+	now := time.Now()
+	groups := make(map[string][]string)
+	for hash, pt := range p.tracked {
+		if now.Sub(pt.lastSeen) > pollerStaleAfter {
+			delete(p.tracked, hash)
+			continue
+		}
+		groups[pt.downloader] = append(groups[pt.downloader], hash)
+	}
+	p.mu.Unlock()
+
+	var errs []error
+	for name, hashes := range groups {
+		if err := p.sweep(ctx, p.clients.Get(name), hashes); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// sweep 查询一个下载器中的种子
+func (p *StatusPoller) sweep(ctx context.Context, client *DownloadClient, hashes []string) error {
+	infos, err := client.GetTorrentsInfo(ctx, hashes)
+	if err != nil {
+		return err
+	}
+
+	var wake []string
+	p.mu.Lock()
+	for _, hash := range hashes {
+		pt, ok := p.tracked[hash]
+		if !ok {
+			continue
+		}
+		info := infos[hash]
+		wasDone := pt.swept && (pt.info == nil || pt.info.Done() || pt.info.Failed())
+		pt.info = info
+		pt.swept = true
+		if !wasDone && (info == nil || info.Done() || info.Failed()) {
+			wake = append(wake, pt.link)
+		}
+	}
+	p.mu.Unlock()
+
+	slog.Debug("[download poller] 批量轮询完成", "downloader", client.Name, "tracked", len(hashes), "found", len(infos))
+	if p.wake != nil {
+		for _, link := range wake {
+			p.wake(link)
+		}
+	}
+	return nil
+}
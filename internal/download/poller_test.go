@@ -0,0 +1,59 @@
+package download
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"goto-bangumi/internal/download/downloader"
+	"goto-bangumi/internal/model"
+)
+
+func TestStatusPollerSweepWakesFinishedTorrents(t *testing.T) {
+	ctx := context.Background()
+	client := NewDownloadClient()
+	client.Init(&model.DownloaderConfig{Type: "mock", SavePath: "/downloads/Bangumi"})
+	mock := client.Downloader.(*downloader.MockDownloader)
+	if _, err := mock.Add(ctx, &model.TorrentInfo{Name: "downloading", InfoHashV1: "downloading"}, "/downloads/Bangumi"); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	var woke []string
+	poller := NewStatusPoller(NewManagerWith(client), 0, func(link string) {
+		woke = append(woke, link)
+	})
+	poller.Track("1317e47882474c771e29ed2271b282fbfb56e7d2", "finished", "")
+	poller.Track("downloading", "downloading", "")
+	poller.Track("missing", "missing", "")
+
+	if _, swept := poller.Status("downloading"); swept {
+		t.Fatal("status reported swept before the first sweep")
+	}
+
+	if err := poller.Sweep(ctx); err != nil {
+		t.Fatalf("Sweep() error = %v", err)
+	}
+	slices.Sort(woke)
+	if !slices.Equal(woke, []string{"finished", "missing"}) {
+		t.Fatalf("woke = %v, want finished and missing", woke)
+	}
+	if info, swept := poller.Status("missing"); !swept || info != nil {
+		t.Fatalf("missing status = %v/%v, want swept without info", info, swept)
+	}
+	info, swept := poller.Status("downloading")
+	if !swept || info == nil || info.Completed != 0 {
+		t.Fatalf("downloading status = %+v/%v, want swept and unfinished", info, swept)
+	}
+
+	// 已经唤醒过的种子不会重复唤醒，模拟种子在第三次查询时完成
+	woke = nil
+	poller.Untrack("missing")
+	for range 2 {
+		if err := poller.Sweep(ctx); err != nil {
+			t.Fatalf("Sweep() error = %v", err)
+		}
+	}
+	if !slices.Equal(woke, []string{"downloading"}) {
+		t.Fatalf("woke = %v, want only downloading", woke)
+	}
+}
@@ -0,0 +1,119 @@
+package download
+
+import (
+	"log/slog"
+	"path"
+	"strings"
+
+	"goto-bangumi/internal/model"
+	"goto-bangumi/internal/parser"
+
+	"github.com/dlclark/regexp2"
+)
+
+// FileSelector 按扩展名、排除规则和大小筛选种子内需要下载的文件
+type FileSelector struct {
+	extensions map[string]bool
+	exclude    []*regexp2.Regexp
+	skipPoint5 bool
+	minSize    int64
+	maxSize    int64
+}
+
+var fileSelector *FileSelector
+
+// InitFileSelector 根据配置初始化文件筛选器，未启用时不筛选任何文件
+func InitFileSelector(cfg *model.FileSelectConfig) {
+	if cfg == nil || !cfg.Enable {
+		fileSelector = nil
+		return
+	}
+	fileSelector = NewFileSelector(cfg)
+}
+
+// NewFileSelector 创建文件筛选器，无法编译的排除规则会被忽略
+func NewFileSelector(cfg *model.FileSelectConfig) *FileSelector {
+	s := &FileSelector{
+		extensions: make(map[string]bool, len(cfg.Extensions)),
+		skipPoint5: cfg.SkipPoint5,
+		minSize:    cfg.MinSize << 20,
+		maxSize:    cfg.MaxSize << 20,
+	}
+	for _, ext := range cfg.Extensions {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		s.extensions[ext] = true
+	}
+	for _, pattern := range cfg.Exclude {
+		re, err := regexp2.Compile(pattern, regexp2.None)
+		if err != nil {
+			slog.Warn("[file selector] 排除规则无效，已忽略", "pattern", pattern, "error", err)
+			continue
+		}
+		s.exclude = append(s.exclude, re)
+	}
+	return s
+}
+
+// WantedPath 只按路径判断文件是否需要下载
+func (s *FileSelector) WantedPath(filePath string) bool {
+	if s == nil {
+		return true
+	}
+	if len(s.extensions) > 0 && !s.extensions[strings.ToLower(path.Ext(filePath))] {
+		return false
+	}
+	for _, re := range s.exclude {
+		if ok, _ := re.MatchString(filePath); ok {
+			return false
+		}
+	}
+	if s.skipPoint5 && parser.IsPoint5(path.Base(filePath)) {
+		return false
+	}
+	return true
+}
+
+// Wanted 判断文件是否需要下载
+func (s *FileSelector) Wanted(filePath string, size int64) bool {
+	if !s.WantedPath(filePath) {
+		return false
+	}
+	if s == nil {
+		return true
+	}
+	if s.minSize > 0 && size < s.minSize {
+		return false
+	}
+	if s.maxSize > 0 && size > s.maxSize {
+		return false
+	}
+	return true
+}
+
+// SkipIndexes 返回不需要下载的文件下标，所有文件都不需要时返回空，避免添加一个什么都不下载的种子
+func (s *FileSelector) SkipIndexes(files []model.TorrentFile) []int {
+	if s == nil {
+		return nil
+	}
+	var skip []int
+	for i, f := range files {
+		if !s.Wanted(f.Path, f.Size) {
+			skip = append(skip, i)
+		}
+	}
+	if len(skip) == len(files) {
+		return nil
+	}
+	return skip
+}
+
+// WantedPath 使用全局筛选器判断文件是否需要处理，重命名时用来跳过未下载的文件
+func WantedPath(filePath string) bool {
+	return fileSelector.WantedPath(filePath)
+}
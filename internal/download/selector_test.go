@@ -0,0 +1,108 @@
+package download
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"goto-bangumi/internal/model"
+
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/ilyakaznacheev/cleanenv"
+)
+
+// defaultSelectConfig 读取配置中的默认筛选规则
+func defaultSelectConfig(t *testing.T) *model.FileSelectConfig {
+	t.Helper()
+	cfg := &model.FileSelectConfig{}
+	if err := cleanenv.ReadEnv(cfg); err != nil {
+		t.Fatalf("ReadEnv: %v", err)
+	}
+	return cfg
+}
+
+func TestFileSelector_Wanted(t *testing.T) {
+	s := NewFileSelector(defaultSelectConfig(t))
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"[LoliHouse] Frieren/[LoliHouse] Frieren - 01 [WebRip 1080p HEVC-10bit AAC].mkv", true},
+		{"[LoliHouse] Frieren/[LoliHouse] Frieren - 01 [WebRip 1080p HEVC-10bit AAC].SC.ass", true},
+		{"[VCB-Studio] Frieren/SPs/[VCB-Studio] Frieren [Menu01][Ma10p_1080p][x265_flac].mkv", false},
+		{"[VCB-Studio] Frieren/[VCB-Studio] Frieren [NCOP][Ma10p_1080p][x265_flac].mkv", false},
+		{"[VCB-Studio] Frieren/[VCB-Studio] Frieren [NCED2][Ma10p_1080p][x265_flac].mkv", false},
+		{"[VCB-Studio] Frieren/Scans/01.jpg", false},
+		{"[VCB-Studio] Frieren/Fonts/SourceHanSans.otf", false},
+		{"[VCB-Studio] Frieren/readme.txt", false},
+		{"[LoliHouse] Slime S3/[LoliHouse] Slime S3 - 17.5 [WebRip 1080p].mkv", false},
+		// 标题里的 SPY 不能被当作 SP
+		{"[Skymoon-Raws] SPY×FAMILY Season 3 - 41 [ViuTV][WEB-DL][1080p].mkv", true},
+	}
+	for _, tt := range tests {
+		if got := s.Wanted(tt.path, 1<<30); got != tt.want {
+			t.Errorf("Wanted(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestFileSelector_Size(t *testing.T) {
+	cfg := defaultSelectConfig(t)
+	cfg.MinSize, cfg.MaxSize = 10, 100
+	s := NewFileSelector(cfg)
+	for size, want := range map[int64]bool{5 << 20: false, 50 << 20: true, 200 << 20: false} {
+		if got := s.Wanted("Frieren - 01.mkv", size); got != want {
+			t.Errorf("Wanted(size %d) = %v, want %v", size, got, want)
+		}
+	}
+}
+
+func TestFileSelector_SkipIndexes(t *testing.T) {
+	s := NewFileSelector(defaultSelectConfig(t))
+	files := []model.TorrentFile{
+		{Path: "Frieren/Frieren - 01.mkv"},
+		{Path: "Frieren/NCOP.mkv"},
+		{Path: "Frieren/Scans/01.png"},
+	}
+	if got := s.SkipIndexes(files); !reflect.DeepEqual(got, []int{1, 2}) {
+		t.Errorf("SkipIndexes() = %v, want [1 2]", got)
+	}
+	// 所有文件都不需要时不跳过，交给下载器照常下载
+	if got := s.SkipIndexes(files[1:]); got != nil {
+		t.Errorf("SkipIndexes() with nothing wanted = %v, want nil", got)
+	}
+
+	var disabled *FileSelector
+	if got := disabled.SkipIndexes(files); got != nil || !disabled.WantedPath("readme.txt") {
+		t.Errorf("nil selector should not skip anything, got %v", got)
+	}
+}
+
+func TestParseTorrent_Files(t *testing.T) {
+	data, err := os.ReadFile("./test_data/test2.torrent")
+	if err != nil {
+		t.Fatalf("read torrent: %v", err)
+	}
+	info, err := ParseTorrent(data)
+	if err != nil {
+		t.Fatalf("ParseTorrent() error = %v", err)
+	}
+	if len(info.Files) != 1 || info.Files[0].Path != info.Name || info.Files[0].Size <= 0 {
+		t.Errorf("Files = %+v", info.Files)
+	}
+}
+
+func TestTorrentFilesSkipsPadding(t *testing.T) {
+	info := &metainfo.Info{
+		Name: "Show",
+		Files: []metainfo.FileInfo{
+			{Path: []string{"01.mkv"}, Length: 100},
+			{Path: []string{".pad", "28"}, Length: 28, ExtendedFileAttrs: metainfo.ExtendedFileAttrs{Attr: "p"}},
+			{Path: []string{"02.mkv"}, Length: 200},
+		},
+	}
+	files := torrentFiles(info)
+	if len(files) != 2 || files[0].Path != "Show/01.mkv" || files[1].Path != "Show/02.mkv" {
+		t.Errorf("torrentFiles() = %+v", files)
+	}
+}
@@ -0,0 +1,213 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"goto-bangumi/internal/apperrors"
+)
+
+// 登录遇到网络错误后的退避时间，每失败一次翻倍
+const (
+	sessionBackoffMin = 5 * time.Second
+	sessionBackoffMax = 5 * time.Minute
+)
+
+// sessionLoginTimeout 单次登录的超时时间，登录不跟随调用方的 ctx
+const sessionLoginTimeout = 30 * time.Second
+
+// SessionState 下载器连接状态
+type SessionState string
+
+const (
+	SessionDisconnected SessionState = "disconnected" // 尚未登录，或登录已失效
+	SessionConnected    SessionState = "connected"
+	SessionBackoff      SessionState = "backoff"     // 网络错误，等待退避结束后重新登录
+	SessionAuthFailed   SessionState = "auth_failed" // 认证失败，需要修改配置后重新 Init
+)
+
+// SessionStatus 下载器连接状态的快照
+type SessionStatus struct {
+	State    SessionState
+	Err      error     // 最近一次登录失败的原因
+	Failures int       // 连续网络错误的次数
+	RetryAt  time.Time // 退避结束的时间
+}
+
+// SessionEvent 下载器连接状态变化
+type SessionEvent struct {
+	Downloader string
+	From       SessionState
+	To         SessionState
+	Err        error
+}
+
+// SessionListener 连接状态变化回调，在状态变化的协程中同步调用，不应阻塞
+type SessionListener func(SessionEvent)
+
+// session 管理一个下载器的登录状态，所有字段由 mu 保护
+// generation 在 reset 时递增，重置前发起的登录结果会被丢弃，避免旧配置的结果覆盖新配置
+type session struct {
+	mu         sync.Mutex
+	status     SessionStatus
+	generation uint64
+	listeners  []SessionListener
+	group      singleflight.Group
+}
+
+// snapshot 返回当前状态
+func (s *session) snapshot() SessionStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := s.status
+	if status.State == "" {
+		status.State = SessionDisconnected
+	}
+	return status
+}
+
+// subscribe 注册状态变化回调
+func (s *session) subscribe(fn SessionListener) {
+	s.mu.Lock()
+	s.listeners = append(s.listeners, fn)
+	s.mu.Unlock()
+}
+
+// transition 在持有 mu 时切换状态，返回需要在释放锁之后发布的事件
+func (s *session) transition(name string, status SessionStatus) (func(), bool) {
+	from := s.status.State
+	if from == "" {
+		from = SessionDisconnected
+	}
+	s.status = status
+	if from == status.State {
+		return nil, false
+	}
+	listeners := append([]SessionListener(nil), s.listeners...)
+	event := SessionEvent{Downloader: name, From: from, To: status.State, Err: status.Err}
+	return func() {
+		for _, fn := range listeners {
+			fn(event)
+		}
+	}, true
+}
+
+// set 切换状态并发布变化
+func (s *session) set(name string, status SessionStatus) {
+	s.mu.Lock()
+	publish, changed := s.transition(name, status)
+	s.mu.Unlock()
+	if changed {
+		publish()
+	}
+}
+
+// reset 配置变化后清除错误状态，下次调用时重新登录
+func (s *session) reset(name string) {
+	s.mu.Lock()
+	s.generation++
+	publish, changed := s.transition(name, SessionStatus{State: SessionDisconnected})
+	s.mu.Unlock()
+	if changed {
+		publish()
+	}
+}
+
+// expire 登录失效，只有当前仍是已连接时才改为未登录
+func (s *session) expire(name string) {
+	s.mu.Lock()
+	if s.status.State != SessionConnected {
+		s.mu.Unlock()
+		return
+	}
+	publish, _ := s.transition(name, SessionStatus{State: SessionDisconnected})
+	s.mu.Unlock()
+	publish()
+}
+
+// login 使用 singleflight 保证同一时间只有一个协程登录，auth 为下载器的认证方法
+// 登录结果由所有等待的协程共享，所以在独立的 ctx 上执行，第一个调用方被取消不会让登录失败；
+// 调用方的 ctx 结束时只是不再等待结果
+func (s *session) login(ctx context.Context, name string, auth func(context.Context) (bool, error)) error {
+	s.mu.Lock()
+	generation := s.generation
+	s.mu.Unlock()
+
+	ch := s.group.DoChan("login", func() (any, error) {
+		loginCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), sessionLoginTimeout)
+		defer cancel()
+		_, err := auth(loginCtx)
+
+		s.mu.Lock()
+		if s.generation != generation || errors.Is(err, context.Canceled) {
+			// 登录期间配置已变化，或者登录被取消，结果作废，交给下一次调用重新登录
+			s.mu.Unlock()
+			return nil, err
+		}
+		var status SessionStatus
+		switch {
+		case err == nil:
+			status = SessionStatus{State: SessionConnected}
+		case apperrors.IsDownloadAuthenticationError(err) || apperrors.IsDownloadForbiddenError(err) || apperrors.IsDownloadLoginError(err):
+			err = apperrors.NewDownloadLoginError(fmt.Errorf("下载客户端认证失败，请检查配置: %w", err))
+			status = SessionStatus{State: SessionAuthFailed, Err: err}
+		default:
+			failures := s.status.Failures + 1
+			status = SessionStatus{
+				State:    SessionBackoff,
+				Err:      err,
+				Failures: failures,
+				RetryAt:  time.Now().Add(sessionBackoff(failures)),
+			}
+			slog.Error("[download client] 下载客户端登录失败，网络错误", "name", name, "failures", failures, "error", err)
+		}
+		publish, changed := s.transition(name, status)
+		s.mu.Unlock()
+		if changed {
+			publish()
+		}
+		return nil, err
+	})
+	select {
+	case res := <-ch:
+		return res.Err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ensure 确保已登录：已连接直接返回，认证失败和退避期间不再请求下载器
+func (s *session) ensure(ctx context.Context, name string, auth func(context.Context) (bool, error)) error {
+	status := s.snapshot()
+	switch status.State {
+	case SessionConnected:
+		return nil
+	case SessionAuthFailed:
+		return status.Err
+	case SessionBackoff:
+		if wait := time.Until(status.RetryAt); wait > 0 {
+			return &apperrors.NetworkError{Err: fmt.Errorf("下载器连接失败，%s 后重试: %w", wait.Round(time.Second), status.Err)}
+		}
+	}
+	return s.login(ctx, name, auth)
+}
+
+// sessionBackoff 第 failures 次失败后的退避时间
+func sessionBackoff(failures int) time.Duration {
+	backoff := sessionBackoffMin
+	for i := 1; i < failures && backoff < sessionBackoffMax; i++ {
+		backoff *= 2
+	}
+	return min(backoff, sessionBackoffMax)
+}
+
+// isSessionExpired 操作返回的错误是否表示登录已失效
+func isSessionExpired(err error) bool {
+	return apperrors.IsDownloadAuthenticationError(err) || apperrors.IsDownloadForbiddenError(err)
+}
@@ -0,0 +1,228 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"goto-bangumi/internal/apperrors"
+	"goto-bangumi/internal/download/downloader"
+	"goto-bangumi/internal/model"
+)
+
+// sessionDownloader 按设置返回登录结果，expired 次数内的操作返回登录失效
+type sessionDownloader struct {
+	downloader.BaseDownloader
+	mu      sync.Mutex
+	authErr error
+	auths   int
+	expired int
+	release chan struct{} // 不为 nil 时登录阻塞到 release 关闭
+}
+
+func (d *sessionDownloader) Auth(ctx context.Context) (bool, error) {
+	if d.release != nil {
+		select {
+		case <-d.release:
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.auths++
+	return d.authErr == nil, d.authErr
+}
+
+func (d *sessionDownloader) GetTorrentFiles(context.Context, string) ([]string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.expired > 0 {
+		d.expired--
+		return nil, &apperrors.DownloadAuthenticationError{Err: errors.New("需要先登录")}
+	}
+	return []string{"a.mkv"}, nil
+}
+
+func newSessionClient(fake *sessionDownloader) (*DownloadClient, *[]SessionEvent) {
+	client := NewDownloadClient()
+	client.Name = "test"
+	client.Downloader = fake
+	var events []SessionEvent
+	client.OnStateChange(func(e SessionEvent) { events = append(events, e) })
+	return client, &events
+}
+
+func TestSessionReauthenticatesOnExpiredLogin(t *testing.T) {
+	ctx := context.Background()
+	fake := &sessionDownloader{expired: 1}
+	client, events := newSessionClient(fake)
+
+	files, err := client.GetTorrentFiles(ctx, "hash")
+	if err != nil || len(files) != 1 {
+		t.Fatalf("GetTorrentFiles() = %v, %v, want the retry to succeed", files, err)
+	}
+	if fake.auths != 2 {
+		t.Errorf("auths = %d, want a login and a re-login", fake.auths)
+	}
+	var states []SessionState
+	for _, e := range *events {
+		states = append(states, e.To)
+	}
+	want := []SessionState{SessionConnected, SessionDisconnected, SessionConnected}
+	if len(states) != len(want) || states[0] != want[0] || states[1] != want[1] || states[2] != want[2] {
+		t.Errorf("state changes = %v, want %v", states, want)
+	}
+}
+
+// swappingDownloader 登录失效时把客户端换成另一个下载器，模拟重新登录期间被重新配置
+type swappingDownloader struct {
+	*sessionDownloader
+	swap func()
+}
+
+func (d *swappingDownloader) GetTorrentFiles(ctx context.Context, hash string) ([]string, error) {
+	files, err := d.sessionDownloader.GetTorrentFiles(ctx, hash)
+	if err != nil {
+		d.swap()
+	}
+	return files, err
+}
+
+func TestSessionRetryUsesCurrentDownloader(t *testing.T) {
+	ctx := context.Background()
+	replacement := &sessionDownloader{}
+	old := &swappingDownloader{sessionDownloader: &sessionDownloader{expired: 2}}
+	client, _ := newSessionClient(old.sessionDownloader)
+	client.Downloader = old
+	old.swap = func() {
+		client.mu.Lock()
+		client.Downloader = replacement
+		client.mu.Unlock()
+	}
+
+	files, err := client.GetTorrentFiles(ctx, "hash")
+	if err != nil || len(files) != 1 {
+		t.Fatalf("GetTorrentFiles() = %v, %v, want the retry to use the new downloader", files, err)
+	}
+	if replacement.auths != 1 {
+		t.Errorf("replacement auths = %d, want the re-login on the new downloader", replacement.auths)
+	}
+}
+
+func TestSessionBacksOffOnNetworkError(t *testing.T) {
+	ctx := context.Background()
+	fake := &sessionDownloader{authErr: &apperrors.NetworkError{Err: errors.New("connection refused")}}
+	client, _ := newSessionClient(fake)
+
+	if _, err := client.GetTorrentFiles(ctx, "hash"); !apperrors.IsNetworkError(err) {
+		t.Fatalf("err = %v, want network error", err)
+	}
+	// 退避期间不请求下载器
+	if _, err := client.GetTorrentFiles(ctx, "hash"); !apperrors.IsNetworkError(err) || fake.auths != 1 {
+		t.Fatalf("err = %v, auths = %d, want a network error without logging in again", err, fake.auths)
+	}
+	status := client.Session()
+	if status.State != SessionBackoff || status.Failures != 1 || time.Until(status.RetryAt) <= 0 {
+		t.Fatalf("Session() = %+v, want backoff", status)
+	}
+
+	// 退避结束后重新登录
+	client.session.mu.Lock()
+	client.session.status.RetryAt = time.Now()
+	client.session.mu.Unlock()
+	fake.authErr = nil
+	if _, err := client.GetTorrentFiles(ctx, "hash"); err != nil || fake.auths != 2 {
+		t.Fatalf("err = %v, auths = %d, want the login retried", err, fake.auths)
+	}
+	if got := client.Session(); got.State != SessionConnected || got.Failures != 0 {
+		t.Errorf("Session() = %+v, want connected", got)
+	}
+}
+
+func TestSessionAuthFailureClearedByInit(t *testing.T) {
+	ctx := context.Background()
+	fake := &sessionDownloader{authErr: &apperrors.DownloadAuthenticationError{Err: errors.New("用户名或密码错误")}}
+	client, _ := newSessionClient(fake)
+
+	for range 2 {
+		if _, err := client.GetTorrentFiles(ctx, "hash"); !apperrors.IsDownloadLoginError(err) {
+			t.Fatalf("err = %v, want login error", err)
+		}
+	}
+	if fake.auths != 1 || client.Session().State != SessionAuthFailed {
+		t.Fatalf("auths = %d, state = %s, want a single failed login", fake.auths, client.Session().State)
+	}
+
+	// 修改配置后重新 Init 清除错误状态
+	client.Init(&model.DownloaderConfig{Type: "mock"})
+	if client.Session().State != SessionDisconnected {
+		t.Fatalf("state after Init = %s, want disconnected", client.Session().State)
+	}
+	if _, err := client.GetTorrentFiles(ctx, "unknown"); apperrors.IsDownloadLoginError(err) {
+		t.Fatalf("err = %v, want the new config to log in", err)
+	}
+	if client.Session().State != SessionConnected {
+		t.Errorf("state = %s, want connected", client.Session().State)
+	}
+}
+
+func TestSessionConcurrentLogin(t *testing.T) {
+	ctx := context.Background()
+	fake := &sessionDownloader{}
+	client, _ := newSessionClient(fake)
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.GetTorrentFiles(ctx, "hash"); err != nil {
+				t.Errorf("GetTorrentFiles() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if client.Session().State != SessionConnected {
+		t.Errorf("state = %s, want connected", client.Session().State)
+	}
+}
+
+func TestSessionBackoff(t *testing.T) {
+	for failures, want := range map[int]time.Duration{1: 5 * time.Second, 2: 10 * time.Second, 4: 40 * time.Second, 20: 5 * time.Minute} {
+		if got := sessionBackoff(failures); got != want {
+			t.Errorf("sessionBackoff(%d) = %v, want %v", failures, got, want)
+		}
+	}
+}
+
+func TestSessionLoginSurvivesCancelledCaller(t *testing.T) {
+	fake := &sessionDownloader{release: make(chan struct{})}
+	client, _ := newSessionClient(fake)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- client.EnsureLogin(ctx) }()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("EnsureLogin() error = %v, want context.Canceled", err)
+	}
+	if state := client.Session().State; state == SessionBackoff {
+		t.Fatalf("state = %s, cancelled caller should not put the session into backoff", state)
+	}
+
+	// 登录在独立的 ctx 上继续，完成后其他调用方直接使用
+	close(fake.release)
+	if err := client.EnsureLogin(context.Background()); err != nil {
+		t.Fatalf("EnsureLogin() error = %v", err)
+	}
+	if state := client.Session().State; state != SessionConnected {
+		t.Errorf("state = %s, want connected", state)
+	}
+	if fake.auths != 1 {
+		t.Errorf("auths = %d, want the first login to be shared", fake.auths)
+	}
+}
@@ -0,0 +1,15 @@
+package download
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+)
+
+// LinkTagPrefix 种子链接标签的前缀
+const LinkTagPrefix = "gb:"
+
+// LinkTag 根据种子链接生成标签，用于在下载器中找回对应的种子记录
+func LinkTag(link string) string {
+	sum := sha1.Sum([]byte(link))
+	return LinkTagPrefix + hex.EncodeToString(sum[:8])
+}
@@ -3,6 +3,8 @@ package download
 import (
 	"bytes"
 	"encoding/hex"
+	"path"
+	"strings"
 
 	"goto-bangumi/internal/model"
 
@@ -20,7 +22,34 @@ func ParseTorrent(torrent []byte) (*model.TorrentInfo, error) {
 	if err != nil {
 		return nil, err
 	}
-	return parse(&magnetV2, torrent)
+	ti, err := parse(&magnetV2, torrent)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := mi.UnmarshalInfo(); err == nil {
+		ti.Files = torrentFiles(&info)
+	}
+	return ti, nil
+}
+
+// torrentFiles 列出种子内的文件，顺序与种子中的文件顺序一致
+// BEP 47 的填充文件不计入，下载器的文件序号也不包含填充文件
+func torrentFiles(info *metainfo.Info) []model.TorrentFile {
+	name := info.BestName()
+	if !info.IsDir() {
+		return []model.TorrentFile{{Path: name, Size: info.TotalLength()}}
+	}
+	var files []model.TorrentFile
+	for _, f := range info.UpvertedFiles() {
+		if strings.Contains(f.Attr, "p") {
+			continue
+		}
+		files = append(files, model.TorrentFile{
+			Path: path.Join(append([]string{name}, f.BestPath()...)...),
+			Size: f.Length,
+		})
+	}
+	return files
 }
 
 func ParseTorrentURL(torrentURL string) (*model.TorrentInfo, error) {
@@ -0,0 +1,168 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"goto-bangumi/internal/model"
+	"goto-bangumi/internal/network"
+)
+
+// torrentCache 本地保存的 .torrent 文件，为 nil 时不缓存，每次都从链接下载
+var torrentCache *TorrentCache
+
+// InitTorrentCache 设置 .torrent 文件的缓存目录，需在添加种子之前调用，dir 为空时关闭缓存
+func InitTorrentCache(dir string) {
+	if dir == "" {
+		torrentCache = nil
+		return
+	}
+	torrentCache = NewTorrentCache(dir)
+}
+
+// TorrentCache 以 info hash 命名保存 .torrent 文件
+// 订阅源的链接可能过期，保存下来的文件用于添加失败后的重试、下载器丢失种子后的重新添加
+type TorrentCache struct {
+	dir string
+}
+
+// NewTorrentCache 创建缓存，目录在第一次保存时创建
+func NewTorrentCache(dir string) *TorrentCache {
+	return &TorrentCache{dir: dir}
+}
+
+// path 返回 hash 对应的文件路径，hash 只允许十六进制字符，避免拼出目录外的路径
+func (c *TorrentCache) path(hash string) (string, error) {
+	hash = strings.ToLower(hash)
+	if hash == "" || strings.Trim(hash, "0123456789abcdef") != "" {
+		return "", fmt.Errorf("[torrent cache] 无效的 info hash: %q", hash)
+	}
+	return filepath.Join(c.dir, hash+".torrent"), nil
+}
+
+// Save 保存种子文件，同一 hash 已存在时不覆盖
+func (c *TorrentCache) Save(hash string, data []byte) error {
+	path, err := c.path(hash)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return err
+	}
+	// 先写临时文件再改名，进程中断时不会留下不完整的种子
+	tmp, err := os.CreateTemp(c.dir, hash+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Load 读取种子文件，不存在时返回 os.ErrNotExist
+func (c *TorrentCache) Load(hash string) ([]byte, error) {
+	path, err := c.path(hash)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+// Has 是否缓存了种子文件
+func (c *TorrentCache) Has(hash string) bool {
+	path, err := c.path(hash)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(path)
+	return err == nil
+}
+
+// Remove 删除种子文件，不存在时不报错
+func (c *TorrentCache) Remove(hash string) error {
+	path, err := c.path(hash)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// TorrentHash 返回种子的 info hash，优先使用 v1，纯 v2 种子使用 v2
+func TorrentHash(info *model.TorrentInfo) string {
+	if info.InfoHashV1 != "" {
+		return strings.ToLower(info.InfoHashV1)
+	}
+	return strings.ToLower(info.InfoHashV2)
+}
+
+// LoadTorrent 解析种子链接或磁力链接
+// infoHash 为之前缓存的种子 hash，缓存中有时直接使用，否则从链接下载并写入缓存
+func LoadTorrent(ctx context.Context, link, infoHash string) (*model.TorrentInfo, error) {
+	if strings.HasPrefix(link, "magnet:") {
+		torrentInfo, err := ParseTorrentURL(link)
+		if err != nil {
+			return nil, fmt.Errorf("[download client] 解析磁力链接失败: %w", err)
+		}
+		return torrentInfo, nil
+	}
+
+	if infoHash != "" && torrentCache != nil {
+		if data, err := torrentCache.Load(infoHash); err == nil {
+			if torrentInfo, err := ParseTorrent(data); err == nil {
+				slog.Debug("[torrent cache] 使用缓存的种子文件", "hash", infoHash)
+				return torrentInfo, nil
+			}
+			slog.Warn("[torrent cache] 缓存的种子文件无法解析，重新下载", "hash", infoHash)
+		}
+	}
+
+	// 下载种子文件
+	respBody, err := network.GetRequestClient().Get(ctx, link)
+	if err != nil {
+		return nil, fmt.Errorf("[download client] 下载种子文件失败: %w", err)
+	}
+	// 解析种子文件
+	torrentInfo, err := ParseTorrent(respBody)
+	if err != nil {
+		return nil, fmt.Errorf("[download client] 解析种子文件失败: %w", err)
+	}
+	if torrentCache != nil {
+		if err := torrentCache.Save(TorrentHash(torrentInfo), respBody); err != nil {
+			slog.Warn("[torrent cache] 保存种子文件失败", "link", link, "error", err)
+		}
+	}
+	return torrentInfo, nil
+}
+
+// HasCachedTorrent 是否缓存了 info hash 对应的种子文件
+func HasCachedTorrent(infoHash string) bool {
+	return infoHash != "" && torrentCache != nil && torrentCache.Has(infoHash)
+}
+
+// RemoveCachedTorrent 删除缓存的种子文件，种子做种结束、不会再添加时调用
+func RemoveCachedTorrent(infoHash string) {
+	if infoHash == "" || torrentCache == nil {
+		return
+	}
+	if err := torrentCache.Remove(infoHash); err != nil {
+		slog.Warn("[torrent cache] 删除种子文件失败", "hash", infoHash, "error", err)
+	}
+}
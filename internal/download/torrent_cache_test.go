@@ -0,0 +1,51 @@
+package download
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"goto-bangumi/internal/network"
+)
+
+func TestLoadTorrentUsesCache(t *testing.T) {
+	ctx := context.Background()
+	data, err := os.ReadFile("./test_data/test2.torrent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	InitTorrentCache(t.TempDir())
+	defer InitTorrentCache("")
+
+	link := "https://mikanani.me/Download/torrent-cache-test.torrent"
+	network.SetTestCache(link, data)
+	info, err := LoadTorrent(ctx, link, "")
+	network.ClearTestCache(link)
+	if err != nil {
+		t.Fatalf("LoadTorrent() error = %v", err)
+	}
+	hash := TorrentHash(info)
+	if hash != "7a34f9ba65b362c424524057882357e191368f2e" || !HasCachedTorrent(hash) {
+		t.Fatalf("hash = %q, cached = %v, want the fetched torrent cached", hash, HasCachedTorrent(hash))
+	}
+
+	// 链接已经失效，使用缓存的种子文件
+	cached, err := LoadTorrent(ctx, link, hash)
+	if err != nil || TorrentHash(cached) != hash || len(cached.Files) != len(info.Files) {
+		t.Fatalf("LoadTorrent() from cache = %+v, %v", cached, err)
+	}
+
+	RemoveCachedTorrent(hash)
+	if HasCachedTorrent(hash) {
+		t.Error("RemoveCachedTorrent did not remove the file")
+	}
+}
+
+func TestTorrentCacheRejectsInvalidHash(t *testing.T) {
+	cache := NewTorrentCache(t.TempDir())
+	for _, hash := range []string{"", "../data", "7a34f9ba/.."} {
+		if err := cache.Save(hash, []byte("d4:infoe")); err == nil {
+			t.Errorf("Save(%q) should fail", hash)
+		}
+	}
+}
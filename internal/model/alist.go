@@ -0,0 +1,39 @@
+package model
+
+// AlistResponse Alist v3 API 的统一响应
+type AlistResponse[T any] struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    T      `json:"data"`
+}
+
+// AlistTask Alist 任务信息
+// 对应 API: /api/task/offline_download/undone、/api/task/offline_download/done
+type AlistTask struct {
+	ID       string  `json:"id"`
+	Name     string  `json:"name"` // 形如 download magnet:?xt=urn:btih:xxx to (/path)
+	State    int     `json:"state"`
+	Status   string  `json:"status"`
+	Progress float64 `json:"progress"` // 下载进度 (0-100)
+	Error    string  `json:"error"`
+}
+
+// Alist 任务状态
+const (
+	AlistTaskPending   = 0
+	AlistTaskRunning   = 1
+	AlistTaskSucceeded = 2
+	AlistTaskCanceling = 3
+	AlistTaskCanceled  = 4
+	AlistTaskErrored   = 5
+	AlistTaskFailing   = 6
+	AlistTaskFailed    = 7
+)
+
+// AlistObject Alist 文件信息
+// 对应 API: /api/fs/list 的 content 字段
+type AlistObject struct {
+	Name  string `json:"name"`
+	IsDir bool   `json:"is_dir"`
+	Size  int64  `json:"size"`
+}
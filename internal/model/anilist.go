@@ -0,0 +1,77 @@
+package model
+
+// AnilistTitle AniList 条目标题
+type AnilistTitle struct {
+	Romaji  string `json:"romaji"`
+	English string `json:"english"`
+	Native  string `json:"native"`
+}
+
+// AnilistDate AniList 日期，未知的部分为 0
+type AnilistDate struct {
+	Year  int `json:"year"`
+	Month int `json:"month"`
+	Day   int `json:"day"`
+}
+
+// AnilistCover AniList 封面
+type AnilistCover struct {
+	ExtraLarge string `json:"extraLarge"`
+	Large      string `json:"large"`
+}
+
+// AnilistRelationNode 关联条目
+type AnilistRelationNode struct {
+	ID     int    `json:"id"`
+	Type   string `json:"type"`
+	Format string `json:"format"`
+}
+
+// AnilistRelationEdge 关联关系，RelationType 为 PREQUEL、SEQUEL、SIDE_STORY 等
+type AnilistRelationEdge struct {
+	RelationType string              `json:"relationType"`
+	Node         AnilistRelationNode `json:"node"`
+}
+
+// AnilistRelations AniList 关联条目
+type AnilistRelations struct {
+	Edges []AnilistRelationEdge `json:"edges"`
+}
+
+// AnilistMedia AniList 动画条目
+type AnilistMedia struct {
+	ID           int              `json:"id"`
+	Title        AnilistTitle     `json:"title"`
+	Synonyms     []string         `json:"synonyms"`
+	Format       string           `json:"format"`
+	SeasonYear   int              `json:"seasonYear"`
+	StartDate    AnilistDate      `json:"startDate"`
+	Episodes     int              `json:"episodes"`
+	AverageScore int              `json:"averageScore"`
+	CoverImage   AnilistCover     `json:"coverImage"`
+	Relations    AnilistRelations `json:"relations"`
+}
+
+// AnilistError GraphQL 返回的错误
+type AnilistError struct {
+	Message string `json:"message"`
+	Status  int    `json:"status"`
+}
+
+// AnilistSearchResult AniList 搜索结果
+type AnilistSearchResult struct {
+	Data struct {
+		Page struct {
+			Media []AnilistMedia `json:"media"`
+		} `json:"Page"`
+	} `json:"data"`
+	Errors []AnilistError `json:"errors"`
+}
+
+// AnilistMediaResult AniList 按 ID 查询的结果
+type AnilistMediaResult struct {
+	Data struct {
+		Media *AnilistMedia `json:"Media"`
+	} `json:"data"`
+	Errors []AnilistError `json:"errors"`
+}
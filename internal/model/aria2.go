@@ -0,0 +1,27 @@
+package model
+
+// Aria2Status aria2 下载任务状态
+// 对应 API: aria2.tellStatus / tellActive / tellWaiting / tellStopped，数值字段 aria2 都以字符串返回
+type Aria2Status struct {
+	Gid             string      `json:"gid"`
+	Status          string      `json:"status"` // active, waiting, paused, error, complete, removed
+	TotalLength     string      `json:"totalLength"`
+	CompletedLength string      `json:"completedLength"`
+	DownloadSpeed   string      `json:"downloadSpeed"`
+	UploadLength    string      `json:"uploadLength"`
+	UploadSpeed     string      `json:"uploadSpeed"`
+	Connections     string      `json:"connections"`
+	InfoHash        string      `json:"infoHash"`
+	Dir             string      `json:"dir"`
+	FollowedBy      []string    `json:"followedBy"` // 磁力链接下载完元数据后生成的真正任务
+	ErrorMessage    string      `json:"errorMessage"`
+	Files           []Aria2File `json:"files"`
+}
+
+// Aria2File aria2 任务中的文件
+type Aria2File struct {
+	Index    string `json:"index"`
+	Path     string `json:"path"` // 绝对路径
+	Length   string `json:"length"`
+	Selected string `json:"selected"` // "true" 表示下载，select-file 未选中的为 "false"
+}
@@ -59,6 +59,56 @@ func NewTmdbItem() *TmdbItem {
 	}
 }
 
+// SeriesItem bgm.tv、AniList 条目的公共字段，这两个来源每一季都是单独的条目，季度按前传的数量推算
+type SeriesItem struct {
+	Year         string  `json:"year" gorm:"default:'';comment:'番剧年份'"`
+	AirDate      string  `json:"air_date" gorm:"default:'';comment:'首播日期'"`
+	EpisodeCount int     `json:"episode_count" gorm:"default:0;comment:'总集数'"`
+	Season       int     `json:"season" gorm:"default:1;comment:'季度，按前传数推算'"`
+	PrequelID    int     `json:"prequel_id" gorm:"default:0;comment:'前传条目 ID'"`
+	SequelID     int     `json:"sequel_id" gorm:"default:0;comment:'续集条目 ID'"`
+	PosterLink   string  `json:"poster_link" gorm:"default:'';comment:'封面链接'"`
+	Score        float64 `json:"score" gorm:"default:0;comment:'评分，十分制'"`
+}
+
+// describe 生成 String 的输出，label 为条目 ID 的名称
+func (s SeriesItem) describe(label string, id int, title, originalTitle string) string {
+	return fmt.Sprintf(
+		`%s: %d,
+ Title: %s,
+ OriginalTitle: %s,
+ AirDate: %s,
+ EpisodeCount: %d,
+ Season: %d,
+ PosterLink: %s`, label, id, title, originalTitle, s.AirDate, s.EpisodeCount, s.Season, s.PosterLink)
+}
+
+// BangumiItem bgm.tv 条目信息
+type BangumiItem struct {
+	ID            int    `json:"bgm_id" gorm:"primaryKey;comment:'bgm.tv 条目 ID'"`
+	Title         string `json:"title" gorm:"default:'';comment:'中文名'"`
+	OriginalTitle string `json:"original_title" gorm:"default:'';comment:'原名'"`
+	SeriesItem
+}
+
+func (b BangumiItem) String() string {
+	return b.describe("BgmID", b.ID, b.Title, b.OriginalTitle)
+}
+
+// AnilistItem AniList 条目信息
+type AnilistItem struct {
+	ID          int      `json:"anilist_id" gorm:"primaryKey;comment:'AniList ID'"`
+	Title       string   `json:"title" gorm:"default:'';comment:'英文名，没有时为罗马音'"`
+	RomajiTitle string   `json:"romaji_title" gorm:"default:'';comment:'罗马音标题'"`
+	NativeTitle string   `json:"native_title" gorm:"default:'';comment:'原名'"`
+	Synonyms    []string `json:"synonyms" gorm:"serializer:json;comment:'别名'"`
+	SeriesItem
+}
+
+func (a AnilistItem) String() string {
+	return a.describe("AnilistID", a.ID, a.Title, a.NativeTitle)
+}
+
 // EpisodeMetadata 用来存储番剧解析器的原始信息
 // 是否要认为一个 EpisodeMetadata 可以对应多个 Bangumi?
 type EpisodeMetadata struct {
@@ -116,14 +166,18 @@ type Bangumi struct {
 	Season        int    `json:"season" gorm:"default:1;comment:'番剧季度'"`
 
 	// 外键关联（一对多关系）
-	MikanID *int `json:"mikan_id" gorm:"index;comment:'关联的Mikan ID'"`
-	TmdbID  *int `json:"tmdb_id" gorm:"index;comment:'关联的TMDB ID'"`
+	MikanID   *int `json:"mikan_id" gorm:"index;comment:'关联的Mikan ID'"`
+	TmdbID    *int `json:"tmdb_id" gorm:"index;comment:'关联的TMDB ID'"`
+	BgmID     *int `json:"bgm_id" gorm:"index;comment:'关联的 bgm.tv ID'"`
+	AnilistID *int `json:"anilist_id" gorm:"index;comment:'关联的 AniList ID'"`
 
 	// GORM 关联对象（用于预加载）
 	// 一个 Bangumi 属于一个 MikanItem 和一个 TmdbItem
 	// 使用指针类型表示"可能没有"，foreignKey 指向 Bangumi 的外键字段，references 指向关联表的主键字段
-	MikanItem *MikanItem `gorm:"foreignKey:MikanID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
-	TmdbItem  *TmdbItem  `gorm:"foreignKey:TmdbID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
+	MikanItem   *MikanItem   `gorm:"foreignKey:MikanID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
+	TmdbItem    *TmdbItem    `gorm:"foreignKey:TmdbID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
+	BgmItem     *BangumiItem `gorm:"foreignKey:BgmID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
+	AnilistItem *AnilistItem `gorm:"foreignKey:AnilistID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
 	// 属于一个 RSSItem
 	RSSLink string `json:"rss_link" gorm:"default:'';comment:'关联的RSS订阅链接'"`
 
@@ -136,7 +190,30 @@ type Bangumi struct {
 	ExcludeFilter string `json:"exclude_filter" gorm:"default:'';comment:'番剧排除过滤器'"`
 	Parse         string `json:"parser" gorm:"default:'tmdb';comment:'番剧解析器'"`
 	PosterLink    string `json:"poster_link" gorm:"default:'';comment:'番剧海报链接'"`
-	DeletedAt     gorm.DeletedAt `json:"-" gorm:"index"`
+	Downloader    string `json:"downloader" gorm:"default:'';comment:'使用的下载器名称，为空时使用默认下载器'"`
+	// 做种策略，为空时使用全局配置
+	SeedRatioLimit  *float64       `json:"seed_ratio_limit" gorm:"comment:'分享率限制'"`
+	SeedingTime     *int           `json:"seeding_time" gorm:"comment:'做种时长限制，单位分钟'"`
+	SeedDeleteFiles *bool          `json:"seed_delete_files" gorm:"comment:'移除种子时是否删除数据'"`
+	DeletedAt       gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// SeedingPolicy 合并番剧自己的做种策略和全局配置
+func (b *Bangumi) SeedingPolicy(global SeedingConfig) SeedingConfig {
+	policy := global
+	if b == nil {
+		return policy
+	}
+	if b.SeedRatioLimit != nil {
+		policy.RatioLimit = *b.SeedRatioLimit
+	}
+	if b.SeedingTime != nil {
+		policy.SeedingTime = *b.SeedingTime
+	}
+	if b.SeedDeleteFiles != nil {
+		policy.DeleteFiles = *b.SeedDeleteFiles
+	}
+	return policy
 }
 
 // NewBangumi 创建一个默认的 Bangumi 实例
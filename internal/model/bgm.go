@@ -0,0 +1,57 @@
+package model
+
+// BgmImages bgm.tv 条目封面
+type BgmImages struct {
+	Large  string `json:"large"`
+	Common string `json:"common"`
+	Medium string `json:"medium"`
+	Small  string `json:"small"`
+	Grid   string `json:"grid"`
+}
+
+// BgmSearchSubject bgm.tv 搜索结果中的条目
+type BgmSearchSubject struct {
+	ID      int       `json:"id"`
+	URL     string    `json:"url"`
+	Type    int       `json:"type"`
+	Name    string    `json:"name"`
+	NameCN  string    `json:"name_cn"`
+	AirDate string    `json:"air_date"`
+	Images  BgmImages `json:"images"`
+}
+
+// BgmSearchResult bgm.tv 条目搜索结果，没有结果时 list 为空
+type BgmSearchResult struct {
+	Results int                `json:"results"`
+	List    []BgmSearchSubject `json:"list"`
+}
+
+// BgmRating bgm.tv 条目评分
+type BgmRating struct {
+	Score float64 `json:"score"`
+	Total int     `json:"total"`
+}
+
+// BgmSubject bgm.tv 条目详情
+type BgmSubject struct {
+	ID            int       `json:"id"`
+	Type          int       `json:"type"`
+	Name          string    `json:"name"`
+	NameCN        string    `json:"name_cn"`
+	Date          string    `json:"date"`
+	Platform      string    `json:"platform"`
+	Images        BgmImages `json:"images"`
+	Eps           int       `json:"eps"`
+	TotalEpisodes int       `json:"total_episodes"`
+	Rating        BgmRating `json:"rating"`
+}
+
+// BgmRelatedSubject bgm.tv 关联条目，Relation 为 前传、续集、番外篇 等
+type BgmRelatedSubject struct {
+	ID       int       `json:"id"`
+	Type     int       `json:"type"`
+	Name     string    `json:"name"`
+	NameCN   string    `json:"name_cn"`
+	Relation string    `json:"relation"`
+	Images   BgmImages `json:"images"`
+}
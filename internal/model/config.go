@@ -8,6 +8,13 @@ type Config struct {
 	Rename       BangumiRenameConfig `toml:"rename" env-prefix:"RENAME_"`
 	Notification NotificationConfig  `toml:"notification" env-prefix:"NOTIFICATION_"`
 	Proxy        ProxyConfig         `toml:"proxy" env-prefix:"PROXY_"`
+	Task         TaskConfig          `toml:"task" env-prefix:"TASK_"`
+	Selector     FileSelectConfig    `toml:"selector" env-prefix:"SELECTOR_"`
+	Seeding      SeedingConfig       `toml:"seeding" env-prefix:"SEEDING_"`
+	Reconcile    ReconcileConfig     `toml:"reconcile" env-prefix:"RECONCILE_"`
+	Failover     FailoverConfig      `toml:"failover" env-prefix:"FAILOVER_"`
+	// Downloaders 额外的命名下载器，[downloader] 为默认下载器
+	Downloaders []DownloaderConfig `toml:"downloaders"`
 }
 
 type ProgramConfig struct {
@@ -17,7 +24,17 @@ type ProgramConfig struct {
 	DebugEnable bool   `toml:"debug_enable" env:"DEBUG_ENABLE" env-default:"false"`
 }
 
+// TaskConfig 任务执行器的并发配置，可通过 API 在运行时调整
+type TaskConfig struct {
+	MaxConcurrency int `toml:"max_concurrency" env:"MAX_CONCURRENCY" env-default:"8"`
+	MaxDownload    int `toml:"max_download" env:"MAX_DOWNLOAD" env-default:"4"`
+	SlotTimeout    int `toml:"slot_timeout" env:"SLOT_TIMEOUT" env-default:"10"` // 下载槽位超时，单位分钟
+	PollInterval   int `toml:"poll_interval" env:"POLL_INTERVAL" env-default:"10"` // 批量查询下载状态的间隔，单位秒
+}
+
 type DownloaderConfig struct {
+	// 下载器名称，用于番剧和 RSS 的下载器路由，默认下载器为空时使用 "default"
+	Name     string `toml:"name" env:"NAME"`
 	Type     string `toml:"type" env:"TYPE" env-default:"qbittorrent"`
 	SavePath string `toml:"path" env:"PATH" env-default:"/downloads/Bangumi"`
 	MediaPath string `toml:"media_path" env:"MEDIA_PATH" env-default:"/downloads/Bangumi"`
@@ -26,6 +43,80 @@ type DownloaderConfig struct {
 	Username string `toml:"username" env:"USERNAME" env-default:"admin"`
 	Password string `toml:"password" env:"PASSWORD" env-default:"adminadmin"`
 	Token    string `toml:"token" env:"TOKEN"`
+	// qBittorrent 中种子的分类，登录时不存在会自动创建
+	Category string `toml:"category" env:"CATEGORY" env-default:"GotoBangumi"`
+	// Alist 离线下载使用的工具，如 qBittorrent、aria2、115 Cloud
+	OfflineTool string `toml:"offline_tool" env:"OFFLINE_TOOL" env-default:"qBittorrent"`
+	// 内置下载器的监听端口，0 表示随机端口
+	ListenPort int `toml:"listen_port" env:"LISTEN_PORT" env-default:"0"`
+	// 内置下载器的做种分享率，达到后停止做种；0 表示下载完成即停止，负数表示一直做种
+	SeedRatio float64 `toml:"seed_ratio" env:"SEED_RATIO" env-default:"1"`
+	// 模拟下载器（type = "mock"）的模拟模式
+	Mock MockConfig `toml:"mock" env-prefix:"MOCK_"`
+}
+
+// DefaultDownloaderConfig 下载器的默认配置，与 DownloaderConfig 的 env-default 一致
+// cleanenv 不会对 [[downloaders]] 数组中的元素应用 env-default，读取配置时用它补全没有写的字段
+func DefaultDownloaderConfig() DownloaderConfig {
+	return DownloaderConfig{
+		Type:        "qbittorrent",
+		SavePath:    "/downloads/Bangumi",
+		MediaPath:   "/downloads/Bangumi",
+		Host:        "127.0.0.1:8080",
+		Username:    "admin",
+		Password:    "adminadmin",
+		Category:    "GotoBangumi",
+		OfflineTool: "qBittorrent",
+		SeedRatio:   1,
+		Mock: MockConfig{
+			Duration:  60,
+			TimeScale: 1,
+		},
+	}
+}
+
+// MockConfig 模拟下载器的模拟模式，种子按虚拟时间下载，可能失败、停滞或丢失，用于演示和端到端测试
+type MockConfig struct {
+	Simulate    bool    `toml:"simulate" env:"SIMULATE" env-default:"false"`
+	Duration    int     `toml:"duration" env:"DURATION" env-default:"60"`        // 下载一个种子需要的虚拟时间，单位秒
+	TimeScale   float64 `toml:"time_scale" env:"TIME_SCALE" env-default:"1"`     // 虚拟时间的倍速，0 表示虚拟时间只在调用 Advance 时前进
+	FailRate    float64 `toml:"fail_rate" env:"FAIL_RATE" env-default:"0"`       // 下载中途失败的概率
+	StallRate   float64 `toml:"stall_rate" env:"STALL_RATE" env-default:"0"`     // 下载中途停滞的概率
+	MissingRate float64 `toml:"missing_rate" env:"MISSING_RATE" env-default:"0"` // 下载中途从下载器中消失的概率
+	Seed        int64   `toml:"seed" env:"SEED" env-default:"0"`                 // 随机数种子，0 表示每次启动都不同
+}
+
+// FileSelectConfig 添加种子前的文件筛选规则，不需要的文件设为不下载
+type FileSelectConfig struct {
+	Enable     bool     `toml:"enable" env:"ENABLE" env-default:"true"`
+	Extensions []string `toml:"extensions" env:"EXTENSIONS" env-default:".mkv,.mp4,.avi,.ts,.m2ts,.webm,.flv,.rmvb,.wmv,.mov,.ass,.ssa,.srt,.sup,.vtt"`
+	// 路径匹配任一正则的文件不下载，默认跳过 SP、NCOP、NCED、菜单、PV、CM、扫图和字体
+	Exclude    []string `toml:"exclude" env:"EXCLUDE" env-default:"(?i)(^|[\\[\\]\\s/._-])(SPs?|NCOP\\d*|NCED\\d*|Menus?\\d*|PV\\d*|CM\\d*|Scans?|Fonts?|Previews?|Bonus|Extras?)($|[\\[\\]\\s/._-])"`
+	SkipPoint5 bool     `toml:"skip_point5" env:"SKIP_POINT5" env-default:"true"`
+	MinSize    int64    `toml:"min_size" env:"MIN_SIZE" env-default:"0"` // 单位 MB，0 表示不限制
+	MaxSize    int64    `toml:"max_size" env:"MAX_SIZE" env-default:"0"` // 单位 MB，0 表示不限制
+}
+
+// SeedingConfig 做种策略，已完成且已重命名的种子满足任一限制后从下载器中移除
+type SeedingConfig struct {
+	Enable      bool    `toml:"enable" env:"ENABLE" env-default:"false"`
+	RatioLimit  float64 `toml:"ratio_limit" env:"RATIO_LIMIT" env-default:"2"`       // 0 表示不按分享率移除
+	SeedingTime int     `toml:"seeding_time" env:"SEEDING_TIME" env-default:"10080"` // 单位分钟，0 表示不按做种时长移除
+	// 移除时是否删除数据，已硬链接或已移动到媒体库的文件始终保留
+	DeleteFiles bool `toml:"delete_files" env:"DELETE_FILES" env-default:"false"`
+	Interval    int  `toml:"interval" env:"INTERVAL" env-default:"3600"` // 检查间隔，单位秒
+}
+
+// ReconcileConfig 数据库与下载器的定期对账
+type ReconcileConfig struct {
+	Enable   bool `toml:"enable" env:"ENABLE" env-default:"true"`
+	Interval int  `toml:"interval" env:"INTERVAL" env-default:"1800"` // 对账间隔，单位秒
+}
+
+// FailoverConfig 下载停滞时换用同一集的其他发布
+type FailoverConfig struct {
+	Enable    bool `toml:"enable" env:"ENABLE" env-default:"true"`
+	StallTime int  `toml:"stall_time" env:"STALL_TIME" env-default:"30"` // 下载进度多久没有增长算作停滞，单位分钟
 }
 
 type RssParserConfig struct {
@@ -35,6 +126,9 @@ type RssParserConfig struct {
 	Language       string   `toml:"language" env:"LANGUAGE" env-default:"zh"`
 	MikanCustomURL string   `toml:"mikan_custom_url" env:"MIKAN_CUSTOM_URL" env-default:"mikanani.me"`
 	TmdbAPIKey     string   `toml:"tmdb_api_key" env:"TMDB_API_KEY"`
+	// 番剧元数据来源，按顺序尝试，可选 tmdb、bangumi（bgm.tv）、anilist
+	// 国产番剧 bgm.tv 匹配得更准，只有英文名的种子 anilist 匹配得更准
+	Providers []string `toml:"providers" env:"PROVIDERS" env-default:"tmdb"`
 }
 
 type BangumiRenameConfig struct {
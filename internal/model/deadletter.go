@@ -0,0 +1,38 @@
+package model
+
+import "time"
+
+// DeadLetter 失败任务的死信记录
+// 任务失败时写入，保留失败的阶段和恢复任务需要的数据，重投时从失败的阶段继续执行
+type DeadLetter struct {
+	ID          uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	TorrentLink string    `gorm:"uniqueIndex;column:torrent_link" json:"torrent_link"`
+	BangumiID   uint      `gorm:"index;column:bangumi_id" json:"bangumi_id"`
+	Phase       TaskPhase `gorm:"default:0;column:phase" json:"phase"` // 失败的阶段
+	Error       string    `gorm:"default:'';column:error" json:"error"`
+	ErrorClass  string    `gorm:"index;default:'';column:error_class" json:"error_class"` // apperrors.Class*
+	Guids       []string  `gorm:"serializer:json;column:guids" json:"guids"`
+	Episodes    []int     `gorm:"serializer:json;column:episodes" json:"episodes"`
+	Redrive     bool      `gorm:"index;default:false;column:redrive" json:"redrive"` // 等待重投
+	FailedCount int       `gorm:"default:1;column:failed_count" json:"failed_count"`
+	CreatedAt   time.Time `gorm:"autoCreateTime;column:created_at" json:"created_at"`
+	UpdatedAt   time.Time `gorm:"autoUpdateTime;column:updated_at" json:"updated_at"`
+}
+
+// DeadLetterFilter 死信筛选条件，零值字段不参与筛选
+type DeadLetterFilter struct {
+	IDs        []uint `json:"ids"`
+	BangumiID  uint   `json:"bangumi_id"`
+	ErrorClass string `json:"error_class"`
+}
+
+// NewPhaseTask 创建从指定阶段开始的任务，用于死信重投
+func NewPhaseTask(torrent *Torrent, bangumi *Bangumi, phase TaskPhase) *Task {
+	return &Task{
+		CurrentPhase: phase,
+		State:        TaskStateCreated,
+		Torrent:      torrent,
+		Bangumi:      bangumi,
+		Downloader:   taskDownloader(torrent, bangumi),
+	}
+}
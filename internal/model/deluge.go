@@ -0,0 +1,34 @@
+package model
+
+// DelugeTorrentStatus Deluge 种子状态
+// 对应 API: core.get_torrent_status / core.get_torrents_status
+type DelugeTorrentStatus struct {
+	Hash          string       `json:"hash"`
+	Name          string       `json:"name"`
+	State         string       `json:"state"`
+	SavePath      string       `json:"save_path"`
+	Progress      float64      `json:"progress"` // 下载进度 (0-100)
+	Eta           int64        `json:"eta"`      // 预计剩余时间（秒）
+	IsFinished    bool         `json:"is_finished"`
+	CompletedTime int64        `json:"completed_time"` // 完成时间（Unix时间戳，0表示未完成）
+	Label         string       `json:"label"`
+	Files         []DelugeFile `json:"files"`
+	FilePriority  []int        `json:"file_priorities"` // 按文件下标，0 表示不下载
+	Ratio         float64      `json:"ratio"`           // 分享率，-1 表示不可用
+	SeedingTime   int64        `json:"seeding_time"`    // 累计做种时长（秒）
+	Message       string       `json:"message"`         // 状态说明，出错时为错误信息
+	TotalWanted   int64        `json:"total_wanted"`    // 需要下载的大小（字节）
+	TotalDone     int64        `json:"total_done"`      // 已下载大小（字节）
+	TotalUploaded int64        `json:"total_uploaded"`  // 已上传大小（字节）
+	DownloadRate  int64        `json:"download_payload_rate"`
+	UploadRate    int64        `json:"upload_payload_rate"`
+	NumSeeds      int          `json:"num_seeds"`
+	NumPeers      int          `json:"num_peers"`
+}
+
+// DelugeFile Deluge 种子文件
+type DelugeFile struct {
+	Index int    `json:"index"`
+	Path  string `json:"path"` // 文件名（包含路径）
+	Size  int64  `json:"size"`
+}
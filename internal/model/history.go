@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// 任务历史中阶段结束的状态
+const (
+	HistoryStateDone      = "done"      // 阶段正常结束，进入下一阶段
+	HistoryStateFailed    = "failed"    // 阶段失败，任务结束
+	HistoryStateCancelled = "cancelled" // 任务被取消
+	HistoryStateReplaced  = "replaced"  // 任务被新任务替换，比如下载停滞后换用其他发布
+)
+
+// TaskHistory 任务阶段变更记录
+// 每个阶段结束时写入一行，用以在任务结束后追溯种子的完整生命周期
+type TaskHistory struct {
+	ID          uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	TorrentLink string    `gorm:"index;column:torrent_link" json:"torrent_link"`
+	BangumiID   uint      `gorm:"index;column:bangumi_id" json:"bangumi_id"`
+	Phase       string    `gorm:"default:'';column:phase" json:"phase"`
+	State       string    `gorm:"default:'';column:state" json:"state"`
+	CreatedAt   time.Time `gorm:"autoCreateTime;index;column:created_at" json:"created_at"`
+	Duration    int64     `gorm:"default:0;column:duration" json:"duration_ms"` // 阶段耗时（毫秒）
+	Error       string    `gorm:"default:'';column:error" json:"error"`
+	RetryCount  int       `gorm:"default:0;column:retry_count" json:"retry_count"`
+}
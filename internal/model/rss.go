@@ -10,4 +10,6 @@ type RSSItem struct {
 	IncludeFilter string `json:"include_filter" gorm:"default:'';comment:'番剧包含过滤器'"`
 	ExcludeFilter string `json:"exclude_filter" gorm:"default:'';comment:'番剧排除过滤器'"`
 	Enabled   bool    `gorm:"default:true;column:enabled" json:"enabled"`
+	// 新番剧默认使用的下载器名称，为空时使用默认下载器
+	Downloader string `gorm:"default:'';column:downloader" json:"downloader"`
 }
@@ -2,6 +2,7 @@ package model
 
 import (
 	"context"
+	"slices"
 	"sync"
 	"time"
 )
@@ -9,16 +10,40 @@ import (
 // TaskPhase 任务阶段
 type TaskPhase int
 
+// 阶段的数值会保存到死信表，已有的值不能改，新阶段只能往后加
+// 执行顺序由 phasePipeline 决定，不能用数值大小比较先后
 const (
-	PhaseAdding      TaskPhase = iota // 添加到下载器
-	PhaseChecking                     // 检查下载是否成功添加
-	PhaseDownloading                  // 下载中，等待完成
-	PhaseRenaming                     // 重命名文件
-	PhaseCompleted                    // 完成
-	PhaseFailed                       // 失败
-	PhaseEnd                          // 任务完成标志
+	PhaseAdding      TaskPhase = 0 // 添加到下载器
+	PhaseChecking    TaskPhase = 1 // 检查下载是否成功添加
+	PhaseDownloading TaskPhase = 2 // 下载中，等待完成
+	PhaseRenaming    TaskPhase = 3 // 重命名文件
+	PhaseNotifying   TaskPhase = 4 // 发送更新通知
+	PhaseCompleted   TaskPhase = 5 // 完成
+	PhaseFailed      TaskPhase = 6 // 失败
+	PhaseEnd         TaskPhase = 7 // 任务完成标志
+	PhaseMoving      TaskPhase = 8 // 从 SavePath 转移到 MediaPath
 )
 
+// phasePipeline 阶段的执行顺序
+var phasePipeline = []TaskPhase{
+	PhaseAdding,
+	PhaseChecking,
+	PhaseDownloading,
+	PhaseRenaming,
+	PhaseMoving,
+	PhaseNotifying,
+	PhaseCompleted,
+}
+
+// Next 返回下一个阶段，Completed、Failed 之后都是 End
+func (p TaskPhase) Next() TaskPhase {
+	i := slices.Index(phasePipeline, p)
+	if i < 0 || i == len(phasePipeline)-1 {
+		return PhaseEnd
+	}
+	return phasePipeline[i+1]
+}
+
 type TaskState int
 
 const (
@@ -40,6 +65,10 @@ func (p TaskPhase) String() string {
 		return "downloading"
 	case PhaseRenaming:
 		return "renaming"
+	case PhaseMoving:
+		return "moving"
+	case PhaseNotifying:
+		return "notifying"
 	case PhaseCompleted:
 		return "completed"
 	case PhaseFailed:
@@ -51,6 +80,25 @@ func (p TaskPhase) String() string {
 	}
 }
 
+func (s TaskState) String() string {
+	switch s {
+	case TaskStateCreated:
+		return "created"
+	case TaskStateReady:
+		return "ready"
+	case TaskStateWaiting:
+		return "waiting"
+	case TaskStateQueued:
+		return "queued"
+	case TaskStateRunning:
+		return "running"
+	case TaskStateCompleted:
+		return "completed"
+	default:
+		return "unknown"
+	}
+}
+
 // IsTerminal 是否为终态
 func (p TaskPhase) IsTerminal() bool {
 	return p == PhaseEnd
@@ -68,17 +116,53 @@ type Task struct {
 	CancelFunc func()          // 取消当前阶段的上下文（如果有）
 
 	// 业务数据
-	Guids     []string  // 可能的 hash 列表
-	StartTime time.Time // 开始下载时间（用于超时判断）
-	NextPoll  time.Time // Waiting 状态的预计唤醒时间
-	EndTime   time.Time // 结束时间（成功或失败）
-	ErrorMsg  string
+	Guids      []string  // 可能的 hash 列表
+	StartTime  time.Time // 开始下载时间（用于超时判断）
+	PhaseStart time.Time // 当前阶段第一次执行的时间，用于记录阶段耗时
+	NextPoll   time.Time // Waiting 状态的预计唤醒时间
+	EndTime    time.Time // 结束时间（成功或失败）
+	ErrorMsg   string
+	Message    string // 当前阶段等待的原因，比如离线配额不足，进入下一阶段时清空
+	Downloader string // 种子所在的下载器名称，为空表示默认下载器
+
+	RenamedEpisodes []int       // 本次重命名的集数，由 Renaming 阶段写入，Notifying 阶段读取
+	RenamePlan      *RenamePlan // 添加时根据种子内容生成的重命名计划，磁力链接没有
+
+	Progress         float64    // 最近一次查询到的下载进度
+	ProgressAt       time.Time  // 下载进度最近一次增长的时间，用于判断下载停滞
+	FailoverSearched time.Time  // 最近一次查找替代发布的时间
+	Failovers        []Failover // 下载停滞后换用其他发布的记录，换出的新任务会继承
 
 	// 关联对象（内存引用）
 	Torrent *Torrent
 	Bangumi *Bangumi
 }
 
+// Failover 一次换用其他发布的记录
+type Failover struct {
+	Time   time.Time `json:"time"`
+	From   string    `json:"from"` // 停滞的种子名称
+	To     string    `json:"to"`   // 换用的种子名称
+	Reason string    `json:"reason"`
+}
+
+// RenamePlan 添加前根据种子内的文件预先计算的重命名结果，用来在下载之前发现问题
+type RenamePlan struct {
+	Collection bool          `json:"collection"` // 种子包含多集
+	Episodes   []int         `json:"episodes"`   // 重命名后的集数，已加上番剧的偏移
+	Files      []PlannedFile `json:"files"`
+	Problems   []string      `json:"problems,omitempty"` // 无法解析的集数、重名的文件、已经下载过的集数等
+}
+
+// PlannedFile 种子内一个文件的重命名计划
+type PlannedFile struct {
+	Path    string `json:"path"`
+	Target  string `json:"target,omitempty"`  // 重命名后的路径，跳过或无法解析时为空
+	Episode int    `json:"episode"`           // 无法解析时为 -1
+	Skipped bool   `json:"skipped,omitempty"` // 被文件筛选规则排除或是 0.5 集
+	Problem string `json:"problem,omitempty"`
+}
+
 // NewAddTask 创建下载任务（从 PhaseAdding 开始）
 func NewAddTask(torrent *Torrent, bangumi *Bangumi) *Task {
 	return &Task{
@@ -86,6 +170,7 @@ func NewAddTask(torrent *Torrent, bangumi *Bangumi) *Task {
 		State:        TaskStateCreated,
 		Torrent:      torrent,
 		Bangumi:      bangumi,
+		Downloader:   taskDownloader(torrent, bangumi),
 	}
 }
 
@@ -95,5 +180,17 @@ func NewRenameTask(torrent *Torrent, bangumi *Bangumi) *Task {
 		CurrentPhase: PhaseRenaming,
 		Torrent:      torrent,
 		Bangumi:      bangumi,
+		Downloader:   taskDownloader(torrent, bangumi),
+	}
+}
+
+// taskDownloader 种子已经记录了下载器时沿用，否则按番剧的路由规则选择
+func taskDownloader(torrent *Torrent, bangumi *Bangumi) string {
+	if torrent != nil && torrent.Downloader != "" {
+		return torrent.Downloader
+	}
+	if bangumi != nil {
+		return bangumi.Downloader
 	}
+	return ""
 }
@@ -2,6 +2,7 @@ package model
 
 import (
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -13,6 +14,7 @@ const (
 	DownloadSending DownloadStatus = 1 // 已发送到下载器
 	DownloadDone    DownloadStatus = 2 // 下载完成
 	DownloadError   DownloadStatus = 4 // 异常/手动停止下载
+	DownloadRemoved DownloadStatus = 8 // 做种结束，已从下载器中移除
 )
 
 // Torrent 种子信息模型
@@ -27,16 +29,82 @@ type Torrent struct {
 	// torrent 属于一个 bangumi
 	BangumiID uint   `gorm:"index;column:bangumi_id" json:"bangumi_id"`
 	Homepage  string `gorm:"column:homepage" json:"homepage"`
+	// 种子所在的下载器名称，添加到下载器时写入
+	Downloader string `gorm:"default:'';column:downloader" json:"downloader"`
+	// 种子文件的 info hash，种子文件缓存在本地，链接过期后仍能重新添加
+	InfoHash string `gorm:"default:'';column:info_hash" json:"info_hash,omitempty"`
 
 	// GORM 关联对象（用于预加载）
 	Bangumi *Bangumi `gorm:"foreignKey:BangumiID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
 }
 
-// TorrentDownloadInfo 种子下载信息
-type TorrentDownloadInfo struct {
-	ETA       int    `json:"eta"`
-	SavePath  string `json:"save_path"`
-	Completed int    `json:"completed"`
+// TorrentStatus 下载器中种子的状态，各下载器统一成相同的字段和状态
+type TorrentStatus struct {
+	Hash          string        `json:"hash"`
+	Name          string        `json:"name"`
+	State         DownloadState `json:"state"`
+	Message       string        `json:"message,omitempty"` // 下载器给出的状态说明，比如失败原因
+	Progress      float64       `json:"progress"`          // 下载进度 0-1
+	Size          int64         `json:"size"`              // 需要下载的大小（字节）
+	Downloaded    int64         `json:"downloaded"`        // 已下载大小（字节）
+	Uploaded      int64         `json:"uploaded"`          // 已上传大小（字节）
+	DownloadSpeed int64         `json:"download_speed"`    // 下载速度（字节/秒）
+	UploadSpeed   int64         `json:"upload_speed"`      // 上传速度（字节/秒）
+	ETA           int           `json:"eta"`               // 预计剩余时间（秒），-1 表示未知
+	Ratio         float64       `json:"ratio"`             // 分享率，-1 表示下载器不提供
+	SeedingTime   int64         `json:"seeding_time"`      // 做种时长（秒），-1 表示下载器不提供
+	Completed     int           `json:"completed"`         // 完成时间（Unix 时间戳），0 表示未完成
+	SavePath      string        `json:"save_path"`
+	Category      string        `json:"category,omitempty"`
+	Tags          []string      `json:"tags,omitempty"` // nil 表示下载器不支持标签
+}
+
+// DownloadState 归一化后的种子状态
+type DownloadState string
+
+const (
+	DownloadStateUnknown      DownloadState = "unknown"
+	DownloadStateMetadata     DownloadState = "metadata"      // 正在获取元数据
+	DownloadStateWaiting      DownloadState = "waiting"       // 排队等待中
+	DownloadStateChecking     DownloadState = "checking"      // 校验文件中
+	DownloadStateDownloading  DownloadState = "downloading"   // 下载中
+	DownloadStateStalled      DownloadState = "stalled"       // 没有可用的连接，下载停滞
+	DownloadStatePaused       DownloadState = "paused"        // 未完成时被暂停
+	DownloadStateSeeding      DownloadState = "seeding"       // 已完成，做种中
+	DownloadStateCompleted    DownloadState = "completed"     // 已完成，已停止做种
+	DownloadStateMissingFiles DownloadState = "missing_files" // 已下载的文件丢失
+	DownloadStateFailed       DownloadState = "failed"        // 下载失败
+)
+
+// Done 是否已下载完成
+func (s *TorrentStatus) Done() bool {
+	return s != nil && s.Completed > 0
+}
+
+// Failed 是否处于无法自行恢复的状态，需要重新开始或放弃
+func (s *TorrentStatus) Failed() bool {
+	return s != nil && (s.State == DownloadStateFailed || s.State == DownloadStateMissingFiles)
+}
+
+// HasTag 是否带有指定前缀的标签
+func (s *TorrentStatus) HasTag(prefix string) bool {
+	for _, tag := range s.Tags {
+		if strings.HasPrefix(tag, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// SplitTags 把逗号分隔的标签拆成列表，去掉空白和空标签，没有标签时返回空列表而不是 nil
+func SplitTags(tags string) []string {
+	result := []string{}
+	for _, tag := range strings.Split(tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			result = append(result, tag)
+		}
+	}
+	return result
 }
 
 // TorrentUpdate 种子更新信息
@@ -65,6 +133,26 @@ type TorrentInfo struct {
 	InfoHashV2 string
 	MagnetURI  string
 	File       []byte
+	Tags       []string      // 添加时附加的标签，不支持标签的下载器忽略
+	Files      []TorrentFile // 种子内的文件，磁力链接没有
+	SkipFiles  []int         // 不下载的文件在 Files 中的下标，由 DownloadClient.Add 按筛选规则写入
+}
+
+// TorrentFile 种子内的文件
+type TorrentFile struct {
+	Path string // 相对保存目录的路径，多文件种子包含种子名目录
+	Size int64
+}
+
+// SkipPaths 返回不下载的文件路径
+func (t TorrentInfo) SkipPaths() map[string]bool {
+	paths := make(map[string]bool, len(t.SkipFiles))
+	for _, i := range t.SkipFiles {
+		if i >= 0 && i < len(t.Files) {
+			paths[t.Files[i].Path] = true
+		}
+	}
+	return paths
 }
 
 func (t TorrentInfo) String() string {
@@ -0,0 +1,49 @@
+package model
+
+// TransmissionRequest Transmission RPC 请求
+type TransmissionRequest struct {
+	Method    string `json:"method"`
+	Arguments any    `json:"arguments,omitempty"`
+}
+
+// TransmissionTorrent Transmission 种子信息
+// 对应 API: torrent-get 的 torrents 字段
+type TransmissionTorrent struct {
+	ID                      int                       `json:"id"`
+	HashString              string                    `json:"hashString"`
+	Name                    string                    `json:"name"`
+	DownloadDir             string                    `json:"downloadDir"`
+	Eta                     int64                     `json:"eta"`           // 预计剩余时间（秒，-1 未知，-2 无限）
+	PercentDone             float64                   `json:"percentDone"`   // 下载进度 (0-1)
+	DoneDate                int64                     `json:"doneDate"`      // 完成时间（Unix时间戳，0表示未完成）
+	LeftUntilDone           int64                     `json:"leftUntilDone"` // 剩余大小（字节）
+	Labels                  []string                  `json:"labels"`
+	UploadRatio             float64                   `json:"uploadRatio"`    // 分享率，-1 表示不可用
+	SecondsSeeding          int64                     `json:"secondsSeeding"` // 累计做种时长（秒）
+	Status                  int                       `json:"status"`         // 0 停止，1 等待校验，2 校验中，3 等待下载，4 下载中，5 等待做种，6 做种中
+	Error                   int                       `json:"error"`          // 0 正常，1 tracker 警告，2 tracker 错误，3 本地错误
+	ErrorString             string                    `json:"errorString"`
+	SizeWhenDone            int64                     `json:"sizeWhenDone"`   // 勾选文件的总大小（字节）
+	DownloadedEver          int64                     `json:"downloadedEver"` // 累计下载（字节）
+	UploadedEver            int64                     `json:"uploadedEver"`   // 累计上传（字节）
+	RateDownload            int64                     `json:"rateDownload"`   // 下载速度（字节/秒）
+	RateUpload              int64                     `json:"rateUpload"`     // 上传速度（字节/秒）
+	PeersSendingToUs        int                       `json:"peersSendingToUs"`
+	MetadataPercentComplete float64                   `json:"metadataPercentComplete"` // 磁力链接的元数据进度 (0-1)
+	Files                   []TransmissionTorrentFile `json:"files"`
+	FileStats               []TransmissionFileStat    `json:"fileStats"` // 与 Files 一一对应
+}
+
+// TransmissionTorrentFile Transmission 种子文件信息
+type TransmissionTorrentFile struct {
+	Name           string `json:"name"` // 文件名（包含路径）
+	Length         int64  `json:"length"`
+	BytesCompleted int64  `json:"bytesCompleted"`
+}
+
+// TransmissionFileStat Transmission 种子文件状态
+type TransmissionFileStat struct {
+	BytesCompleted int64 `json:"bytesCompleted"`
+	Wanted         bool  `json:"wanted"`
+	Priority       int   `json:"priority"`
+}
@@ -181,6 +181,35 @@ func (r *RequestClient) Post(ctx context.Context, url string, contentType string
 	return resp.Body(), nil
 }
 
+// PostJSONTo 以 JSON 发送 payload，并把 JSON 响应解析到 v，用于 GraphQL 这类只支持 POST 的查询接口
+func (r *RequestClient) PostJSONTo(ctx context.Context, url string, payload any, v any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode JSON: %w", err)
+	}
+	resp, err := r.client.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetHeader("Accept", "application/json").
+		SetBody(body).
+		Post(url)
+	if err != nil {
+		return &apperrors.NetworkError{Err: fmt.Errorf("POST request failed: %w", err), StatusCode: 0}
+	}
+
+	if resp.StatusCode() < 200 || resp.StatusCode() >= 300 {
+		return &apperrors.NetworkError{
+			Err:        fmt.Errorf("POST request failed with status: %d", resp.StatusCode()),
+			StatusCode: resp.StatusCode(),
+		}
+	}
+
+	if err := json.Unmarshal(resp.Body(), v); err != nil {
+		return &apperrors.ParseError{Err: fmt.Errorf("failed to parse JSON: %w", err)}
+	}
+	return nil
+}
+
 // SetHeader sets a custom header
 func (r *RequestClient) SetHeader(key, value string) {
 	r.client.SetHeader(key, value)
@@ -15,6 +15,11 @@ type Client struct {
 // NotificationClient is the global notification client.
 var NotificationClient = &Client{}
 
+// NewClient creates a client backed by the given notifier.
+func NewClient(notifier Notifier) *Client {
+	return &Client{notifier: notifier}
+}
+
 // Init initializes the notification client with the configured channel.
 func (c *Client) Init(config *model.NotificationConfig) {
 	if !config.Enable {
@@ -35,14 +40,22 @@ func (c *Client) Init(config *model.NotificationConfig) {
 	}
 }
 
-// Send sends a notification message. Errors are logged but not returned.
-func (c *Client) Send(ctx context.Context, message *Message) {
+// Enabled reports whether a notifier has been initialized.
+func (c *Client) Enabled() bool {
+	return c.notifier != nil
+}
+
+// Send sends a notification message.
+// Returns nil without sending when no notifier is initialized.
+func (c *Client) Send(ctx context.Context, message *Message) error {
 	if c.notifier == nil {
-		slog.Warn("[Notification] No notifier initialized, skipping")
-		return
+		slog.Debug("[Notification] No notifier initialized, skipping")
+		return nil
 	}
 
 	if err := c.notifier.Send(ctx, message); err != nil {
 		slog.Error("[Notification] Send failed", "error", err)
+		return err
 	}
+	return nil
 }
@@ -0,0 +1,212 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"goto-bangumi/internal/apperrors"
+	"goto-bangumi/internal/model"
+	"goto-bangumi/internal/network"
+)
+
+const (
+	// anilistRelationsFields 查询关联条目需要的字段
+	anilistRelationsFields = `relations { edges { relationType node { id type format } } }`
+
+	anilistSearchQuery = `query ($search: String) {
+  Page(perPage: 10) {
+    media(search: $search, type: ANIME, sort: SEARCH_MATCH) {
+      id
+      title { romaji english native }
+      synonyms
+      format
+      seasonYear
+      startDate { year month day }
+      episodes
+      averageScore
+      coverImage { extraLarge large }
+      ` + anilistRelationsFields + `
+    }
+  }
+}`
+
+	anilistRelationsQuery = `query ($id: Int) {
+  Media(id: $id, type: ANIME) {
+    id
+    ` + anilistRelationsFields + `
+  }
+}`
+)
+
+// anilistURL is the GraphQL endpoint for AniList
+var anilistURL = "https://graphql.anilist.co"
+
+// AnilistParser handles AniList GraphQL API interactions and parsing
+type AnilistParser struct{}
+
+// NewAnilistParser creates a new AniList parser instance
+func NewAnilistParser() *AnilistParser {
+	return &AnilistParser{}
+}
+
+// anilistRequest GraphQL 请求体
+type anilistRequest struct {
+	Query     string         `json:"query"`
+	Variables map[string]any `json:"variables"`
+}
+
+// anilistErr 把 GraphQL 返回的错误转换为 ParseError
+func anilistErr(errs []model.AnilistError) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return &apperrors.ParseError{Err: fmt.Errorf("anilist: %s (status %d)", errs[0].Message, errs[0].Status)}
+}
+
+// AnilistSearch 搜索动画，AniList 会同时匹配罗马音、原名、英文名和别名
+func (p *AnilistParser) AnilistSearch(ctx context.Context, keyword string) ([]model.AnilistMedia, error) {
+	slog.Debug("[anilist] Searching anime", "keyword", keyword)
+
+	var result model.AnilistSearchResult
+	req := anilistRequest{Query: anilistSearchQuery, Variables: map[string]any{"search": keyword}}
+	client := network.GetRequestClient()
+	if err := client.PostJSONTo(ctx, anilistURL, req, &result); err != nil {
+		return nil, err
+	}
+	if err := anilistErr(result.Errors); err != nil {
+		return nil, err
+	}
+
+	slog.Debug("[anilist] Search completed", "results_count", len(result.Data.Page.Media))
+	return result.Data.Page.Media, nil
+}
+
+// AnilistRelations 查询条目的关联条目
+func (p *AnilistParser) AnilistRelations(ctx context.Context, id int) ([]model.AnilistRelationEdge, error) {
+	var result model.AnilistMediaResult
+	req := anilistRequest{Query: anilistRelationsQuery, Variables: map[string]any{"id": id}}
+	client := network.GetRequestClient()
+	if err := client.PostJSONTo(ctx, anilistURL, req, &result); err != nil {
+		return nil, err
+	}
+	if err := anilistErr(result.Errors); err != nil {
+		return nil, err
+	}
+	if result.Data.Media == nil {
+		return nil, &apperrors.ParseError{Err: fmt.Errorf("anilist media not found: %d", id)}
+	}
+	return result.Data.Media.Relations.Edges, nil
+}
+
+// FindAnilistMedia 从搜索结果中选出条目，任一标题或别名与关键字一致（忽略大小写）的优先，否则使用第一个
+func FindAnilistMedia(media []model.AnilistMedia, title string) *model.AnilistMedia {
+	if len(media) == 0 {
+		return nil
+	}
+	title = strings.TrimSpace(title)
+	for i := range media {
+		m := &media[i]
+		names := append([]string{m.Title.Romaji, m.Title.English, m.Title.Native}, m.Synonyms...)
+		for _, name := range names {
+			if name != "" && strings.EqualFold(strings.TrimSpace(name), title) {
+				return m
+			}
+		}
+	}
+	return &media[0]
+}
+
+// anilistSeriesRelations 从关联条目中取出前传和续集，剧场版、OVA 等前传不计入季度
+func anilistSeriesRelations(edges []model.AnilistRelationEdge) seriesRelations {
+	var result seriesRelations
+	for _, e := range edges {
+		if e.Node.Type != "ANIME" {
+			continue
+		}
+		related := &relatedSeries{ID: e.Node.ID}
+		switch e.Node.Format {
+		case "TV", "TV_SHORT", "ONA":
+			related.Series = true
+		}
+		switch {
+		case e.RelationType == "PREQUEL" && result.Prequel == nil:
+			result.Prequel = related
+		case e.RelationType == "SEQUEL" && result.Sequel == nil:
+			result.Sequel = related
+		}
+	}
+	return result
+}
+
+// lookupRelations 查询条目的前传和续集，用于沿前传推算季度
+func (p *AnilistParser) lookupRelations(ctx context.Context, id int) (seriesRelations, error) {
+	edges, err := p.AnilistRelations(ctx, id)
+	if err != nil {
+		return seriesRelations{}, err
+	}
+	return anilistSeriesRelations(edges), nil
+}
+
+// AnilistParse searches and parses AniList information for a bangumi
+func (p *AnilistParser) AnilistParse(ctx context.Context, title string) (*model.AnilistItem, error) {
+	slog.Debug("[anilist] Starting AniList parser", "title", title)
+
+	media, err := p.AnilistSearch(ctx, title)
+	if err != nil {
+		return nil, err
+	}
+	content := FindAnilistMedia(media, title)
+	if content == nil {
+		slog.Warn("[anilist] No results found for title", "title", title)
+		return nil, &apperrors.ParseError{Err: fmt.Errorf("no AniList results found for title: %s", title)}
+	}
+	slog.Debug("[anilist] Anime found", "name", content.Title.Romaji, "id", content.ID)
+
+	// 搜索结果已经带有当前条目的关联，只需要再查询前传的关联
+	season, prequelID, sequelID, err := inferSeason(ctx, content.ID, anilistSeriesRelations(content.Relations.Edges), p.lookupRelations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get AniList relations: %w", err)
+	}
+
+	item := &model.AnilistItem{
+		ID:          content.ID,
+		Title:       content.Title.English,
+		RomajiTitle: content.Title.Romaji,
+		NativeTitle: content.Title.Native,
+		Synonyms:    content.Synonyms,
+		SeriesItem: model.SeriesItem{
+			EpisodeCount: content.Episodes,
+			Season:       season,
+			PrequelID:    prequelID,
+			SequelID:     sequelID,
+			PosterLink:   content.CoverImage.ExtraLarge,
+			// AniList 的评分是百分制，换算成和 TMDB、bgm.tv 一样的十分制
+			Score: float64(content.AverageScore) / 10,
+		},
+	}
+	if item.Title == "" {
+		item.Title = content.Title.Romaji
+	}
+	if item.PosterLink == "" {
+		item.PosterLink = content.CoverImage.Large
+	}
+	year := content.SeasonYear
+	if year == 0 {
+		year = content.StartDate.Year
+	}
+	if year != 0 {
+		item.Year = strconv.Itoa(year)
+	}
+	if d := content.StartDate; d.Year != 0 && d.Month != 0 && d.Day != 0 {
+		item.AirDate = fmt.Sprintf("%04d-%02d-%02d", d.Year, d.Month, d.Day)
+	}
+	return item, nil
+}
+
+// ParseAnilist is a convenience function that creates a parser and parses
+func ParseAnilist(ctx context.Context, title string) (*model.AnilistItem, error) {
+	return NewAnilistParser().AnilistParse(ctx, title)
+}
@@ -0,0 +1,121 @@
+package parser
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"goto-bangumi/internal/apperrors"
+	"goto-bangumi/internal/model"
+)
+
+//go:embed testdata/anilist_search_oshinoko.json
+var anilistSearchOshinoko []byte
+
+//go:embed testdata/anilist_search_empty.json
+var anilistSearchEmpty []byte
+
+//go:embed testdata/anilist_relations_150672.json
+var anilistRelations150672 []byte
+
+//go:embed testdata/anilist_not_found.json
+var anilistNotFound []byte
+
+// newFakeAnilist 启动模拟的 AniList GraphQL 接口，按请求变量返回测试数据
+func newFakeAnilist(t *testing.T) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Variables struct {
+				Search string `json:"search"`
+				ID     int    `json:"id"`
+			} `json:"variables"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case req.Variables.Search == "Oshi no Ko Season 2":
+			w.Write(anilistSearchOshinoko)
+		case req.Variables.Search != "":
+			w.Write(anilistSearchEmpty)
+		case req.Variables.ID == 150672:
+			w.Write(anilistRelations150672)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write(anilistNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	oldURL := anilistURL
+	anilistURL = srv.URL
+	t.Cleanup(func() { anilistURL = oldURL })
+}
+
+func TestAnilistParse(t *testing.T) {
+	newFakeAnilist(t)
+
+	info, err := NewAnilistParser().AnilistParse(context.Background(), "Oshi no Ko Season 2")
+	if err != nil {
+		t.Fatalf("AnilistParse() error = %v", err)
+	}
+	// 第一个结果是第一季，应按别名选中第二季
+	if info.ID != 166531 {
+		t.Fatalf("AnilistParse() ID = %d, want 166531", info.ID)
+	}
+	if info.Title != "[Oshi No Ko] Season 2" || info.RomajiTitle != "[Oshi no Ko] 2nd Season" || info.NativeTitle != "【推しの子】第2期" {
+		t.Errorf("AnilistParse() titles = %q / %q / %q", info.Title, info.RomajiTitle, info.NativeTitle)
+	}
+	if info.Season != 2 || info.PrequelID != 150672 || info.SequelID != 0 {
+		t.Errorf("AnilistParse() season = %d, prequel = %d, sequel = %d, want 2, 150672, 0", info.Season, info.PrequelID, info.SequelID)
+	}
+	if info.Year != "2024" || info.AirDate != "2024-07-03" || info.EpisodeCount != 13 {
+		t.Errorf("AnilistParse() year = %s, air date = %s, episodes = %d", info.Year, info.AirDate, info.EpisodeCount)
+	}
+	if info.PosterLink != "https://s4.anilist.co/file/anilistcdn/media/anime/cover/large/bx166531-6Qr3Uq4pA1Qv.jpg" {
+		t.Errorf("AnilistParse() PosterLink = %s", info.PosterLink)
+	}
+	if info.Score != 8.1 {
+		t.Errorf("AnilistParse() Score = %v, want 8.1", info.Score)
+	}
+}
+
+func TestAnilistParse_NotFound(t *testing.T) {
+	newFakeAnilist(t)
+
+	_, err := NewAnilistParser().AnilistParse(context.Background(), "Nothing Here")
+	if !apperrors.IsParseError(err) {
+		t.Errorf("AnilistParse() error = %v, want ParseError", err)
+	}
+}
+
+func TestFindAnilistMedia(t *testing.T) {
+	media := []model.AnilistMedia{
+		{ID: 1, Title: model.AnilistTitle{Romaji: "Sousou no Frieren", Native: "葬送のフリーレン"}},
+		{ID: 2, Title: model.AnilistTitle{Romaji: "Sousou no Frieren 2nd Season", English: "Frieren: Beyond Journey's End Season 2"}, Synonyms: []string{"Frieren S2"}},
+	}
+	tests := []struct {
+		name  string
+		title string
+		want  int
+	}{
+		{name: "英文名忽略大小写", title: "frieren: beyond journey's end season 2", want: 2},
+		{name: "别名", title: "Frieren S2", want: 2},
+		{name: "原名", title: "葬送のフリーレン", want: 1},
+		{name: "没有一致的标题时使用第一个", title: "Frieren", want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FindAnilistMedia(media, tt.title)
+			if got == nil || got.ID != tt.want {
+				t.Errorf("FindAnilistMedia() = %+v, want ID %d", got, tt.want)
+			}
+		})
+	}
+	if got := FindAnilistMedia(nil, "Frieren"); got != nil {
+		t.Errorf("FindAnilistMedia(nil) = %+v, want nil", got)
+	}
+}
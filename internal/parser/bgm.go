@@ -0,0 +1,180 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+
+	"goto-bangumi/internal/apperrors"
+	"goto-bangumi/internal/model"
+	"goto-bangumi/internal/network"
+)
+
+const (
+	// bgmURL is the base URL for bgm.tv API
+	bgmURL = "https://api.bgm.tv"
+
+	// bgmSubjectAnime bgm.tv 条目类型中的动画
+	bgmSubjectAnime = 2
+)
+
+// BgmParser handles bgm.tv API interactions and parsing
+type BgmParser struct{}
+
+// NewBgmParser creates a new bgm.tv parser instance
+func NewBgmParser() *BgmParser {
+	return &BgmParser{}
+}
+
+// BgmSearchURL 生成 bgm.tv 动画条目搜索 URL
+func BgmSearchURL(keyword string) string {
+	return fmt.Sprintf("%s/search/subject/%s?type=%d&responseGroup=small",
+		bgmURL, url.PathEscape(keyword), bgmSubjectAnime)
+}
+
+// BgmSubjectURL 生成 bgm.tv 条目详情 URL
+func BgmSubjectURL(id int) string {
+	return fmt.Sprintf("%s/v0/subjects/%d", bgmURL, id)
+}
+
+// BgmRelationsURL 生成 bgm.tv 关联条目 URL
+func BgmRelationsURL(id int) string {
+	return fmt.Sprintf("%s/v0/subjects/%d/subjects", bgmURL, id)
+}
+
+// BgmSearch searches for anime subjects on bgm.tv by keyword
+func (p *BgmParser) BgmSearch(ctx context.Context, keyword string) ([]model.BgmSearchSubject, error) {
+	url := BgmSearchURL(keyword)
+	slog.Debug("[bgm] Searching subjects", "keyword", keyword, "url", url)
+
+	var searchResult model.BgmSearchResult
+	client := network.GetRequestClient()
+	if err := client.GetJSONTo(ctx, url, &searchResult); err != nil {
+		return nil, err
+	}
+
+	slog.Debug("[bgm] Search completed", "results_count", len(searchResult.List))
+	return searchResult.List, nil
+}
+
+// BgmSubject fetches detailed information for a subject
+func (p *BgmParser) BgmSubject(ctx context.Context, id int) (*model.BgmSubject, error) {
+	var subject model.BgmSubject
+	client := network.GetRequestClient()
+	if err := client.GetJSONTo(ctx, BgmSubjectURL(id), &subject); err != nil {
+		return nil, err
+	}
+	return &subject, nil
+}
+
+// BgmRelations fetches related subjects for a subject
+func (p *BgmParser) BgmRelations(ctx context.Context, id int) ([]model.BgmRelatedSubject, error) {
+	var relations []model.BgmRelatedSubject
+	client := network.GetRequestClient()
+	if err := client.GetJSONTo(ctx, BgmRelationsURL(id), &relations); err != nil {
+		return nil, err
+	}
+	return relations, nil
+}
+
+// FindBgmSubject 从搜索结果中选出条目，中文名或原名与标题完全一致的优先，否则使用第一个动画条目
+func FindBgmSubject(subjects []model.BgmSearchSubject, title string) *model.BgmSearchSubject {
+	title = strings.TrimSpace(title)
+	var first *model.BgmSearchSubject
+	for i := range subjects {
+		s := &subjects[i]
+		if s.Type != 0 && s.Type != bgmSubjectAnime {
+			continue
+		}
+		if strings.TrimSpace(s.NameCN) == title || strings.TrimSpace(s.Name) == title {
+			return s
+		}
+		if first == nil {
+			first = s
+		}
+	}
+	return first
+}
+
+// bgmSeriesRelations 查询条目的前传和续集，bgm.tv 的关联条目没有放送类型，前传都算作一季
+func (p *BgmParser) bgmSeriesRelations(ctx context.Context, id int) (seriesRelations, error) {
+	relations, err := p.BgmRelations(ctx, id)
+	if err != nil {
+		return seriesRelations{}, err
+	}
+	var result seriesRelations
+	for _, r := range relations {
+		if r.Type != bgmSubjectAnime {
+			continue
+		}
+		switch {
+		case r.Relation == "前传" && result.Prequel == nil:
+			result.Prequel = &relatedSeries{ID: r.ID, Series: true}
+		case r.Relation == "续集" && result.Sequel == nil:
+			result.Sequel = &relatedSeries{ID: r.ID, Series: true}
+		}
+	}
+	return result, nil
+}
+
+// BgmParse searches and parses bgm.tv information for a bangumi
+func (p *BgmParser) BgmParse(ctx context.Context, title string) (*model.BangumiItem, error) {
+	slog.Debug("[bgm] Starting bgm.tv parser", "title", title)
+
+	contents, err := p.BgmSearch(ctx, title)
+	if err != nil {
+		return nil, err
+	}
+	content := FindBgmSubject(contents, title)
+	if content == nil {
+		slog.Warn("[bgm] No results found for title", "title", title)
+		return nil, &apperrors.ParseError{Err: fmt.Errorf("no bgm.tv results found for title: %s", title)}
+	}
+	slog.Debug("[bgm] Subject found", "name", content.Name, "id", content.ID)
+
+	subject, err := p.BgmSubject(ctx, content.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bgm.tv subject: %w", err)
+	}
+	relations, err := p.bgmSeriesRelations(ctx, subject.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bgm.tv relations: %w", err)
+	}
+	season, prequelID, sequelID, err := inferSeason(ctx, subject.ID, relations, p.bgmSeriesRelations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bgm.tv relations: %w", err)
+	}
+
+	item := &model.BangumiItem{
+		ID:            subject.ID,
+		Title:         subject.NameCN,
+		OriginalTitle: subject.Name,
+		SeriesItem: model.SeriesItem{
+			AirDate:      subject.Date,
+			EpisodeCount: subject.Eps,
+			Season:       season,
+			PrequelID:    prequelID,
+			SequelID:     sequelID,
+			PosterLink:   subject.Images.Large,
+			Score:        subject.Rating.Score,
+		},
+	}
+	// 没有中文名的条目用原名
+	if item.Title == "" {
+		item.Title = subject.Name
+	}
+	if item.EpisodeCount == 0 {
+		item.EpisodeCount = subject.TotalEpisodes
+	}
+	if len(subject.Date) >= 4 {
+		item.Year = subject.Date[:4]
+	}
+	return item, nil
+}
+
+// ParseBgm is a convenience function that creates a parser and parses
+func ParseBgm(ctx context.Context, title string) (*model.BangumiItem, error) {
+	return NewBgmParser().BgmParse(ctx, title)
+}
@@ -0,0 +1,87 @@
+package parser
+
+import (
+	"context"
+	_ "embed"
+	"testing"
+
+	"goto-bangumi/internal/apperrors"
+	"goto-bangumi/internal/model"
+)
+
+//go:embed testdata/bgm_search_classroom.json
+var bgmSearchClassroom []byte
+
+//go:embed testdata/bgm_search_empty.json
+var bgmSearchEmpty []byte
+
+//go:embed testdata/bgm_subject_373267.json
+var bgmSubject373267 []byte
+
+//go:embed testdata/bgm_relations_373267.json
+var bgmRelations373267 []byte
+
+//go:embed testdata/bgm_relations_371546.json
+var bgmRelations371546 []byte
+
+//go:embed testdata/bgm_relations_216371.json
+var bgmRelations216371 []byte
+
+func TestBgmParse(t *testing.T) {
+	info, err := NewBgmParser().BgmParse(context.Background(), "欢迎来到实力至上主义的教室 第三季")
+	if err != nil {
+		t.Fatalf("BgmParse() error = %v", err)
+	}
+	want := model.BangumiItem{
+		ID:            373267,
+		Title:         "欢迎来到实力至上主义的教室 第三季",
+		OriginalTitle: "ようこそ実力至上主義の教室へ 3rd Season",
+		SeriesItem: model.SeriesItem{
+			Year:         "2024",
+			AirDate:      "2024-01-03",
+			EpisodeCount: 13,
+			Season:       3,
+			PrequelID:    371546,
+			PosterLink:   "https://lain.bgm.tv/pic/cover/l/e3/0b/373267_xq5X5.jpg",
+			Score:        6.9,
+		},
+	}
+	if *info != want {
+		t.Errorf("BgmParse() = %+v, want %+v", *info, want)
+	}
+}
+
+func TestBgmParse_NotFound(t *testing.T) {
+	_, err := NewBgmParser().BgmParse(context.Background(), "不存在的番剧")
+	if !apperrors.IsParseError(err) {
+		t.Errorf("BgmParse() error = %v, want ParseError", err)
+	}
+}
+
+func TestFindBgmSubject(t *testing.T) {
+	subjects := []model.BgmSearchSubject{
+		{ID: 1, Type: 1, NameCN: "欢迎来到实力至上主义的教室"},
+		{ID: 2, Type: 2, NameCN: "欢迎来到实力至上主义的教室 第二季"},
+		{ID: 3, Type: 2, Name: "ようこそ実力至上主義の教室へ", NameCN: "欢迎来到实力至上主义的教室"},
+	}
+	tests := []struct {
+		name  string
+		title string
+		want  int
+	}{
+		{name: "中文名完全一致", title: "欢迎来到实力至上主义的教室", want: 3},
+		{name: "原名完全一致", title: "ようこそ実力至上主義の教室へ", want: 3},
+		{name: "没有完全一致时使用第一个动画", title: "实力至上主义", want: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FindBgmSubject(subjects, tt.title)
+			if got == nil || got.ID != tt.want {
+				t.Errorf("FindBgmSubject() = %+v, want ID %d", got, tt.want)
+			}
+		})
+	}
+	if got := FindBgmSubject(nil, "欢迎来到实力至上主义的教室"); got != nil {
+		t.Errorf("FindBgmSubject(nil) = %+v, want nil", got)
+	}
+}
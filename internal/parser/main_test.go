@@ -42,6 +42,16 @@ func TestMain(m *testing.M) {
 	// 设置 Mikan 测试缓存 - 边缘情况（无mikanID、无官方标题、无poster）
 	network.SetTestCache("https://mikanani.me/Home/Episode/699000310671bae565c37abb20d119824efeb6f0", mikanEdgeCaseHTML)
 
+	// 设置 bgm.tv 测试缓存 - 欢迎来到实力至上主义的教室 第三季
+	network.SetTestCache(BgmSearchURL("欢迎来到实力至上主义的教室 第三季"), bgmSearchClassroom)
+	network.SetTestCache(BgmSubjectURL(373267), bgmSubject373267)
+	network.SetTestCache(BgmRelationsURL(373267), bgmRelations373267)
+	network.SetTestCache(BgmRelationsURL(371546), bgmRelations371546)
+	network.SetTestCache(BgmRelationsURL(216371), bgmRelations216371)
+
+	// 设置 bgm.tv 测试缓存 - 没有搜索结果
+	network.SetTestCache(BgmSearchURL("不存在的番剧"), bgmSearchEmpty)
+
 	// 运行测试
 	code := m.Run()
 
@@ -0,0 +1,49 @@
+package parser
+
+import "context"
+
+// maxPrequels 推算季度时最多向前查找的前传数量，防止关联成环
+const maxPrequels = 10
+
+// relatedSeries 条目的一个关联条目
+type relatedSeries struct {
+	ID     int
+	Series bool // 是否算作一季，剧场版、OVA 等前传不计入季度
+}
+
+// seriesRelations 条目的前传和续集，没有时为 nil
+type seriesRelations struct {
+	Prequel *relatedSeries
+	Sequel  *relatedSeries
+}
+
+// relationsFunc 查询条目的前传和续集
+type relationsFunc func(ctx context.Context, id int) (seriesRelations, error)
+
+// inferSeason bgm.tv、AniList 每一季都是单独的条目，沿着前传一直找到第一季来推算季度
+// current 为当前条目 id 的关联，lookup 用来查询前传的关联；返回季度和直接的前传、续集 ID
+func inferSeason(ctx context.Context, id int, current seriesRelations, lookup relationsFunc) (season, prequelID, sequelID int, err error) {
+	if current.Sequel != nil {
+		sequelID = current.Sequel.ID
+	}
+	season = 1
+	visited := map[int]bool{id: true}
+	relations := current
+	for range maxPrequels {
+		prequel := relations.Prequel
+		if prequel == nil || visited[prequel.ID] {
+			break
+		}
+		if prequelID == 0 {
+			prequelID = prequel.ID
+		}
+		visited[prequel.ID] = true
+		if prequel.Series {
+			season++
+		}
+		if relations, err = lookup(ctx, prequel.ID); err != nil {
+			return 0, 0, 0, err
+		}
+	}
+	return season, prequelID, sequelID, nil
+}
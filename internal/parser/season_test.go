@@ -0,0 +1,38 @@
+package parser
+
+import (
+	"context"
+	"testing"
+)
+
+func TestInferSeason(t *testing.T) {
+	// 4 -> 3(剧场版) -> 2 -> 1，5 为 4 的续集
+	chain := map[int]seriesRelations{
+		3: {Prequel: &relatedSeries{ID: 2, Series: true}, Sequel: &relatedSeries{ID: 4, Series: true}},
+		2: {Prequel: &relatedSeries{ID: 1, Series: true}},
+		1: {},
+	}
+	lookup := func(ctx context.Context, id int) (seriesRelations, error) {
+		return chain[id], nil
+	}
+	current := seriesRelations{
+		Prequel: &relatedSeries{ID: 3, Series: false},
+		Sequel:  &relatedSeries{ID: 5, Series: true},
+	}
+	season, prequel, sequel, err := inferSeason(context.Background(), 4, current, lookup)
+	if err != nil {
+		t.Fatalf("inferSeason() error = %v", err)
+	}
+	if season != 3 || prequel != 3 || sequel != 5 {
+		t.Errorf("inferSeason() = %d, %d, %d, want 3, 3, 5", season, prequel, sequel)
+	}
+
+	// 关联成环时停止
+	loop := func(ctx context.Context, id int) (seriesRelations, error) {
+		return seriesRelations{Prequel: &relatedSeries{ID: 1, Series: true}}, nil
+	}
+	season, _, _, err = inferSeason(context.Background(), 1, seriesRelations{Prequel: &relatedSeries{ID: 2, Series: true}}, loop)
+	if err != nil || season != 2 {
+		t.Errorf("inferSeason() with a loop = %d, %v, want 2", season, err)
+	}
+}
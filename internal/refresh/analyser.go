@@ -2,6 +2,7 @@ package refresh
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"regexp"
 	"strings"
@@ -33,38 +34,121 @@ func OfficialTitleParse(ctx context.Context, torrent *model.Torrent) (*model.Ban
 			}
 		}
 	}
-	if bangumi.Parse == "bangumi" {
-		// Bangumi 解析, 没有做
+	var title string
+	if bangumi.OfficialTitle != "" {
+		// 优先使用 mikan 解析到的标题
+		title = bangumi.OfficialTitle
 	} else {
-		tmdbParse := parser.NewTMDBParse()
-		var title string
-		if bangumi.OfficialTitle != "" {
-			// 优先使用 mikan 解析到的标题
-			title = bangumi.OfficialTitle
-		} else {
-			// 否则使用种子标题
-			title = parser.NewTitleMetaParse().Parse(torrent.Name).Title
+		// 否则使用种子标题
+		title = parser.NewTitleMetaParse().Parse(torrent.Name).Title
+	}
+	err := metadataParse(ctx, bangumi, title, parser.ParserConfig.Providers)
+	// 当元数据来源都没有找到信息的时候，如果 mikan 也没有找到， 报错
+	if err != nil {
+		if bangumi.OfficialTitle == "" {
+			return nil, err
 		}
+		return bangumi, err
+	}
+	return bangumi, nil
+}
 
-		tmdbInfo, err := tmdbParse.TMDBParse(ctx, title, "zh")
-		// 当 tmdb 也没有找到信息的时候，如果 mikan 也没有找到， 报错
-		if err != nil {
-			if bangumi.OfficialTitle == "" {
-				return nil, err
-			}
-			return bangumi, err
+// metadataProvider 用一个元数据来源补全番剧信息，失败时不修改 bangumi
+type metadataProvider func(ctx context.Context, bangumi *model.Bangumi, title string) error
+
+// metadataProviders 可用的元数据来源，键为配置中的名称，也是 bangumi.Parse 的值
+var metadataProviders = map[string]metadataProvider{
+	"tmdb":    tmdbParse,
+	"bangumi": bgmParse,
+	"anilist": anilistParse,
+}
+
+// metadataParse 按配置的顺序尝试各个元数据来源，以第一个成功的为准
+// 全部失败时只要有一个是网络错误就返回网络错误，让这个番剧在下次刷新时重试
+func metadataParse(ctx context.Context, bangumi *model.Bangumi, title string, providers []string) error {
+	if len(providers) == 0 {
+		providers = []string{"tmdb"}
+	}
+	var lastErr, networkErr error
+	for _, name := range providers {
+		name = strings.ToLower(strings.TrimSpace(name))
+		parse, ok := metadataProviders[name]
+		if !ok {
+			slog.Warn("[OfficialTitleParse] 未知的元数据来源", "provider", name)
+			continue
 		}
-		// 只有在没有解析到标题的情况下才使用 tmdb 的结果
-		if bangumi.OfficialTitle == "" {
-			bangumi.OfficialTitle = tmdbInfo.Title
-			bangumi.PosterLink = tmdbInfo.PosterLink
+		err := parse(ctx, bangumi, title)
+		if err == nil {
+			bangumi.Parse = name
+			return nil
 		}
-		// 总是以 tmdb 的季度为准
-		bangumi.Season = tmdbInfo.Season
-		bangumi.Year = tmdbInfo.Year
-		bangumi.TmdbItem = tmdbInfo
+		slog.Debug("[OfficialTitleParse] 元数据解析失败", "provider", name, "title", title, "error", err)
+		if networkErr == nil && apperrors.IsNetworkError(err) {
+			networkErr = err
+		}
+		lastErr = err
 	}
-	return bangumi, nil
+	if networkErr != nil {
+		return networkErr
+	}
+	if lastErr == nil {
+		return &apperrors.ParseError{Err: fmt.Errorf("没有可用的元数据来源: %v", providers)}
+	}
+	return lastErr
+}
+
+// tmdbParse 用 TMDB 补全番剧信息
+func tmdbParse(ctx context.Context, bangumi *model.Bangumi, title string) error {
+	tmdbInfo, err := parser.NewTMDBParse().TMDBParse(ctx, title, "zh")
+	if err != nil {
+		return err
+	}
+	// 只有在没有解析到标题的情况下才使用 tmdb 的结果
+	if bangumi.OfficialTitle == "" {
+		bangumi.OfficialTitle = tmdbInfo.Title
+		bangumi.PosterLink = tmdbInfo.PosterLink
+	}
+	// 总是以 tmdb 的季度为准
+	bangumi.Season = tmdbInfo.Season
+	bangumi.Year = tmdbInfo.Year
+	bangumi.TmdbItem = tmdbInfo
+	return nil
+}
+
+// bgmParse 用 bgm.tv 补全番剧信息, bgm.tv 每季是单独的条目, 季度按前传数量推算
+func bgmParse(ctx context.Context, bangumi *model.Bangumi, title string) error {
+	bgmInfo, err := parser.NewBgmParser().BgmParse(ctx, title)
+	if err != nil {
+		return err
+	}
+	if bangumi.OfficialTitle == "" {
+		bangumi.OfficialTitle = bgmInfo.Title
+	}
+	if bangumi.PosterLink == "" {
+		bangumi.PosterLink = bgmInfo.PosterLink
+	}
+	bangumi.Season = bgmInfo.Season
+	bangumi.Year = bgmInfo.Year
+	bangumi.BgmItem = bgmInfo
+	return nil
+}
+
+// anilistParse 用 AniList 补全番剧信息, 只有英文名的种子也能通过别名匹配
+func anilistParse(ctx context.Context, bangumi *model.Bangumi, title string) error {
+	anilistInfo, err := parser.NewAnilistParser().AnilistParse(ctx, title)
+	if err != nil {
+		return err
+	}
+	if bangumi.OfficialTitle == "" {
+		bangumi.OfficialTitle = anilistInfo.Title
+	}
+	if bangumi.PosterLink == "" {
+		bangumi.PosterLink = anilistInfo.PosterLink
+	}
+	bangumi.Season = anilistInfo.Season
+	bangumi.Year = anilistInfo.Year
+	bangumi.AnilistItem = anilistInfo
+	return nil
 }
 
 // FilterTorrent 通过bangumi信息判断torrent是否符合要求
@@ -141,6 +225,10 @@ func (r *Refresher) createBangumi(ctx context.Context, torrent *model.Torrent, r
 
 	if bangumi != nil {
 		slog.Debug("createBangumi", "名称", bangumi.OfficialTitle)
+		// 新番剧沿用 RSS 的下载器路由
+		if bangumi.Downloader == "" {
+			bangumi.Downloader = rssItem.Downloader
+		}
 		// if torrent.Homepage != "" && bangumi.MikanItem == nil {
 		// 	// 这里对应 mikan 未添加的情况, 一般出现在季度初
 		// 	// TODO: 没想好怎么处理, 先放着
@@ -2,8 +2,10 @@ package refresh
 
 import (
 	"context"
+	"errors"
 	"testing"
 
+	"goto-bangumi/internal/apperrors"
 	"goto-bangumi/internal/database"
 	"goto-bangumi/internal/model"
 )
@@ -245,3 +247,92 @@ func TestCreateBangumi(t *testing.T) {
 		t.Errorf("TmdbID = %v, want 261343", bangumi.TmdbID)
 	}
 }
+
+func TestMetadataParse_Order(t *testing.T) {
+	var calls []string
+	provider := func(name string, err error) metadataProvider {
+		return func(ctx context.Context, bangumi *model.Bangumi, title string) error {
+			calls = append(calls, name)
+			if err == nil {
+				bangumi.OfficialTitle = title + " (" + name + ")"
+			}
+			return err
+		}
+	}
+	parseErr := &apperrors.ParseError{Err: errors.New("not found")}
+	networkErr := &apperrors.NetworkError{Err: errors.New("timeout")}
+
+	oldProviders := metadataProviders
+	defer func() { metadataProviders = oldProviders }()
+
+	tests := []struct {
+		name        string
+		providers   map[string]metadataProvider
+		order       []string
+		wantCalls   []string
+		wantParse   string
+		wantNetwork bool
+		wantErr     bool
+	}{
+		{
+			name:      "第一个失败时使用下一个",
+			providers: map[string]metadataProvider{"tmdb": provider("tmdb", parseErr), "anilist": provider("anilist", nil)},
+			order:     []string{"tmdb", "anilist"},
+			wantCalls: []string{"tmdb", "anilist"},
+			wantParse: "anilist",
+		},
+		{
+			name:      "成功后不再尝试后面的来源",
+			providers: map[string]metadataProvider{"tmdb": provider("tmdb", nil), "anilist": provider("anilist", nil)},
+			order:     []string{"anilist", "tmdb"},
+			wantCalls: []string{"anilist"},
+			wantParse: "anilist",
+		},
+		{
+			name:      "跳过未知的来源",
+			providers: map[string]metadataProvider{"tmdb": provider("tmdb", nil)},
+			order:     []string{"unknown", " TMDB "},
+			wantCalls: []string{"tmdb"},
+			wantParse: "tmdb",
+		},
+		{
+			name:        "全部失败时优先返回网络错误",
+			providers:   map[string]metadataProvider{"tmdb": provider("tmdb", networkErr), "bangumi": provider("bangumi", parseErr)},
+			order:       []string{"tmdb", "bangumi"},
+			wantCalls:   []string{"tmdb", "bangumi"},
+			wantNetwork: true,
+			wantErr:     true,
+		},
+		{
+			name:      "没有配置时使用 tmdb",
+			providers: map[string]metadataProvider{"tmdb": provider("tmdb", parseErr)},
+			wantCalls: []string{"tmdb"},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls = nil
+			metadataProviders = tt.providers
+			bangumi := model.NewBangumi()
+			err := metadataParse(context.Background(), bangumi, "title", tt.order)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("metadataParse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantNetwork && !apperrors.IsNetworkError(err) {
+				t.Errorf("metadataParse() error = %v, want NetworkError", err)
+			}
+			if len(calls) != len(tt.wantCalls) {
+				t.Fatalf("calls = %v, want %v", calls, tt.wantCalls)
+			}
+			for i := range calls {
+				if calls[i] != tt.wantCalls[i] {
+					t.Errorf("calls = %v, want %v", calls, tt.wantCalls)
+				}
+			}
+			if tt.wantParse != "" && bangumi.Parse != tt.wantParse {
+				t.Errorf("bangumi.Parse = %s, want %s", bangumi.Parse, tt.wantParse)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package refresh
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"goto-bangumi/internal/model"
+	"goto-bangumi/internal/network"
+	"goto-bangumi/internal/parser"
+	"goto-bangumi/internal/rss"
+)
+
+// FindAlternative 在已知的 RSS 源里找同一集的其他发布，用来替换下载停滞的种子
+// 要求是同一番剧、同一季同一集、分辨率和字幕语言相同、字幕组不同；
+// 数据库里已有的种子不会被选中，避免在几个都下不动的发布之间来回切换。没有找到时返回 nil
+func (r *Refresher) FindAlternative(ctx context.Context, stalled *model.Torrent, bangumi *model.Bangumi) (*model.Torrent, error) {
+	if stalled == nil || bangumi == nil {
+		return nil, nil
+	}
+	want := parser.NewTitleMetaParse().Parse(stalled.Name)
+	if want.Collection || want.Episode < 0 {
+		return nil, nil
+	}
+
+	feeds, err := r.feedLinks(ctx, bangumi)
+	if err != nil {
+		return nil, err
+	}
+	titles := make(map[string]bool)
+	if parses, err := r.db.GetParsesByBangumiID(ctx, bangumi.ID); err == nil {
+		for _, p := range parses {
+			titles[p.Title] = true
+		}
+	}
+
+	var errs []error
+	client := network.GetRequestClient()
+	for _, link := range feeds {
+		torrents, err := rss.GetTorrents(ctx, client, link)
+		if err != nil {
+			slog.Warn("[FindAlternative] 获取 RSS 失败", "URL", link, "error", err)
+			errs = append(errs, err)
+			continue
+		}
+		candidates, err := r.db.CheckNewTorrents(ctx, torrents)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range candidates {
+			if r.isAlternative(ctx, t, want, bangumi, titles) {
+				t.Bangumi = bangumi
+				t.BangumiID = bangumi.ID
+				return t, nil
+			}
+		}
+	}
+	// 所有源都请求失败时才报错，有源能访问但没找到就是没有替代
+	if len(errs) == len(feeds) && len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return nil, nil
+}
+
+// feedLinks 番剧自己的 RSS 加上所有启用的 RSS，去重后番剧的排在最前
+func (r *Refresher) feedLinks(ctx context.Context, bangumi *model.Bangumi) ([]string, error) {
+	items, err := r.db.ListActiveRSS(ctx)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool, len(items)+1)
+	var links []string
+	for _, link := range append([]string{bangumi.RSSLink}, rssLinks(items)...) {
+		if link == "" || seen[link] {
+			continue
+		}
+		seen[link] = true
+		links = append(links, link)
+	}
+	return links, nil
+}
+
+func rssLinks(items []*model.RSSItem) []string {
+	links := make([]string, 0, len(items))
+	for _, item := range items {
+		links = append(links, item.Link)
+	}
+	return links
+}
+
+// isAlternative 判断种子是否是同一集的其他发布
+func (r *Refresher) isAlternative(ctx context.Context, t *model.Torrent, want *model.EpisodeMetadata, bangumi *model.Bangumi, titles map[string]bool) bool {
+	meta := parser.NewTitleMetaParse().Parse(t.Name)
+	if meta.Collection || meta.Episode != want.Episode || meta.Season != want.Season {
+		return false
+	}
+	if meta.Group == "" || meta.Group == want.Group {
+		return false
+	}
+	if normalizeResolution(meta.Resolution) != normalizeResolution(want.Resolution) || meta.Sub != want.Sub {
+		return false
+	}
+	// 尊重番剧的排除规则，包含规则通常锁定了字幕组，这里不使用
+	if !FilterTorrent(t, "", bangumi.ExcludeFilter) {
+		return false
+	}
+	if titles[meta.Title] {
+		return true
+	}
+	matched, err := r.db.GetBangumiParseByTitle(ctx, t.Name)
+	return err == nil && matched.ID == bangumi.ID
+}
+
+var resolutionSize = regexp.MustCompile(`\d{3,4}\s*[×xX]\s*(\d{3,4})`)
+
+// normalizeResolution 统一分辨率的写法，1920x1080 和 1080P 视为相同
+func normalizeResolution(resolution string) string {
+	resolution = strings.ToLower(strings.TrimSpace(resolution))
+	if m := resolutionSize.FindStringSubmatch(resolution); m != nil {
+		return m[1] + "p"
+	}
+	if resolution == "4k" {
+		return "2160p"
+	}
+	return resolution
+}
@@ -0,0 +1,85 @@
+package refresh
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+	"testing"
+
+	"goto-bangumi/internal/database"
+	"goto-bangumi/internal/model"
+	"goto-bangumi/internal/network"
+)
+
+// failoverRSS 生成只有标题和种子链接的 RSS
+func failoverRSS(names ...string) []byte {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="utf-8"?><rss version="2.0"><channel><title>Frieren</title>`)
+	for i, name := range names {
+		fmt.Fprintf(&b, `<item><title>%s</title><link>https://example.com/%d</link><enclosure url="https://example.com/%d.torrent" /></item>`, html.EscapeString(name), i, i)
+	}
+	b.WriteString(`</channel></rss>`)
+	return []byte(b.String())
+}
+
+func TestFindAlternative(t *testing.T) {
+	t.Parallel()
+	memoryDB := ":memory:"
+	db, err := database.NewDB(&memoryDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	rssURL := "https://mikanani.me/RSS/Bangumi?bangumiId=3141&failover=test"
+	network.SetTestCache(rssURL, failoverRSS(
+		"[桜都字幕组] 葬送的芙莉莲 / Sousou no Frieren [05][1080p][简繁内封]",
+		"[喵萌奶茶屋&LoliHouse] 葬送的芙莉莲 / Sousou no Frieren - 05 [WebRip 1080p HEVC-10bit AAC][简繁日内封字幕]",
+		"[桜都字幕组] 葬送的芙莉莲 / Sousou no Frieren [05][720p][简繁内封]",
+		"[桜都字幕组] 葬送的芙莉莲 / Sousou no Frieren [06][1080p][简繁内封]",
+		"[LoliHouse] 葬送的芙莉莲 / Sousou no Frieren - 05 v2 [WebRip 1080p HEVC-10bit AAC][简繁内封字幕]",
+		"[北宇治字幕组] 葬送的芙莉莲 / Sousou no Frieren [05][WebRip][1920x1080][AVC AAC][简繁内封]",
+	))
+	defer network.ClearTestCache(rssURL)
+
+	bangumi := &model.Bangumi{
+		OfficialTitle:   "葬送的芙莉莲",
+		Season:          1,
+		RSSLink:         rssURL,
+		EpisodeMetadata: []model.EpisodeMetadata{{Title: "葬送的芙莉莲", Group: "LoliHouse", Season: 1}},
+	}
+	if err := db.CreateBangumi(bangumi); err != nil {
+		t.Fatal(err)
+	}
+	// 桜都的 1080p 已经在数据库里，比如之前换用过但同样下载失败
+	known := &model.Torrent{Link: "https://example.com/0.torrent", Name: "[桜都字幕组] 葬送的芙莉莲 / Sousou no Frieren [05][1080p][简繁内封]"}
+	if err := db.CreateTorrent(ctx, known); err != nil {
+		t.Fatal(err)
+	}
+
+	r := New(db)
+	stalled := &model.Torrent{Link: "stalled", Name: "[LoliHouse] 葬送的芙莉莲 / Sousou no Frieren - 05 [WebRip 1080p HEVC-10bit AAC][简繁内封字幕]"}
+	alt, err := r.FindAlternative(ctx, stalled, bangumi)
+	if err != nil {
+		t.Fatalf("FindAlternative: %v", err)
+	}
+	if alt == nil || alt.Link != "https://example.com/5.torrent" || alt.BangumiID != bangumi.ID {
+		t.Fatalf("FindAlternative() = %+v, want the 北宇治字幕组 release", alt)
+	}
+
+	// 合集没有单独的一集可以替换
+	collection := &model.Torrent{Name: "[LoliHouse] 葬送的芙莉莲 / Sousou no Frieren [01-28 合集][WebRip 1080p][简繁内封字幕]"}
+	if alt, err := r.FindAlternative(ctx, collection, bangumi); err != nil || alt != nil {
+		t.Errorf("FindAlternative(collection) = %+v, %v, want nil", alt, err)
+	}
+}
+
+func TestNormalizeResolution(t *testing.T) {
+	for in, want := range map[string]string{"1080P": "1080p", "1920x1080": "1080p", "1920×1080": "1080p", "4K": "2160p", "720p": "720p"} {
+		if got := normalizeResolution(in); got != want {
+			t.Errorf("normalizeResolution(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package rename
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+
+	"goto-bangumi/internal/download"
+	"goto-bangumi/internal/model"
+	"goto-bangumi/internal/parser"
+)
+
+// Plan 在添加之前按种子内的文件生成重命名计划，规则与下载完成后的 Rename 相同
+// downloaded 为同一番剧已经下载完成的种子，用来发现重复下载的集数。磁力链接没有文件列表，返回 nil
+func Plan(torrentInfo *model.TorrentInfo, bangumi *model.Bangumi, downloaded []*model.Torrent) *model.RenamePlan {
+	if torrentInfo == nil || len(torrentInfo.Files) == 0 || bangumi == nil {
+		return nil
+	}
+	done := downloadedEpisodes(downloaded, bangumi)
+	skip := torrentInfo.SkipPaths()
+	plan := &model.RenamePlan{Files: make([]model.PlannedFile, 0, len(torrentInfo.Files))}
+	targets := make(map[string]string) // 新文件名 -> 原路径
+	for _, f := range torrentInfo.Files {
+		file := model.PlannedFile{Path: f.Path, Episode: -1}
+		name := filepath.Base(f.Path)
+		switch {
+		case skip[f.Path] || !download.WantedPath(f.Path) || parser.IsPoint5(name):
+			file.Skipped = true
+		default:
+			planFile(&file, name, bangumi, targets, done)
+		}
+		if file.Problem != "" {
+			plan.Problems = append(plan.Problems, fmt.Sprintf("%s: %s", name, file.Problem))
+		}
+		if file.Episode >= 0 && !slices.Contains(plan.Episodes, file.Episode) {
+			plan.Episodes = append(plan.Episodes, file.Episode)
+		}
+		plan.Files = append(plan.Files, file)
+	}
+	slices.Sort(plan.Episodes)
+	plan.Collection = len(plan.Episodes) > 1
+	return plan
+}
+
+// planFile 计算单个文件的新文件名并检查问题
+func planFile(file *model.PlannedFile, name string, bangumi *model.Bangumi, targets map[string]string, done map[int]string) {
+	metaInfo := parser.NewTitleMetaParse().Parse(name)
+	if metaInfo.Episode == -1 {
+		file.Problem = "无法解析集数"
+		return
+	}
+	file.Episode = metaInfo.Episode + bangumi.Offset
+	file.Target = buildPath(name, metaInfo, bangumi)
+	if other, ok := targets[file.Target]; ok {
+		file.Problem = fmt.Sprintf("与 %s 重命名后同名", filepath.Base(other))
+		return
+	}
+	targets[file.Target] = file.Path
+	if torrentName, ok := done[file.Episode]; ok {
+		file.Problem = fmt.Sprintf("第 %d 集已经由 %s 下载过", file.Episode, torrentName)
+	}
+}
+
+// downloadedEpisodes 从已完成种子的名称解析出已经下载过的集数，合集无法确定集数时跳过
+func downloadedEpisodes(torrents []*model.Torrent, bangumi *model.Bangumi) map[int]string {
+	episodes := make(map[int]string, len(torrents))
+	for _, t := range torrents {
+		metaInfo := parser.NewTitleMetaParse().Parse(t.Name)
+		if metaInfo.Collection || metaInfo.Episode < 0 {
+			continue
+		}
+		episodes[metaInfo.Episode+bangumi.Offset] = t.Name
+	}
+	return episodes
+}
@@ -0,0 +1,58 @@
+package rename
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"goto-bangumi/internal/model"
+)
+
+func TestPlan(t *testing.T) {
+	Init(&model.BangumiRenameConfig{})
+	bangumi := &model.Bangumi{OfficialTitle: "葬送的芙莉莲", Season: 1}
+	torrentInfo := &model.TorrentInfo{
+		Files: []model.TorrentFile{
+			{Path: "Frieren/[LoliHouse] Sousou no Frieren - 01 [WebRip 1080p].mkv"},
+			{Path: "Frieren/[LoliHouse] Sousou no Frieren - 02 [WebRip 1080p].mkv"},
+			{Path: "Frieren/[LoliHouse] Sousou no Frieren - 02 [WebRip 1080p].sc.ass"},
+			{Path: "Frieren/[LoliHouse] Sousou no Frieren - 02 [WebRip 1080p].tc.ass"},
+			{Path: "Frieren/[LoliHouse] Sousou no Frieren - 02.5 [WebRip 1080p].mkv"},
+			{Path: "Frieren/[LoliHouse] Sousou no Frieren - 03 [WebRip 1080p].mkv", Size: 1},
+		},
+		SkipFiles: []int{5},
+	}
+	downloaded := []*model.Torrent{{Name: "[桜都字幕组] 葬送的芙莉莲 / Sousou no Frieren [01][1080p][简繁内封]"}}
+
+	plan := Plan(torrentInfo, bangumi, downloaded)
+	if plan == nil {
+		t.Fatal("Plan() = nil")
+	}
+	if !plan.Collection || !slices.Equal(plan.Episodes, []int{1, 2}) {
+		t.Errorf("Collection = %v, Episodes = %v, want a collection of 1 and 2", plan.Collection, plan.Episodes)
+	}
+	if got := plan.Files[1].Target; got != "葬送的芙莉莲 S01E02.mkv" {
+		t.Errorf("target = %q", got)
+	}
+	if !plan.Files[4].Skipped || !plan.Files[5].Skipped {
+		t.Errorf("0.5 episode and unselected files should be skipped: %+v", plan.Files)
+	}
+	// 第 1 集已经下载过，两个字幕重命名后同名
+	if len(plan.Problems) != 2 ||
+		!strings.Contains(plan.Files[0].Problem, "已经由") ||
+		!strings.Contains(plan.Files[3].Problem, "同名") {
+		t.Errorf("Problems = %v", plan.Problems)
+	}
+
+	if Plan(&model.TorrentInfo{MagnetURI: "magnet:?xt=urn:btih:abc"}, bangumi, nil) != nil {
+		t.Error("Plan() without files should return nil")
+	}
+}
+
+func TestPlanUnparsableEpisode(t *testing.T) {
+	Init(&model.BangumiRenameConfig{})
+	plan := Plan(&model.TorrentInfo{Files: []model.TorrentFile{{Path: "Frieren/Menu.mkv"}}}, &model.Bangumi{OfficialTitle: "葬送的芙莉莲", Season: 1}, nil)
+	if plan == nil || plan.Collection || len(plan.Problems) != 1 || plan.Files[0].Episode != -1 || plan.Files[0].Target != "" {
+		t.Errorf("Plan() = %+v, want the unparsable file flagged", plan)
+	}
+}
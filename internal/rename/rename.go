@@ -2,15 +2,12 @@ package rename
 
 import (
 	"context"
-	"fmt"
 	"log/slog"
 	"path/filepath"
 
 	"goto-bangumi/internal/database"
 	"goto-bangumi/internal/download"
 	"goto-bangumi/internal/model"
-	"goto-bangumi/internal/network"
-	"goto-bangumi/internal/notification"
 	"goto-bangumi/internal/parser"
 )
 
@@ -32,70 +29,66 @@ func Init(cfg *model.BangumiRenameConfig) {
 
 // Renamer 封装重命名相关操作
 type Renamer struct {
-	db         *database.DB
-	downloader *download.DownloadClient
+	db          *database.DB
+	downloaders *download.Manager
 }
 
 // New 创建 Renamer 实例
-func New(db *database.DB, dl *download.DownloadClient) *Renamer {
-	return &Renamer{db: db, downloader: dl}
+func New(db *database.DB, dls *download.Manager) *Renamer {
+	return &Renamer{db: db, downloaders: dls}
 }
 
 func (r *Renamer) GetBangumi(ctx context.Context, torrent *model.Torrent) (*model.Bangumi, error) {
 	return r.getBangumi(ctx, torrent)
 }
 
-func (r *Renamer) Rename(ctx context.Context, torrent *model.Torrent, bangumi *model.Bangumi) {
+// Rename 重命名种子内的剧集文件，返回成功重命名的集数
+// 通知不在这里发送，由 taskrunner 的 Notifying 阶段统一处理
+func (r *Renamer) Rename(ctx context.Context, torrent *model.Torrent, bangumi *model.Bangumi) []int {
 	// 如果 bangumi 为空, 则从 torrent 中获取 bangumi 信息
 	if bangumi == nil {
 		var err error
 		bangumi, err = r.getBangumi(ctx, torrent)
 		if err != nil {
-			return
+			return nil
 		}
 	}
-	fileList, err := r.downloader.GetTorrentFiles(ctx, torrent.DownloadUID)
+	// 种子在哪个下载器里就在哪个下载器里重命名
+	dl := r.downloaders.Get(torrent.Downloader)
+	fileList, err := dl.GetTorrentFiles(ctx, torrent.DownloadUID)
 	if err != nil {
-		return
+		return nil
 	}
 
+	var episodes []int
 	for _, filePath := range fileList {
 		// 从 file_path 中提取出文件名, 通过 filepath
 		torrentName := filepath.Base(filePath)
+		// 跳过文件筛选规则排除的文件，不支持跳过文件的下载器会把它们一起下载下来
+		if !download.WantedPath(filePath) {
+			slog.Debug("[rename] Skip renaming for unselected file", "file", filePath)
+			continue
+		}
 		// 跳过 0.5 集的文件
 		if parser.IsPoint5(torrentName) {
 			slog.Debug("[rename] Skip renaming for 0.5 episode file", "file", torrentName)
 			continue
 		}
 		metaInfo, newPath := GenPath(torrentName, bangumi)
+		if metaInfo == nil {
+			continue
+		}
 		if newPath == filePath {
 			slog.Debug("[rename] File path is the same, no need to rename", "path", filePath)
 			continue
 		}
 
 		// 也不用想着要加速什么的, 慢慢来就好了, 主要的还是 api 调用的时间
-		// err := rename(ctx, torrent.DownloadUID, filePath, newPath)
-		if err := r.downloader.Rename(ctx, torrent.DownloadUID, filePath, newPath); err != nil {
+		if err := dl.Rename(ctx, torrent.DownloadUID, filePath, newPath); err != nil {
 			slog.Error("[rename] Failed to rename file", "oldpath", filePath, "newpath", newPath, "error", err)
-			return
+			return episodes
 		}
-
-		// 发送改名成功通知
-		text := fmt.Sprintf("番剧名称：%s\n季度：第%d季\n更新集数：第%d集",
-			bangumi.OfficialTitle, bangumi.Season, metaInfo.Episode)
-
-		var image []byte
-		if bangumi.PosterLink != "" {
-			image, err = network.LoadImage(ctx, bangumi.PosterLink)
-			if err != nil {
-				slog.Error("[rename] Failed to download poster", "error", err)
-			}
-		}
-
-		notification.NotificationClient.Send(ctx, &notification.Message{
-			Text:  text,
-			Image: image,
-		})
+		episodes = append(episodes, metaInfo.Episode+bangumi.Offset)
 	}
+	return episodes
 }
-
@@ -34,7 +34,7 @@ func TestRename_SingleFile(t *testing.T) {
 	})
 
 	dlClient := setupMockClient()
-	r := New(nil, dlClient)
+	r := New(nil, download.NewManagerWith(dlClient))
 
 	torrent := &model.Torrent{
 		DownloadUID: "1317e47882474c771e29ed2271b282fbfb56e7d2",
@@ -72,7 +72,7 @@ func TestRename_SingleFileWithYearAndGroup(t *testing.T) {
 	})
 
 	dlClient := setupMockClient()
-	r := New(nil, dlClient)
+	r := New(nil, download.NewManagerWith(dlClient))
 
 	torrent := &model.Torrent{
 		DownloadUID: "1317e47882474c771e29ed2271b282fbfb56e7d2",
@@ -108,7 +108,7 @@ func TestRename_MultipleFiles(t *testing.T) {
 	})
 
 	dlClient := setupMockClient()
-	r := New(nil, dlClient)
+	r := New(nil, download.NewManagerWith(dlClient))
 
 	torrent := &model.Torrent{
 		DownloadUID: "e0a951e431269be7b556101447fbdf9d0842d72f",
@@ -165,14 +165,14 @@ func TestRename_WithOffset(t *testing.T) {
 
 	// 通过 Add 方法添加自定义种子
 	hash := "abc123test"
-	mockDownloader.AddMockTorrent(hash, &model.TorrentDownloadInfo{
+	mockDownloader.AddMockTorrent(hash, &model.TorrentStatus{
 		SavePath:  "转生贵族靠鉴定技能一飞冲天/Season 2",
 		Completed: 1,
 	}, []string{
 		"[ANi] 转生贵族靠鉴定技能一飞冲天 - 14 [1080P][Baha][WEB-DL][AAC AVC][CHT].mp4",
 	})
 
-	r := New(nil, dlClient)
+	r := New(nil, download.NewManagerWith(dlClient))
 	torrent := &model.Torrent{
 		DownloadUID: hash,
 		Name:        "[ANi] 转生贵族靠鉴定技能一飞冲天 - 14 [1080P][Baha][WEB-DL][AAC AVC][CHT].mp4",
@@ -205,7 +205,7 @@ func TestRename_NilBangumi_FallbackToGetBangumi(t *testing.T) {
 	})
 
 	dlClient := setupMockClient()
-	r := New(nil, dlClient)
+	r := New(nil, download.NewManagerWith(dlClient))
 
 	torrent := &model.Torrent{
 		DownloadUID: "1317e47882474c771e29ed2271b282fbfb56e7d2",
@@ -248,12 +248,12 @@ func TestRename_SkipSameFilename(t *testing.T) {
 	// 文件名已经是目标格式
 	alreadyRenamed := "败犬女主太多了 S01E02.mp4"
 	hash := "skip_same_test"
-	mockDownloader.AddMockTorrent(hash, &model.TorrentDownloadInfo{
+	mockDownloader.AddMockTorrent(hash, &model.TorrentStatus{
 		SavePath:  "败犬女主太多了/Season 1",
 		Completed: 1,
 	}, []string{alreadyRenamed})
 
-	r := New(nil, dlClient)
+	r := New(nil, download.NewManagerWith(dlClient))
 	torrent := &model.Torrent{
 		DownloadUID: hash,
 		Name:        alreadyRenamed,
@@ -351,12 +351,12 @@ func TestRename_MixedSubGroups(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			mockDownloader.AddMockTorrent(tt.hash, &model.TorrentDownloadInfo{
+			mockDownloader.AddMockTorrent(tt.hash, &model.TorrentStatus{
 				SavePath:  tt.bangumi.OfficialTitle + "/Season 1",
 				Completed: 1,
 			}, []string{tt.file})
 
-			r := New(nil, dlClient)
+			r := New(nil, download.NewManagerWith(dlClient))
 			torrent := &model.Torrent{
 				DownloadUID: tt.hash,
 				Name:        tt.file,
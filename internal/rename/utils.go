@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log/slog"
 	"path/filepath"
+	"strings"
 
 	"goto-bangumi/internal/model"
 	"goto-bangumi/internal/parser"
@@ -12,7 +13,8 @@ import (
 
 func (r *Renamer) getBangumi(ctx context.Context, torrent *model.Torrent) (*model.Bangumi, error) {
 	// 从 download 中拿到下载文件的目录信息
-	downloadInfo, err := r.downloader.GetTorrentInfo(ctx, torrent.DownloadUID)
+	dl := r.downloaders.Get(torrent.Downloader)
+	downloadInfo, err := dl.GetTorrentInfo(ctx, torrent.DownloadUID)
 	if err != nil {
 		slog.Error("[rename] Failed to get torrent download info", "name", torrent.Name, "error", err)
 		return nil, err
@@ -20,7 +22,12 @@ func (r *Renamer) getBangumi(ctx context.Context, torrent *model.Torrent) (*mode
 	savePath := downloadInfo.SavePath
 	// 从 savePath 提取出 bangumi 的名字和季度 以及 可能存在的年份 组成为 savePath/BangumiName (Year)/Season \d
 	// 首先提取一个相对路径, 拿到最后的 BangumiName (Year)/Season \d, 以 downloader.SavePath 为基准
-	relativePath, err := filepath.Rel(r.downloader.SavePath, savePath)
+	// 已经转移到媒体库的种子以 MediaPath 为基准，两个目录嵌套时取更深的一个
+	base := dl.SavePath
+	if dl.MediaPath != "" && isSubPath(dl.MediaPath, savePath) && (!isSubPath(base, savePath) || isSubPath(base, dl.MediaPath)) {
+		base = dl.MediaPath
+	}
+	relativePath, err := filepath.Rel(base, savePath)
 	if err != nil {
 		slog.Error("[rename] Failed to get relative path", "name", torrent.Name, "path", savePath, "error", err)
 		return nil, err
@@ -56,14 +63,17 @@ func (r *Renamer) getBangumi(ctx context.Context, torrent *model.Torrent) (*mode
 // GenPath 生成新的文件路径,形如 败犬女主太多了 (2024) S01E02 - Ani.mp4
 func GenPath(torrentName string, bangumi *model.Bangumi) (*model.EpisodeMetadata, string) {
 	metaInfo := parser.NewTitleMetaParse().Parse(torrentName)
-	episode := metaInfo.Episode
-	if episode == -1 {
+	if metaInfo.Episode == -1 {
 		slog.Error("[rename] Failed to parse episode from torrent name", "torrentName", torrentName)
 		return nil, ""
 	}
+	return metaInfo, buildPath(torrentName, metaInfo, bangumi)
+}
 
+// buildPath 根据解析出的剧集信息拼出新的文件名
+func buildPath(torrentName string, metaInfo *model.EpisodeMetadata, bangumi *model.Bangumi) string {
 	// offset, 默认是0
-	episode += bangumi.Offset
+	episode := metaInfo.Episode + bangumi.Offset
 
 	// 获取文件扩展名
 	ext := filepath.Ext(torrentName)
@@ -87,5 +97,11 @@ func GenPath(torrentName string, bangumi *model.Bangumi) (*model.EpisodeMetadata
 	// 添加文件扩展名
 	newPath += ext
 	// TODO: 字幕文件还要加 chs, cht 等标识
-	return metaInfo, newPath
+	return newPath
+}
+
+// isSubPath 判断 p 是否在 dir 目录下
+func isSubPath(dir, p string) bool {
+	rel, err := filepath.Rel(filepath.Clean(dir), filepath.Clean(p))
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
 }
@@ -264,7 +264,7 @@ func TestGetBangumi(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			r := New(nil, dlClient)
+			r := New(nil, download.NewManagerWith(dlClient))
 			bangumi, err := r.GetBangumi(context.Background(), tt.torrent)
 
 			if (err != nil) != tt.wantErr {
@@ -0,0 +1,242 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"goto-bangumi/internal/database"
+	"goto-bangumi/internal/download"
+	"goto-bangumi/internal/model"
+	"goto-bangumi/internal/taskrunner"
+)
+
+// ReconcileItem 对账报告中的一个种子
+type ReconcileItem struct {
+	Downloader string `json:"downloader"`
+	Hash       string `json:"hash"`
+	Name       string `json:"name"`
+	Link       string `json:"link,omitempty"`
+}
+
+// ReconcileReport 一次对账的结果
+type ReconcileReport struct {
+	Time        time.Time       `json:"time"`
+	Checked     int             `json:"checked"`     // 检查的数据库记录数
+	Resubmitted []ReconcileItem `json:"resubmitted"` // 已完成但未重命名，重新提交了重命名任务
+	Missing     []ReconcileItem `json:"missing"`     // 下载器中已不存在，标记为异常
+	Readded     []ReconcileItem `json:"readded"`     // 下载器中已不存在但缓存了种子文件，重新提交了添加任务
+	Orphans     []ReconcileItem `json:"orphans"`     // 下载器中有但数据库里没有记录
+	Errors      []string        `json:"errors,omitempty"`
+}
+
+// ReconcileTask 对账任务
+// 周期性地比较数据库和下载器中的种子：补交已完成未重命名的种子，标记下载器中丢失的种子，报告没有记录的种子
+type ReconcileTask struct {
+	interval    time.Duration
+	config      model.ReconcileConfig
+	runner      *taskrunner.TaskRunner
+	db          *database.DB
+	downloaders *download.Manager
+
+	running sync.Mutex // 保证同一时间只有一次对账
+	mu      sync.Mutex // 保护 report
+	report  *ReconcileReport
+}
+
+// NewReconcileTask 创建对账任务
+func NewReconcileTask(config model.ReconcileConfig, runner *taskrunner.TaskRunner, db *database.DB, downloaders *download.Manager) *ReconcileTask {
+	interval := time.Duration(config.Interval) * time.Second
+	if interval <= 0 {
+		interval = 30 * time.Minute
+	}
+	return &ReconcileTask{
+		interval:    interval,
+		config:      config,
+		runner:      runner,
+		db:          db,
+		downloaders: downloaders,
+	}
+}
+
+// Name 返回任务名称
+func (t *ReconcileTask) Name() string {
+	return "下载器对账任务"
+}
+
+// Interval 返回执行间隔
+func (t *ReconcileTask) Interval() time.Duration {
+	return t.interval
+}
+
+// Enable 返回是否启用
+func (t *ReconcileTask) Enable() bool {
+	return t.config.Enable
+}
+
+// Run 执行一次对账
+func (t *ReconcileTask) Run(ctx context.Context) error {
+	report, err := t.Reconcile(ctx)
+	if err != nil {
+		return err
+	}
+	if len(report.Errors) > 0 {
+		return errors.New(strings.Join(report.Errors, "; "))
+	}
+	return nil
+}
+
+// Report 返回最近一次对账的结果，还没有对账过时返回 nil
+func (t *ReconcileTask) Report() *ReconcileReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.report
+}
+
+// Reconcile 执行一次对账并保存报告，单个下载器失败记录在报告里，不影响其他下载器
+func (t *ReconcileTask) Reconcile(ctx context.Context) (*ReconcileReport, error) {
+	t.running.Lock()
+	defer t.running.Unlock()
+
+	torrents, err := t.db.FindSentTorrents(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// 已经进入死信的种子等待手动重投，不再自动补交
+	deadLetters, err := t.db.DeadLetterLinks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// 按种子所在的下载器分组，所有下载器都要检查孤儿种子
+	groups := make(map[*download.DownloadClient][]*model.Torrent)
+	for _, client := range t.downloaders.Clients() {
+		groups[client] = nil
+	}
+	for _, torrent := range torrents {
+		if client := t.downloaders.Get(torrent.Downloader); client != nil {
+			groups[client] = append(groups[client], torrent)
+		}
+	}
+
+	report := &ReconcileReport{Time: time.Now()}
+	for client, group := range groups {
+		if err := t.reconcile(ctx, client, group, deadLetters, report); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", client.Name, err))
+		}
+	}
+	slog.Info("[task reconcile] 对账完成",
+		"checked", report.Checked,
+		"resubmitted", len(report.Resubmitted),
+		"missing", len(report.Missing),
+		"readded", len(report.Readded),
+		"orphans", len(report.Orphans),
+		"errors", len(report.Errors))
+	t.mu.Lock()
+	t.report = report
+	t.mu.Unlock()
+	return report, nil
+}
+
+// reconcile 对账一个下载器
+func (t *ReconcileTask) reconcile(ctx context.Context, client *download.DownloadClient, torrents []*model.Torrent, deadLetters map[string]bool, report *ReconcileReport) error {
+	entries, err := client.TorrentsInfo(ctx, "", client.Category, nil, 0)
+	if err != nil {
+		return err
+	}
+
+	known := make(map[string]bool, len(torrents))
+	var pending []*model.Torrent
+	for _, torrent := range torrents {
+		known[strings.ToLower(torrent.DownloadUID)] = true
+		// 正在执行的任务由任务自己处理，进入死信的等待重投，做种结束或已出错的不再检查
+		if t.runner.Has(torrent.Link) || deadLetters[torrent.Link] {
+			continue
+		}
+		if torrent.Downloaded == model.DownloadSending || (torrent.Downloaded == model.DownloadDone && !torrent.Renamed) {
+			pending = append(pending, torrent)
+		}
+	}
+
+	for _, entry := range entries {
+		if entry.Hash == "" || known[strings.ToLower(entry.Hash)] || !ownedEntry(entry, client.Category) {
+			continue
+		}
+		report.Orphans = append(report.Orphans, ReconcileItem{Downloader: client.Name, Hash: entry.Hash, Name: entry.Name})
+	}
+
+	if len(pending) == 0 {
+		return nil
+	}
+	hashes := make([]string, 0, len(pending))
+	for _, torrent := range pending {
+		hashes = append(hashes, torrent.DownloadUID)
+	}
+	infos, err := client.GetTorrentsInfo(ctx, hashes)
+	if err != nil {
+		return err
+	}
+
+	for _, torrent := range pending {
+		report.Checked++
+		item := ReconcileItem{Downloader: client.Name, Hash: torrent.DownloadUID, Name: torrent.Name, Link: torrent.Link}
+		info, ok := infos[torrent.DownloadUID]
+		if (!ok || info == nil) && t.readd(torrent) {
+			report.Readded = append(report.Readded, item)
+			continue
+		}
+		if !ok || info == nil {
+			slog.Warn("[task reconcile] 下载器中已不存在，标记为异常", "torrent", torrent.Name, "downloader", client.Name)
+			if err := t.db.AddTorrentError(ctx, torrent.Link); err != nil {
+				return err
+			}
+			report.Missing = append(report.Missing, item)
+			continue
+		}
+		if info.Completed <= 0 {
+			continue
+		}
+
+		if torrent.Downloaded != model.DownloadDone {
+			if err := t.db.AddTorrentDownload(ctx, torrent.Link); err != nil {
+				return err
+			}
+			torrent.Downloaded = model.DownloadDone
+		}
+		if torrent.Bangumi == nil {
+			slog.Warn("[task reconcile] 种子没有关联番剧，无法重命名", "torrent", torrent.Name)
+			continue
+		}
+		if t.runner.Submit(model.NewRenameTask(torrent, torrent.Bangumi)) {
+			slog.Info("[task reconcile] 已完成未重命名，重新提交重命名任务", "torrent", torrent.Name)
+			report.Resubmitted = append(report.Resubmitted, item)
+		}
+	}
+	return nil
+}
+
+// readd 下载中的种子从下载器里消失时，用缓存的种子文件重新添加
+// 已完成的种子文件可能已经被整理过，重新下载没有意义，仍然标记为异常
+func (t *ReconcileTask) readd(torrent *model.Torrent) bool {
+	if torrent.Downloaded != model.DownloadSending || torrent.Bangumi == nil || !download.HasCachedTorrent(torrent.InfoHash) {
+		return false
+	}
+	if !t.runner.Submit(model.NewAddTask(torrent, torrent.Bangumi)) {
+		return false
+	}
+	slog.Info("[task reconcile] 下载器中已不存在，使用缓存的种子文件重新添加", "torrent", torrent.Name)
+	return true
+}
+
+// ownedEntry 判断下载器中的种子是否由 goto-bangumi 添加
+// 有标签的下载器看种子链接标签，Deluge 看 Label 插件的标签是否为配置的分类
+// 既没有标签也没有分类的下载器（内置、网盘离线等）无法区分，一律不算，避免把别人的任务报告为孤儿
+func ownedEntry(entry *model.TorrentStatus, category string) bool {
+	if entry.HasTag(download.LinkTagPrefix) {
+		return true
+	}
+	return entry.Tags == nil && category != "" && strings.EqualFold(entry.Category, category)
+}
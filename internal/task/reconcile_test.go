@@ -0,0 +1,144 @@
+package task
+
+import (
+	"context"
+	"testing"
+
+	"goto-bangumi/internal/database"
+	"goto-bangumi/internal/download"
+	"goto-bangumi/internal/download/downloader"
+	"goto-bangumi/internal/model"
+	"goto-bangumi/internal/taskrunner"
+)
+
+// reconcileDownloader 返回固定的种子列表和下载状态
+type reconcileDownloader struct {
+	*downloader.MockDownloader
+	entries  []*model.TorrentStatus
+	infos    map[string]*model.TorrentStatus
+	category string // 最近一次列出种子时的分类
+}
+
+func (d *reconcileDownloader) TorrentsInfo(ctx context.Context, statusFilter, category string, tag *string, limit int) ([]*model.TorrentStatus, error) {
+	d.category = category
+	return d.entries, nil
+}
+
+func (d *reconcileDownloader) GetTorrentsInfo(ctx context.Context, hashes []string) (map[string]*model.TorrentStatus, error) {
+	result := make(map[string]*model.TorrentStatus)
+	for _, hash := range hashes {
+		if info, ok := d.infos[hash]; ok {
+			result[hash] = info
+		}
+	}
+	return result, nil
+}
+
+func TestReconcileTask_Reconcile(t *testing.T) {
+	testdb := ":memory:"
+	db, err := database.NewDB(&testdb)
+	if err != nil {
+		t.Fatalf("NewDB: %v", err)
+	}
+	ctx := context.Background()
+	bangumi := &model.Bangumi{OfficialTitle: "葬送的芙莉莲", Season: 1}
+	if err := db.CreateBangumi(bangumi); err != nil {
+		t.Fatalf("CreateBangumi: %v", err)
+	}
+	torrents := []*model.Torrent{
+		// 离线期间下载完成
+		{Link: "link-finished", DownloadUID: "hash-finished", Downloaded: model.DownloadSending, BangumiID: bangumi.ID},
+		// 在下载器中被手动删除
+		{Link: "link-deleted", DownloadUID: "hash-deleted", Downloaded: model.DownloadSending, BangumiID: bangumi.ID},
+		{Link: "link-downloading", DownloadUID: "hash-downloading", Downloaded: model.DownloadSending, BangumiID: bangumi.ID},
+		{Link: "link-renamed", DownloadUID: "hash-renamed", Downloaded: model.DownloadDone, Renamed: true, BangumiID: bangumi.ID},
+		// 下载中丢失，缓存了种子文件
+		{Link: "link-lost", DownloadUID: "hash-lost", InfoHash: "0123abcd", Downloaded: model.DownloadSending, BangumiID: bangumi.ID},
+		// 重命名失败进入了死信
+		{Link: "link-dead", DownloadUID: "hash-dead", Downloaded: model.DownloadDone, BangumiID: bangumi.ID},
+	}
+	cacheDir := t.TempDir()
+	download.InitTorrentCache(cacheDir)
+	defer download.InitTorrentCache("")
+	if err := download.NewTorrentCache(cacheDir).Save("0123abcd", []byte("d4:infoe")); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	for _, torrent := range torrents {
+		if err := db.CreateTorrent(ctx, torrent); err != nil {
+			t.Fatalf("CreateTorrent: %v", err)
+		}
+	}
+
+	if err := db.SaveDeadLetter(ctx, &model.DeadLetter{TorrentLink: "link-dead", BangumiID: bangumi.ID, Phase: model.PhaseRenaming}); err != nil {
+		t.Fatalf("SaveDeadLetter: %v", err)
+	}
+
+	dl := &reconcileDownloader{
+		MockDownloader: downloader.NewMockDownloader(),
+		entries: []*model.TorrentStatus{
+			{Hash: "HASH-FINISHED", Tags: []string{"葬送的芙莉莲", download.LinkTag("link-finished")}},
+			{Hash: "hash-downloading", Tags: []string{download.LinkTag("link-downloading")}},
+			{Hash: "hash-renamed", Tags: []string{}},
+			{Hash: "hash-orphan", Name: "orphan", Tags: []string{"S01", download.LinkTag("link-orphan")}},
+			{Hash: "hash-other", Name: "not ours", Tags: []string{"movie"}},
+			// 不支持标签的下载器，只有分类相同的才算自己添加的
+			{Hash: "hash-labeled", Name: "labeled", Category: "gotobangumi"},
+			{Hash: "hash-untagged", Name: "unknown"},
+		},
+		infos: map[string]*model.TorrentStatus{
+			"hash-dead":        {Completed: 1},
+			"hash-finished":    {Completed: 1},
+			"hash-downloading": {ETA: 60},
+		},
+	}
+	client := download.NewDownloadClient()
+	client.Downloader = dl
+	client.Category = "GotoBangumi"
+	runner := taskrunner.New(1, 1)
+
+	task := NewReconcileTask(model.ReconcileConfig{Enable: true}, runner, db, download.NewManagerWith(client))
+	report, err := task.Reconcile(ctx)
+	if err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+
+	if len(report.Resubmitted) != 1 || report.Resubmitted[0].Link != "link-finished" || !runner.Has("link-finished") {
+		t.Errorf("Resubmitted = %+v, want link-finished", report.Resubmitted)
+	}
+	if len(report.Missing) != 1 || report.Missing[0].Link != "link-deleted" {
+		t.Errorf("Missing = %+v, want link-deleted", report.Missing)
+	}
+	if len(report.Readded) != 1 || report.Readded[0].Link != "link-lost" || !runner.Has("link-lost") {
+		t.Errorf("Readded = %+v, want link-lost", report.Readded)
+	}
+	if len(report.Orphans) != 2 || report.Orphans[0].Hash != "hash-orphan" || report.Orphans[1].Hash != "hash-labeled" {
+		t.Errorf("Orphans = %+v, want hash-orphan and hash-labeled", report.Orphans)
+	}
+	if dl.category != "GotoBangumi" {
+		t.Errorf("listed category = %q, want the configured category", dl.category)
+	}
+	if runner.Has("link-dead") {
+		t.Error("torrent with a dead letter should not be resubmitted")
+	}
+	if task.Report() != report {
+		t.Error("Report() should return the last report")
+	}
+
+	finished, _ := db.GetTorrentByURL(ctx, "link-finished")
+	if finished.Downloaded != model.DownloadDone {
+		t.Errorf("finished torrent downloaded = %v, want DownloadDone", finished.Downloaded)
+	}
+	deleted, _ := db.GetTorrentByURL(ctx, "link-deleted")
+	if deleted.Downloaded != model.DownloadError {
+		t.Errorf("deleted torrent downloaded = %v, want DownloadError", deleted.Downloaded)
+	}
+
+	// 已经有任务在执行的种子不会重复提交
+	again, err := task.Reconcile(ctx)
+	if err != nil {
+		t.Fatalf("second Reconcile: %v", err)
+	}
+	if len(again.Resubmitted) != 0 || len(again.Missing) != 0 || len(again.Readded) != 0 {
+		t.Errorf("second Reconcile = %+v, want nothing to fix", again)
+	}
+}
@@ -0,0 +1,91 @@
+package task
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"goto-bangumi/internal/database"
+	"goto-bangumi/internal/model"
+	"goto-bangumi/internal/taskrunner"
+)
+
+// DeadLetterRedriveTask 死信重投任务
+// 周期性地把标记为待重投的死信重新提交到 runner，命令行标记的死信由它接手
+type DeadLetterRedriveTask struct {
+	interval time.Duration
+	runner   *taskrunner.TaskRunner
+	db       *database.DB
+}
+
+// NewDeadLetterRedriveTask 创建死信重投任务
+func NewDeadLetterRedriveTask(runner *taskrunner.TaskRunner, db *database.DB) *DeadLetterRedriveTask {
+	return &DeadLetterRedriveTask{
+		interval: time.Minute,
+		runner:   runner,
+		db:       db,
+	}
+}
+
+// Name 返回任务名称
+func (t *DeadLetterRedriveTask) Name() string {
+	return "死信重投任务"
+}
+
+// Interval 返回执行间隔
+func (t *DeadLetterRedriveTask) Interval() time.Duration {
+	return t.interval
+}
+
+// Enable 返回是否启用
+func (t *DeadLetterRedriveTask) Enable() bool {
+	return true
+}
+
+// Run 重投所有待重投的死信
+func (t *DeadLetterRedriveTask) Run(ctx context.Context) error {
+	_, err := RedriveDeadLetters(ctx, t.db, t.runner)
+	return err
+}
+
+// RedriveDeadLetters 把待重投的死信恢复成任务，从失败的阶段继续执行，返回提交的任务数
+// 提交后删除死信，任务再次失败时会重新写入
+func RedriveDeadLetters(ctx context.Context, db *database.DB, runner *taskrunner.TaskRunner) (int, error) {
+	letters, err := db.ListRedriveDeadLetters(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	submitted := 0
+	for _, letter := range letters {
+		torrent, err := db.GetTorrentWithDetails(ctx, letter.TorrentLink)
+		if err != nil {
+			slog.Warn("[task redrive] 种子不存在，丢弃死信", "link", letter.TorrentLink, "error", err)
+			db.DeleteDeadLetter(ctx, letter.ID)
+			continue
+		}
+		bangumi := torrent.Bangumi
+		if bangumi == nil {
+			bangumi, err = db.GetBangumiByID(letter.BangumiID)
+			if err != nil {
+				slog.Warn("[task redrive] 番剧不存在，丢弃死信", "link", letter.TorrentLink, "error", err)
+				db.DeleteDeadLetter(ctx, letter.ID)
+				continue
+			}
+		}
+
+		task := model.NewPhaseTask(torrent, bangumi, letter.Phase)
+		task.Guids = letter.Guids
+		task.RenamedEpisodes = letter.Episodes
+		if runner.Submit(task) {
+			submitted++
+			slog.Info("[task redrive] 重投死信", "torrent", torrent.Name, "phase", letter.Phase)
+		} else {
+			slog.Info("[task redrive] 种子已有任务在执行，跳过", "torrent", torrent.Name)
+		}
+		if err := db.DeleteDeadLetter(ctx, letter.ID); err != nil {
+			slog.Error("[task redrive] 删除死信失败", "id", letter.ID, "error", err)
+		}
+	}
+	return submitted, nil
+}
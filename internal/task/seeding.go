@@ -0,0 +1,200 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"goto-bangumi/internal/database"
+	"goto-bangumi/internal/download"
+	"goto-bangumi/internal/model"
+	"goto-bangumi/internal/utils"
+)
+
+// SeedingCleanupTask 做种清理任务
+// 周期性地把已完成、已重命名且达到做种限制的种子从下载器中移除
+type SeedingCleanupTask struct {
+	interval    time.Duration
+	config      model.SeedingConfig
+	db          *database.DB
+	downloaders *download.Manager
+}
+
+// NewSeedingCleanupTask 创建做种清理任务
+func NewSeedingCleanupTask(config model.SeedingConfig, db *database.DB, downloaders *download.Manager) *SeedingCleanupTask {
+	interval := time.Duration(config.Interval) * time.Second
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	return &SeedingCleanupTask{
+		interval:    interval,
+		config:      config,
+		db:          db,
+		downloaders: downloaders,
+	}
+}
+
+// Name 返回任务名称
+func (t *SeedingCleanupTask) Name() string {
+	return "做种清理任务"
+}
+
+// Interval 返回执行间隔
+func (t *SeedingCleanupTask) Interval() time.Duration {
+	return t.interval
+}
+
+// Enable 返回是否启用
+func (t *SeedingCleanupTask) Enable() bool {
+	return t.config.Enable
+}
+
+// Run 按做种策略移除种子，单个下载器失败不影响其他下载器
+func (t *SeedingCleanupTask) Run(ctx context.Context) error {
+	torrents, err := t.db.FindSeedingTorrents(ctx)
+	if err != nil {
+		return err
+	}
+	if len(torrents) == 0 {
+		return nil
+	}
+
+	// 按种子所在的下载器分组
+	groups := make(map[*download.DownloadClient][]*model.Torrent)
+	for _, torrent := range torrents {
+		if torrent.DownloadUID == "" {
+			continue
+		}
+		client := t.downloaders.Get(torrent.Downloader)
+		if client == nil {
+			continue
+		}
+		groups[client] = append(groups[client], torrent)
+	}
+
+	var errs []error
+	for client, group := range groups {
+		if err := t.cleanup(ctx, client, group); err != nil {
+			errs = append(errs, fmt.Errorf("[task seeding] 下载器 %s: %w", client.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// cleanup 处理同一个下载器中的种子
+func (t *SeedingCleanupTask) cleanup(ctx context.Context, client *download.DownloadClient, torrents []*model.Torrent) error {
+	infos, err := client.TorrentsInfo(ctx, "completed", "", nil, 0)
+	if err != nil {
+		return err
+	}
+	entries := make(map[string]*model.TorrentStatus, len(infos))
+	for _, info := range infos {
+		if info.Hash != "" {
+			entries[strings.ToLower(info.Hash)] = info
+		}
+	}
+
+	now := time.Now().Unix()
+	for _, torrent := range torrents {
+		entry, ok := entries[strings.ToLower(torrent.DownloadUID)]
+		if !ok {
+			// 不在已完成列表中，可能仍在下载或已被手动删除，下次再看
+			continue
+		}
+		policy := torrent.Bangumi.SeedingPolicy(t.config)
+		ratio, seedingTime := seedingStats(entry, now)
+		if !seedingDone(policy, ratio, seedingTime) {
+			continue
+		}
+
+		deleteFiles := policy.DeleteFiles
+		if deleteFiles {
+			savePath := entry.SavePath
+			if savePath == "" {
+				savePath = client.SavePath
+			}
+			files, err := client.GetTorrentFiles(ctx, torrent.DownloadUID)
+			if err != nil {
+				slog.Warn("[task seeding] 获取文件列表失败，跳过", "torrent", torrent.Name, "error", err)
+				continue
+			}
+			if reason := keepFiles(savePath, client.SavePath, client.MediaPath, files); reason != "" {
+				slog.Info("[task seeding] 保留种子数据", "torrent", torrent.Name, "reason", reason)
+				deleteFiles = false
+			}
+		}
+
+		if err := client.Delete(ctx, []string{torrent.DownloadUID}, deleteFiles); err != nil {
+			slog.Error("[task seeding] 移除种子失败", "torrent", torrent.Name, "error", err)
+			continue
+		}
+		if err := t.db.TorrentRemoved(ctx, torrent.Link); err != nil {
+			slog.Error("[task seeding] 更新种子状态失败", "torrent", torrent.Name, "error", err)
+		}
+		// 做种结束后不会再添加，缓存的种子文件也不再需要
+		download.RemoveCachedTorrent(torrent.InfoHash)
+		slog.Info("[task seeding] 做种结束，已移除种子", "torrent", torrent.Name, "ratio", ratio,
+			"seeding_time", seedingTime, "delete_files", deleteFiles)
+	}
+	return nil
+}
+
+// seedingStats 取出分享率和做种时长（秒），下载器不提供时返回 -1
+// 没有做种时长时用完成时间推算
+func seedingStats(entry *model.TorrentStatus, now int64) (float64, int64) {
+	ratio := -1.0
+	if entry.Ratio >= 0 {
+		ratio = entry.Ratio
+	}
+	seedingTime := int64(-1)
+	if entry.SeedingTime >= 0 {
+		seedingTime = entry.SeedingTime
+	} else if completed := int64(entry.Completed); completed > 1e9 && completed <= now {
+		// 小于这个值的不是时间戳，比如 mock 下载器用 1 表示已完成
+		seedingTime = now - completed
+	}
+	return ratio, seedingTime
+}
+
+// seedingDone 判断是否达到做种限制，任一限制达到即可，限制为 0 时不按这一项移除，两个都为 0 时一直做种
+// 下载器不提供的数据不参与判断
+func seedingDone(policy model.SeedingConfig, ratio float64, seedingTime int64) bool {
+	if policy.RatioLimit > 0 && ratio >= 0 && ratio >= policy.RatioLimit {
+		return true
+	}
+	if policy.SeedingTime > 0 && seedingTime >= 0 && seedingTime >= int64(policy.SeedingTime)*60 {
+		return true
+	}
+	return false
+}
+
+// keepFiles 判断移除种子时是否必须保留数据，返回保留的原因
+// 文件已移动到媒体库、存在硬链接或者无法在本地确认时都保留，宁可多占空间也不能删掉媒体库里的文件
+func keepFiles(savePath, downloadPath, mediaPath string, files []string) string {
+	if mediaPath != "" && filepath.Clean(mediaPath) != filepath.Clean(downloadPath) && isSubPath(mediaPath, savePath) {
+		return "已移动到媒体库"
+	}
+	if len(files) == 0 {
+		return "没有文件列表"
+	}
+	for _, f := range files {
+		n, err := utils.LinkCount(filepath.Join(savePath, f))
+		if err != nil {
+			return "无法读取本地文件"
+		}
+		if n > 1 {
+			return "文件已硬链接"
+		}
+	}
+	return ""
+}
+
+// isSubPath 判断 p 是否在 dir 目录下
+func isSubPath(dir, p string) bool {
+	rel, err := filepath.Rel(filepath.Clean(dir), filepath.Clean(p))
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,160 @@
+package task
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"goto-bangumi/internal/database"
+	"goto-bangumi/internal/download"
+	"goto-bangumi/internal/download/downloader"
+	"goto-bangumi/internal/model"
+)
+
+// seedingDownloader 返回固定的种子列表并记录删除操作
+type seedingDownloader struct {
+	*downloader.MockDownloader
+	entries []*model.TorrentStatus
+	files   map[string][]string
+	deleted map[string]bool // hash -> deleteFiles
+}
+
+func (d *seedingDownloader) TorrentsInfo(ctx context.Context, statusFilter, category string, tag *string, limit int) ([]*model.TorrentStatus, error) {
+	return d.entries, nil
+}
+
+func (d *seedingDownloader) GetTorrentFiles(ctx context.Context, hash string) ([]string, error) {
+	return d.files[hash], nil
+}
+
+func (d *seedingDownloader) Delete(ctx context.Context, hashes []string, deleteFiles bool) (bool, error) {
+	for _, h := range hashes {
+		d.deleted[h] = deleteFiles
+	}
+	return true, nil
+}
+
+func TestSeedingCleanupTask_Run(t *testing.T) {
+	savePath := t.TempDir()
+	os.WriteFile(filepath.Join(savePath, "a.mkv"), []byte("a"), 0o644)
+	os.WriteFile(filepath.Join(savePath, "b.mkv"), []byte("b"), 0o644)
+	// b.mkv 已硬链接到媒体库
+	if err := os.Link(filepath.Join(savePath, "b.mkv"), filepath.Join(t.TempDir(), "b.mkv")); err != nil {
+		t.Skipf("hardlink not supported: %v", err)
+	}
+
+	testdb := ":memory:"
+	db, err := database.NewDB(&testdb)
+	if err != nil {
+		t.Fatalf("NewDB: %v", err)
+	}
+	ctx := context.Background()
+	// 番剧策略只按做种时长，完成一天后移除
+	noRatio, oneDay := 0.0, 24*60
+	bangumi := &model.Bangumi{OfficialTitle: "葬送的芙莉莲", Season: 1, SeedRatioLimit: &noRatio, SeedingTime: &oneDay}
+	if err := db.CreateBangumi(bangumi); err != nil {
+		t.Fatalf("CreateBangumi: %v", err)
+	}
+	torrents := []*model.Torrent{
+		{Link: "link-ratio", DownloadUID: "hash-ratio", Downloaded: model.DownloadDone, Renamed: true},
+		{Link: "link-low", DownloadUID: "hash-low", Downloaded: model.DownloadDone, Renamed: true},
+		{Link: "link-linked", DownloadUID: "hash-linked", Downloaded: model.DownloadDone, Renamed: true},
+		{Link: "link-bangumi", DownloadUID: "hash-bangumi", Downloaded: model.DownloadDone, Renamed: true, BangumiID: bangumi.ID},
+		{Link: "link-unrenamed", DownloadUID: "hash-unrenamed", Downloaded: model.DownloadDone},
+	}
+	for _, torrent := range torrents {
+		if err := db.CreateTorrent(ctx, torrent); err != nil {
+			t.Fatalf("CreateTorrent: %v", err)
+		}
+	}
+
+	dl := &seedingDownloader{
+		MockDownloader: downloader.NewMockDownloader(),
+		entries: []*model.TorrentStatus{
+			{Hash: "HASH-RATIO", Ratio: 2.5, SeedingTime: 60, SavePath: savePath},
+			{Hash: "hash-low", Ratio: 0.5, SeedingTime: 60, SavePath: savePath},
+			{Hash: "hash-linked", Ratio: 3.0, SeedingTime: -1, SavePath: savePath},
+			{Hash: "hash-bangumi", Ratio: 0.1, SeedingTime: -1, Completed: int(time.Now().Add(-48 * time.Hour).Unix()), SavePath: savePath},
+			{Hash: "hash-unrenamed", Ratio: 9.0, SeedingTime: -1, SavePath: savePath},
+		},
+		files: map[string][]string{
+			"hash-ratio":   {"a.mkv"},
+			"hash-linked":  {"a.mkv", "b.mkv"},
+			"hash-bangumi": {"a.mkv"},
+		},
+		deleted: map[string]bool{},
+	}
+	dl.Init(&model.DownloaderConfig{})
+	client := download.NewDownloadClient()
+	client.Downloader = dl
+	client.SavePath = savePath
+	client.MediaPath = savePath
+
+	cfg := model.SeedingConfig{Enable: true, RatioLimit: 2, SeedingTime: 0, DeleteFiles: true}
+	task := NewSeedingCleanupTask(cfg, db, download.NewManagerWith(client))
+	if err := task.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	want := map[string]bool{"hash-ratio": true, "hash-linked": false, "hash-bangumi": true}
+	if len(dl.deleted) != len(want) {
+		t.Fatalf("deleted = %v, want %v", dl.deleted, want)
+	}
+	for hash, deleteFiles := range want {
+		if got, ok := dl.deleted[hash]; !ok || got != deleteFiles {
+			t.Errorf("deleted[%s] = %v, %v, want %v", hash, got, ok, deleteFiles)
+		}
+	}
+
+	remaining, _ := db.FindSeedingTorrents(ctx)
+	if len(remaining) != 1 || remaining[0].Link != "link-low" {
+		t.Errorf("FindSeedingTorrents after Run = %v", remaining)
+	}
+	removed, _ := db.GetTorrentByURL(ctx, "link-ratio")
+	if removed == nil || removed.Downloaded != model.DownloadRemoved {
+		t.Errorf("torrent after Run = %+v, want DownloadRemoved", removed)
+	}
+}
+
+func TestSeedingDone(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      model.SeedingConfig
+		ratio       float64
+		seedingTime int64
+		want        bool
+	}{
+		{"ratio reached", model.SeedingConfig{RatioLimit: 2}, 2.5, 0, true},
+		{"ratio not reached", model.SeedingConfig{RatioLimit: 2}, 1, 1 << 30, false},
+		{"seeding time reached", model.SeedingConfig{SeedingTime: 60}, 0, 3600, true},
+		{"unknown ratio", model.SeedingConfig{RatioLimit: 2}, -1, 3600, false},
+		{"both limits disabled", model.SeedingConfig{}, 10, 1 << 30, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := seedingDone(tt.policy, tt.ratio, tt.seedingTime); got != tt.want {
+				t.Errorf("seedingDone() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeepFiles(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.mkv"), []byte("a"), 0o644)
+
+	if reason := keepFiles(dir, dir, dir, []string{"a.mkv"}); reason != "" {
+		t.Errorf("keepFiles() = %q, want delete allowed", reason)
+	}
+	if reason := keepFiles(filepath.Join(dir, "Media", "Frieren"), dir, filepath.Join(dir, "Media"), []string{"a.mkv"}); reason == "" {
+		t.Error("files moved into the media library should be kept")
+	}
+	if reason := keepFiles(dir, dir, dir, []string{"missing.mkv"}); reason == "" {
+		t.Error("files that cannot be checked should be kept")
+	}
+	if reason := keepFiles(dir, dir, dir, nil); reason == "" {
+		t.Error("torrent without a file list should be kept")
+	}
+}
@@ -15,7 +15,53 @@ import (
 type PhaseResult struct {
 	Err       error         // non-nil 表示任务失败，优先级高于 PollAfter
 	PollAfter time.Duration // >0 表示延迟后重新执行当前阶段
+	Message   string        // 延迟时的等待原因，记录在任务状态中；替换任务时为替换的原因；成功时为阶段结束的说明
+	Replace   *model.Task   // 非 nil 表示结束当前任务并提交这个新任务，比如下载停滞后换用其他发布
 }
 
 // PhaseFunc 阶段处理函数
 type PhaseFunc func(ctx context.Context, task *model.Task) PhaseResult
+
+// Transition 一个阶段结束时的信息
+type Transition struct {
+	Task       *model.Task
+	Phase      model.TaskPhase // 结束的阶段
+	State      string          // model.HistoryState*
+	Err        error
+	Message    string        // 阶段结束的说明，比如替换任务的原因、添加时发现的重命名问题
+	Duration   time.Duration // 阶段从第一次执行到结束的耗时
+	RetryCount int
+}
+
+// TransitionFunc 阶段结束回调，在 runner 的锁之外同步调用，不应阻塞太久
+type TransitionFunc func(t Transition)
+
+// Limits 任务执行器的并发上限
+type Limits struct {
+	MaxConcurrency int
+	MaxDownload    int
+	SlotTimeout    time.Duration
+}
+
+// Stats 任务执行器的当前占用情况
+type Stats struct {
+	Limits
+	Running       int
+	DownloadSlots int
+	Tasks         int
+	Waiting       int
+}
+
+// TaskStatus 单个任务的当前状态
+type TaskStatus struct {
+	Link       string
+	Name       string
+	Downloader string
+	Phase      model.TaskPhase
+	State      model.TaskState
+	RetryCount int
+	NextPoll   time.Time
+	Message    string
+	Failovers  []model.Failover
+	RenamePlan *model.RenamePlan
+}
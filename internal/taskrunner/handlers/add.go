@@ -2,39 +2,119 @@ package handlers
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"goto-bangumi/internal/apperrors"
+	"goto-bangumi/internal/database"
 	"goto-bangumi/internal/download"
 	"goto-bangumi/internal/model"
+	"goto-bangumi/internal/parser"
+	"goto-bangumi/internal/rename"
 	"goto-bangumi/internal/taskrunner"
 )
 
-// NewAddHandler 创建添加下载处理器，将种子添加到下载器
-func NewAddHandler(dl *download.DownloadClient) taskrunner.PhaseFunc {
+// quotaRetryAfter 下载器配额用完时等待多久再尝试添加
+const quotaRetryAfter = 30 * time.Minute
+
+// NewAddHandler 创建添加下载处理器，按任务的下载器路由将种子添加到对应下载器
+func NewAddHandler(db *database.DB, dls *download.Manager) taskrunner.PhaseFunc {
 	return func(ctx context.Context, task *model.Task) taskrunner.PhaseResult {
+		dl := dls.Get(task.Downloader)
 		savePath := genSavePath(task.Bangumi)
-		guids, err := dl.Add(ctx, task.Torrent.Link, savePath)
+		// 重试和重新添加时优先使用缓存的种子文件，订阅源的链接可能已经过期
+		torrentInfo, err := download.LoadTorrent(ctx, task.Torrent.Link, task.Torrent.InfoHash)
+		if err != nil {
+			slog.Warn("[add handler] 获取种子失败，稍后重试",
+				"torrent", task.Torrent.Name, "error", err)
+			if apperrors.IsNetworkError(err) {
+				return taskrunner.PhaseResult{PollAfter: 5 * time.Second}
+			}
+			return taskrunner.PhaseResult{Err: err}
+		}
+		if infoHash := download.TorrentHash(torrentInfo); infoHash != task.Torrent.InfoHash {
+			task.Torrent.InfoHash = infoHash
+			if err := db.SetTorrentInfoHash(ctx, task.Torrent.Link, infoHash); err != nil {
+				slog.Error("[add handler] 记录种子 info hash 失败", "torrent", task.Torrent.Name, "error", err)
+			}
+		}
+
+		guids, err := dl.Add(ctx, torrentInfo, savePath, genTags(task.Torrent, task.Bangumi)...)
 		if err != nil {
 			slog.Warn("[add handler] 添加下载失败，稍后重试",
 				"torrent", task.Torrent.Name, "error", err)
 			// TODO: 不应一直重试, 一是要有次数的限制, 二是要看是什么错误
+			if apperrors.IsDownloadQuotaError(err) {
+				// 配额用完不算失败，等配额恢复后再添加
+				return taskrunner.PhaseResult{PollAfter: quotaRetryAfter, Message: err.Error()}
+			}
 			if apperrors.IsNetworkError(err) {
 				return taskrunner.PhaseResult{PollAfter: 5 * time.Second}
 			}
 			return taskrunner.PhaseResult{Err: err}
 		}
 
+		// 记录种子所在的下载器，后续阶段和重投都发往同一个下载器
 		task.Guids = guids
+		task.Downloader = dl.Name
+		task.Torrent.Downloader = dl.Name
+		if err := db.SetTorrentDownloader(ctx, task.Torrent.Link, dl.Name); err != nil {
+			slog.Error("[add handler] 记录种子下载器失败", "torrent", task.Torrent.Name, "error", err)
+		}
 		slog.Debug("[add handler] 添加下载成功",
-			"torrent", task.Torrent.Name, "guids", guids)
+			"torrent", task.Torrent.Name, "downloader", dl.Name, "guids", guids)
+
+		// 添加成功后用种子信息算出重命名结果，集数无法解析、重名或重复下载的问题在下载完成前就能看到
+		// 不下载的文件由 dl.Add 按筛选规则写入 torrentInfo.SkipFiles，这里直接使用
+		if task.RenamePlan == nil {
+			task.RenamePlan = planRename(ctx, db, task, torrentInfo)
+		}
+		if plan := task.RenamePlan; plan != nil && len(plan.Problems) > 0 {
+			return taskrunner.PhaseResult{Message: "重命名计划: " + strings.Join(plan.Problems, "; ")}
+		}
 		return taskrunner.PhaseResult{}
 	}
 }
 
+// planRename 生成重命名计划并记录发现的问题，问题只做提示，不阻止添加
+func planRename(ctx context.Context, db *database.DB, task *model.Task, torrentInfo *model.TorrentInfo) *model.RenamePlan {
+	var downloaded []*model.Torrent
+	if task.Bangumi.ID != 0 {
+		var err error
+		downloaded, err = db.FindDownloadedTorrents(ctx, task.Bangumi.ID, task.Torrent.Link)
+		if err != nil {
+			slog.Warn("[add handler] 查询已下载的种子失败", "torrent", task.Torrent.Name, "error", err)
+		}
+	}
+	plan := rename.Plan(torrentInfo, task.Bangumi, downloaded)
+	if plan == nil {
+		return nil
+	}
+	for _, problem := range plan.Problems {
+		slog.Warn("[add handler] 重命名计划有问题", "torrent", task.Torrent.Name, "problem", problem)
+	}
+	slog.Debug("[add handler] 重命名计划", "torrent", task.Torrent.Name,
+		"collection", plan.Collection, "episodes", plan.Episodes, "files", len(plan.Files))
+	return plan
+}
+
+// genTags 生成种子标签：番剧名、季度、字幕组和种子链接的 hash
+func genTags(torrent *model.Torrent, bangumi *model.Bangumi) []string {
+	tags := make([]string, 0, 4)
+	if bangumi.OfficialTitle != "" {
+		tags = append(tags, bangumi.OfficialTitle)
+	}
+	tags = append(tags, fmt.Sprintf("S%02d", bangumi.Season))
+	if meta := parser.NewTitleMetaParse().Parse(torrent.Name); meta != nil && meta.Group != "" {
+		tags = append(tags, meta.Group)
+	}
+	return append(tags, download.LinkTag(torrent.Link))
+}
+
 // genSavePath 根据番剧信息生成保存路径
 func genSavePath(bangumi *model.Bangumi) string {
 	folder := bangumi.OfficialTitle
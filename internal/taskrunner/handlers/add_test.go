@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"slices"
+	"testing"
+
+	"goto-bangumi/internal/download"
+	"goto-bangumi/internal/model"
+)
+
+func TestGenTags(t *testing.T) {
+	torrent := &model.Torrent{
+		Link: "https://mikanani.me/Download/20231006/abc.torrent",
+		Name: "[Lilith-Raws] Sousou no Frieren - 05 [Baha][WEB-DL][1080p][AVC AAC][CHT][MP4]",
+	}
+	bangumi := &model.Bangumi{OfficialTitle: "葬送的芙莉莲", Season: 1}
+
+	want := []string{"葬送的芙莉莲", "S01", "Lilith-Raws", download.LinkTag(torrent.Link)}
+	if got := genTags(torrent, bangumi); !slices.Equal(got, want) {
+		t.Errorf("genTags() = %v, want %v", got, want)
+	}
+}
@@ -13,9 +13,10 @@ import (
 	"goto-bangumi/internal/taskrunner"
 )
 
-// NewCheckHandler 创建检查处理器，验证下载是否成功添加到下载器
-func NewCheckHandler(db *database.DB, dl *download.DownloadClient) taskrunner.PhaseFunc {
+// NewCheckHandler 创建检查处理器，验证下载是否成功添加到任务所在的下载器
+func NewCheckHandler(db *database.DB, dls *download.Manager) taskrunner.PhaseFunc {
 	return func(ctx context.Context, task *model.Task) taskrunner.PhaseResult {
+		dl := dls.Get(task.Downloader)
 		for _, guid := range task.Guids {
 			trueID, err := dl.Check(ctx, guid)
 			// GUID 没找到，试下一个
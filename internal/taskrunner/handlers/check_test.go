@@ -27,7 +27,7 @@ func (d *checkNetworkErrorDownloader) CheckHash(context.Context, string) (string
 func TestCheckHandlerRetriesNetworkErrorWithoutReturningIt(t *testing.T) {
 	dl := download.NewDownloadClient()
 	dl.Downloader = &checkNetworkErrorDownloader{}
-	handler := NewCheckHandler(nil, dl)
+	handler := NewCheckHandler(nil, download.NewManagerWith(dl))
 	task := model.NewAddTask(
 		&model.Torrent{Link: "torrent", Name: "torrent"},
 		model.NewBangumi(),
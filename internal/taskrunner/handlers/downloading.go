@@ -4,43 +4,67 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"slices"
 	"time"
 
+	"goto-bangumi/internal/apperrors"
 	"goto-bangumi/internal/database"
 	"goto-bangumi/internal/download"
 	"goto-bangumi/internal/model"
 	"goto-bangumi/internal/taskrunner"
 )
 
+// downloadMaxRestarts 下载器报告失败时最多重新开始几次
+const downloadMaxRestarts = 2
+
+// stalledGrace 下载器报告停滞（比如 qBittorrent 的 stalledDL）后，进度停止这么久就开始找替代发布
+// 停滞状态经常短暂出现，不能一看到就换
+const stalledGrace = 5 * time.Minute
+
+// AlternativeFinder 查找同一集的其他发布，没有找到时返回 nil
+type AlternativeFinder interface {
+	FindAlternative(ctx context.Context, stalled *model.Torrent, bangumi *model.Bangumi) (*model.Torrent, error)
+}
+
 // NewDownloadingHandler 创建下载监控处理器，合并进度检查和 ETA 计算
-func NewDownloadingHandler(db *database.DB, dl *download.DownloadClient) taskrunner.PhaseFunc {
+// 下载状态由 poller 批量查询，handler 只登记 hash 并读取最近一次的结果
+// 下载器报告失败时，支持的下载器先重新开始，次数用完再失败；暂停、停滞等状态按 poller 的间隔跟进
+// 下载停滞时通过 finder 换用同一集的其他发布，finder 为 nil 或未启用时只等待超时
+func NewDownloadingHandler(db *database.DB, poller *download.StatusPoller, dls *download.Manager, finder AlternativeFinder, failover model.FailoverConfig) taskrunner.PhaseFunc {
+	stallTime := time.Duration(failover.StallTime) * time.Minute
+	if stallTime <= 0 {
+		stallTime = 30 * time.Minute
+	}
 	return func(ctx context.Context, task *model.Task) taskrunner.PhaseResult {
+		duid := task.Torrent.DownloadUID
+
 		// 检查是否超时（4小时）
 		if time.Since(task.StartTime) > 4*time.Hour {
 			slog.Warn("[downloading handler] 下载超过4小时，标记为异常",
 				"torrent", task.Torrent.Name,
-				"duid", task.Torrent.DownloadUID,
+				"duid", duid,
 				"elapsed", time.Since(task.StartTime))
 
+			poller.Untrack(duid)
 			db.AddTorrentError(ctx, task.Torrent.Link)
 			return taskrunner.PhaseResult{Err: fmt.Errorf("download timeout after 4 hours")}
 		}
 
-		// 获取种子信息
-		info, err := dl.GetTorrentInfo(ctx, task.Torrent.DownloadUID)
-		if err != nil {
-			slog.Error("[downloading handler] 获取种子信息失败",
-				"error", err, "duid", task.Torrent.DownloadUID)
-			return taskrunner.PhaseResult{Err: err}
+		poller.Track(duid, task.Torrent.Link, task.Downloader)
+		info, swept := poller.Status(duid)
+		if !swept {
+			// 还没轮询到，等 poller 的下一轮
+			return taskrunner.PhaseResult{PollAfter: poller.Interval()}
 		}
 
 		if info == nil {
-			slog.Warn("[downloading handler] 种子不存在", "duid", task.Torrent.DownloadUID)
+			slog.Warn("[downloading handler] 种子不存在", "duid", duid)
+			poller.Untrack(duid)
 			return taskrunner.PhaseResult{Err: fmt.Errorf("torrent not found")}
 		}
 
-		// 检查是否下载完成（Completed > 0 表示已完成，为 Unix 时间戳）
-		if info.Completed > 0 {
+		if info.Done() {
+			poller.Untrack(duid)
 			task.Torrent.Downloaded = model.DownloadDone
 			if err := db.AddTorrentDownload(ctx, task.Torrent.Link); err != nil {
 				slog.Error("[downloading handler] 更新种子状态失败", "error", err)
@@ -51,15 +75,157 @@ func NewDownloadingHandler(db *database.DB, dl *download.DownloadClient) taskrun
 			return taskrunner.PhaseResult{} // 成功，进入下一阶段
 		}
 
-		// 未完成，根据 ETA 自适应轮询
+		if info.Failed() {
+			return restartFailed(ctx, db, poller, dls.Get(task.Downloader), task, info)
+		}
+
+		idle := trackProgress(task, info)
+		if finder != nil && failover.Enable && time.Since(task.FailoverSearched) >= stallTime {
+			reason := ""
+			if idle >= stallTime {
+				reason = fmt.Sprintf("no progress for %s", idle.Round(time.Minute))
+			} else if info.State == model.DownloadStateStalled && idle >= stalledGrace {
+				reason = fmt.Sprintf("stalled for %s", idle.Round(time.Minute))
+			}
+			if reason != "" {
+				if result, ok := switchRelease(ctx, db, poller, dls.Get(task.Downloader), finder, task, reason); ok {
+					return result
+				}
+			}
+		}
+
+		message, downloading := stateMessage(info)
+		if !downloading {
+			// 没有在下载，ETA 没有意义，跟着 poller 的节奏看状态是否恢复
+			slog.Debug("[downloading handler] 种子未在下载",
+				"torrent", task.Torrent.Name,
+				"state", info.State,
+				"message", message)
+			return taskrunner.PhaseResult{PollAfter: poller.Interval(), Message: message}
+		}
+
+		// 正在下载，根据 ETA 自适应轮询；完成或失败时 poller 会提前唤醒任务
 		interval := calculateEta(int64(info.ETA))
 		slog.Debug("[downloading handler] 设置检查间隔",
 			"torrent", task.Torrent.Name,
 			"eta", info.ETA,
 			"interval", interval)
+		return taskrunner.PhaseResult{PollAfter: time.Duration(interval) * time.Second, Message: message}
+	}
+}
+
+// trackProgress 记录下载进度，返回进度停止增长的时长
+// 只有下载中、停滞、获取元数据时才累计停滞时长，暂停、排队、校验等状态下进度本来就不会增长，
+// 每次都重新计时，避免把用户暂停或排队中的种子当成停滞删除
+func trackProgress(task *model.Task, info *model.TorrentStatus) time.Duration {
+	now := time.Now()
+	switch info.State {
+	case model.DownloadStateDownloading, model.DownloadStateStalled, model.DownloadStateMetadata:
+	default:
+		task.Progress = info.Progress
+		task.ProgressAt = now
+		return 0
+	}
+	if task.ProgressAt.IsZero() || info.Progress > task.Progress {
+		task.Progress = info.Progress
+		task.ProgressAt = now
+	}
+	return now.Sub(task.ProgressAt)
+}
 
-		return taskrunner.PhaseResult{PollAfter: time.Duration(interval) * time.Second}
+// switchRelease 换用同一集的其他发布：删除停滞的种子，用新种子的任务替换当前任务
+// 没有找到替代或者出错时返回 false，继续等待原来的种子，过一个停滞时长再找
+func switchRelease(ctx context.Context, db *database.DB, poller *download.StatusPoller, dl *download.DownloadClient, finder AlternativeFinder, task *model.Task, reason string) (taskrunner.PhaseResult, bool) {
+	task.FailoverSearched = time.Now()
+	alt, err := finder.FindAlternative(ctx, task.Torrent, task.Bangumi)
+	if err != nil {
+		slog.Warn("[downloading handler] 查找替代发布失败", "torrent", task.Torrent.Name, "error", err)
+		return taskrunner.PhaseResult{}, false
 	}
+	if alt == nil {
+		slog.Info("[downloading handler] 下载停滞，没有找到替代发布", "torrent", task.Torrent.Name, "reason", reason)
+		return taskrunner.PhaseResult{}, false
+	}
+	if err := db.CreateTorrent(ctx, alt); err != nil {
+		slog.Error("[downloading handler] 保存替代发布失败", "torrent", alt.Name, "error", err)
+		return taskrunner.PhaseResult{}, false
+	}
+
+	duid := task.Torrent.DownloadUID
+	poller.Untrack(duid)
+	if err := dl.Delete(ctx, []string{duid}, true); err != nil {
+		// 删除失败不影响换用，旧种子已标记为异常，不会再被处理
+		slog.Warn("[downloading handler] 删除停滞的种子失败", "torrent", task.Torrent.Name, "error", err)
+	}
+	if err := db.AddTorrentError(ctx, task.Torrent.Link); err != nil {
+		slog.Error("[downloading handler] 更新种子状态失败", "torrent", task.Torrent.Name, "error", err)
+	}
+
+	task.Failovers = append(task.Failovers, model.Failover{
+		Time:   task.FailoverSearched,
+		From:   task.Torrent.Name,
+		To:     alt.Name,
+		Reason: reason,
+	})
+	next := model.NewAddTask(alt, task.Bangumi)
+	next.Failovers = slices.Clone(task.Failovers)
+	slog.Warn("[downloading handler] 下载停滞，换用其他发布",
+		"torrent", task.Torrent.Name,
+		"alternative", alt.Name,
+		"reason", reason)
+	return taskrunner.PhaseResult{
+		Replace: next,
+		Message: fmt.Sprintf("switched to %s: %s", alt.Name, reason),
+	}, true
+}
+
+// stateMessage 生成任务列表中显示的下载状态，第二个返回值表示种子是否正在下载
+func stateMessage(info *model.TorrentStatus) (string, bool) {
+	message := info.Message
+	if message == "" && info.Progress > 0 {
+		message = fmt.Sprintf("%.1f%%", info.Progress*100)
+	}
+	switch info.State {
+	case model.DownloadStateDownloading, model.DownloadStateUnknown, "":
+		return message, true
+	}
+	if message == "" {
+		return string(info.State), false
+	}
+	return fmt.Sprintf("%s: %s", info.State, message), false
+}
+
+// restartFailed 处理下载器报告的失败，还有次数时重新开始下载，否则标记为异常
+func restartFailed(ctx context.Context, db *database.DB, poller *download.StatusPoller, dl *download.DownloadClient, task *model.Task, info *model.TorrentStatus) taskrunner.PhaseResult {
+	duid := task.Torrent.DownloadUID
+	if task.RetryCount < downloadMaxRestarts {
+		supported, err := dl.Restart(ctx, duid)
+		if supported && err == nil {
+			task.RetryCount++
+			slog.Warn("[downloading handler] 下载失败，重新开始下载",
+				"torrent", task.Torrent.Name,
+				"reason", info.Message,
+				"restart", task.RetryCount)
+			// 丢掉缓存的失败状态，等 poller 重新查询
+			poller.Untrack(duid)
+			return taskrunner.PhaseResult{
+				PollAfter: poller.Interval(),
+				Message:   fmt.Sprintf("restarted %d/%d: %s", task.RetryCount, downloadMaxRestarts, info.Message),
+			}
+		}
+		if supported && apperrors.IsNetworkError(err) {
+			slog.Warn("[downloading handler] 重新开始下载失败，稍后重试", "torrent", task.Torrent.Name, "error", err)
+			return taskrunner.PhaseResult{PollAfter: poller.Interval(), Message: info.Message}
+		}
+		if err != nil {
+			slog.Error("[downloading handler] 重新开始下载失败", "torrent", task.Torrent.Name, "error", err)
+		}
+	}
+
+	slog.Error("[downloading handler] 下载失败", "torrent", task.Torrent.Name, "reason", info.Message)
+	poller.Untrack(duid)
+	db.AddTorrentError(ctx, task.Torrent.Link)
+	return taskrunner.PhaseResult{Err: fmt.Errorf("download failed: %s", info.Message)}
 }
 
 // calculateEta 根据 ETA 计算检查间隔（秒）
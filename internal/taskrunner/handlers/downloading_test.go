@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"goto-bangumi/internal/database"
+	"goto-bangumi/internal/download"
+	"goto-bangumi/internal/download/downloader"
+	"goto-bangumi/internal/model"
+	"goto-bangumi/internal/taskrunner"
+)
+
+// failedDownloader 所有种子都报告失败，并记录重新开始的次数
+type failedDownloader struct {
+	*downloader.MockDownloader
+	restarts int
+}
+
+func (d *failedDownloader) GetTorrentsInfo(ctx context.Context, hashes []string) (map[string]*model.TorrentStatus, error) {
+	result := make(map[string]*model.TorrentStatus, len(hashes))
+	for _, hash := range hashes {
+		result[hash] = &model.TorrentStatus{ETA: -1, State: model.DownloadStateFailed, Message: "offline download failed"}
+	}
+	return result, nil
+}
+
+func (d *failedDownloader) Restart(ctx context.Context, hash string) error {
+	d.restarts++
+	return nil
+}
+
+func TestDownloadingHandlerRestartsFailedDownload(t *testing.T) {
+	testdb := ":memory:"
+	db, err := database.NewDB(&testdb)
+	if err != nil {
+		t.Fatalf("NewDB: %v", err)
+	}
+	ctx := context.Background()
+	torrent := &model.Torrent{Link: "torrent", Name: "torrent", DownloadUID: "hash", Downloaded: model.DownloadSending}
+	if err := db.CreateTorrent(ctx, torrent); err != nil {
+		t.Fatalf("CreateTorrent: %v", err)
+	}
+
+	dl := download.NewDownloadClient()
+	fake := &failedDownloader{MockDownloader: downloader.NewMockDownloader()}
+	dl.Downloader = fake
+	dls := download.NewManagerWith(dl)
+	poller := download.NewStatusPoller(dls, time.Second, nil)
+	handler := NewDownloadingHandler(db, poller, dls, nil, model.FailoverConfig{})
+	task := model.NewAddTask(torrent, model.NewBangumi())
+	task.StartTime = time.Now()
+
+	// 每次都先登记、轮询，再读取轮询结果
+	run := func() taskrunner.PhaseResult {
+		handler(ctx, task)
+		if err := poller.Sweep(ctx); err != nil {
+			t.Fatalf("Sweep: %v", err)
+		}
+		return handler(ctx, task)
+	}
+
+	for i := 1; i <= downloadMaxRestarts; i++ {
+		if r := run(); r.Err != nil || r.Message == "" {
+			t.Fatalf("restart %d: err = %v, message = %q, want restart", i, r.Err, r.Message)
+		}
+		if fake.restarts != i {
+			t.Fatalf("restarts = %d, want %d", fake.restarts, i)
+		}
+	}
+	if r := run(); r.Err == nil {
+		t.Fatal("handler should fail after the restarts are used up")
+	}
+	got, _ := db.GetTorrentByURL(ctx, "torrent")
+	if got == nil || got.Downloaded != model.DownloadError {
+		t.Errorf("torrent after failure = %+v, want DownloadError", got)
+	}
+}
+
+// stateDownloader 所有种子都处于指定的状态，不支持重新开始
+type stateDownloader struct {
+	*downloader.MockDownloader
+	state model.DownloadState
+}
+
+func (d *stateDownloader) GetTorrentsInfo(ctx context.Context, hashes []string) (map[string]*model.TorrentStatus, error) {
+	result := make(map[string]*model.TorrentStatus, len(hashes))
+	for _, hash := range hashes {
+		result[hash] = &model.TorrentStatus{ETA: 3600, State: d.state, Progress: 0.5}
+	}
+	return result, nil
+}
+
+func TestDownloadingHandlerReactsToState(t *testing.T) {
+	testdb := ":memory:"
+	db, err := database.NewDB(&testdb)
+	if err != nil {
+		t.Fatalf("NewDB: %v", err)
+	}
+	ctx := context.Background()
+	torrent := &model.Torrent{Link: "torrent", Name: "torrent", DownloadUID: "hash", Downloaded: model.DownloadSending}
+	if err := db.CreateTorrent(ctx, torrent); err != nil {
+		t.Fatalf("CreateTorrent: %v", err)
+	}
+
+	fake := &stateDownloader{MockDownloader: downloader.NewMockDownloader(), state: model.DownloadStatePaused}
+	dl := download.NewDownloadClient()
+	dl.Downloader = fake
+	dls := download.NewManagerWith(dl)
+	poller := download.NewStatusPoller(dls, time.Second, nil)
+	handler := NewDownloadingHandler(db, poller, dls, nil, model.FailoverConfig{})
+	task := model.NewAddTask(torrent, model.NewBangumi())
+	task.StartTime = time.Now()
+
+	run := func() taskrunner.PhaseResult {
+		handler(ctx, task)
+		if err := poller.Sweep(ctx); err != nil {
+			t.Fatalf("Sweep: %v", err)
+		}
+		return handler(ctx, task)
+	}
+
+	// 暂停时不按 ETA 等待，跟着 poller 的间隔检查
+	r := run()
+	if r.Err != nil || r.PollAfter != poller.Interval() || r.Message != "paused: 50.0%" {
+		t.Fatalf("paused: result = %+v", r)
+	}
+
+	// 下载中按 ETA 计算间隔
+	fake.state = model.DownloadStateDownloading
+	if r := run(); r.Err != nil || r.PollAfter != 300*time.Second || r.Message != "50.0%" {
+		t.Fatalf("downloading: result = %+v", r)
+	}
+
+	// 文件丢失且下载器不支持重新开始时立即失败
+	fake.state = model.DownloadStateMissingFiles
+	if r := run(); r.Err == nil {
+		t.Fatal("missing files should fail the task without waiting for the timeout")
+	}
+	got, _ := db.GetTorrentByURL(ctx, "torrent")
+	if got == nil || got.Downloaded != model.DownloadError {
+		t.Errorf("torrent after failure = %+v, want DownloadError", got)
+	}
+}
+
+// fakeFinder 返回固定的替代发布
+type fakeFinder struct {
+	alt   *model.Torrent
+	calls int
+}
+
+func (f *fakeFinder) FindAlternative(ctx context.Context, stalled *model.Torrent, bangumi *model.Bangumi) (*model.Torrent, error) {
+	f.calls++
+	return f.alt, nil
+}
+
+func TestDownloadingHandlerSwitchesStalledRelease(t *testing.T) {
+	testdb := ":memory:"
+	db, err := database.NewDB(&testdb)
+	if err != nil {
+		t.Fatalf("NewDB: %v", err)
+	}
+	ctx := context.Background()
+	torrent := &model.Torrent{Link: "stalled", Name: "[GroupA] Frieren - 05 [1080p]", DownloadUID: "hash", Downloaded: model.DownloadSending}
+	if err := db.CreateTorrent(ctx, torrent); err != nil {
+		t.Fatalf("CreateTorrent: %v", err)
+	}
+
+	fake := &stateDownloader{MockDownloader: downloader.NewMockDownloader(), state: model.DownloadStateStalled}
+	dl := download.NewDownloadClient()
+	dl.Downloader = fake
+	dls := download.NewManagerWith(dl)
+	poller := download.NewStatusPoller(dls, time.Second, nil)
+	finder := &fakeFinder{}
+	handler := NewDownloadingHandler(db, poller, dls, finder, model.FailoverConfig{Enable: true, StallTime: 30})
+	task := model.NewAddTask(torrent, model.NewBangumi())
+	task.StartTime = time.Now()
+
+	run := func() taskrunner.PhaseResult {
+		handler(ctx, task)
+		if err := poller.Sweep(ctx); err != nil {
+			t.Fatalf("Sweep: %v", err)
+		}
+		return handler(ctx, task)
+	}
+
+	// 刚开始停滞，还在宽限期内
+	if r := run(); r.Replace != nil || finder.calls != 0 {
+		t.Fatalf("stalled within grace: result = %+v, calls = %d", r, finder.calls)
+	}
+
+	// 停滞超过宽限期但没有替代，继续等待，一个停滞时长内不再查找
+	task.ProgressAt = time.Now().Add(-10 * time.Minute)
+	if r := run(); r.Replace != nil || r.PollAfter == 0 || finder.calls != 1 {
+		t.Fatalf("no alternative: result = %+v, calls = %d", r, finder.calls)
+	}
+	run()
+	if finder.calls != 1 {
+		t.Fatalf("calls = %d, want no new search within the stall time", finder.calls)
+	}
+
+	// 找到替代后用新任务替换
+	task.FailoverSearched = time.Time{}
+	finder.alt = &model.Torrent{Link: "alternative", Name: "[GroupB] Frieren - 05 [1080p]"}
+	r := handler(ctx, task)
+	if r.Replace == nil || r.Replace.Torrent != finder.alt || r.Replace.CurrentPhase != model.PhaseAdding {
+		t.Fatalf("failover: result = %+v", r)
+	}
+	if len(task.Failovers) != 1 || task.Failovers[0].To != finder.alt.Name || len(r.Replace.Failovers) != 1 {
+		t.Errorf("failovers = %+v, replacement = %+v", task.Failovers, r.Replace.Failovers)
+	}
+	old, _ := db.GetTorrentByURL(ctx, "stalled")
+	if old == nil || old.Downloaded != model.DownloadError {
+		t.Errorf("stalled torrent = %+v, want DownloadError", old)
+	}
+	if alt, _ := db.GetTorrentByURL(ctx, "alternative"); alt == nil {
+		t.Error("alternative torrent should be saved")
+	}
+}
+
+func TestDownloadingHandlerKeepsPausedAndQueuedTorrents(t *testing.T) {
+	testdb := ":memory:"
+	db, err := database.NewDB(&testdb)
+	if err != nil {
+		t.Fatalf("NewDB: %v", err)
+	}
+	ctx := context.Background()
+	for _, state := range []model.DownloadState{model.DownloadStatePaused, model.DownloadStateWaiting, model.DownloadStateChecking} {
+		t.Run(string(state), func(t *testing.T) {
+			torrent := &model.Torrent{Link: "link-" + string(state), Name: "[GroupA] Frieren - 05 [1080p]", DownloadUID: "hash", Downloaded: model.DownloadSending}
+			fake := &stateDownloader{MockDownloader: downloader.NewMockDownloader(), state: state}
+			dl := download.NewDownloadClient()
+			dl.Downloader = fake
+			dls := download.NewManagerWith(dl)
+			poller := download.NewStatusPoller(dls, time.Second, nil)
+			finder := &fakeFinder{alt: &model.Torrent{Link: "alternative", Name: "[GroupB] Frieren - 05 [1080p]"}}
+			handler := NewDownloadingHandler(db, poller, dls, finder, model.FailoverConfig{Enable: true, StallTime: 30})
+			task := model.NewAddTask(torrent, model.NewBangumi())
+			task.StartTime = time.Now()
+			task.ProgressAt = time.Now().Add(-time.Hour)
+
+			handler(ctx, task)
+			if err := poller.Sweep(ctx); err != nil {
+				t.Fatalf("Sweep: %v", err)
+			}
+			if r := handler(ctx, task); r.Replace != nil || finder.calls != 0 {
+				t.Fatalf("result = %+v, calls = %d, want no failover", r, finder.calls)
+			}
+			if time.Since(task.ProgressAt) > time.Minute {
+				t.Errorf("ProgressAt = %v, want reset", task.ProgressAt)
+			}
+		})
+	}
+}
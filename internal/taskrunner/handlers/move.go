@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"path"
+	"path/filepath"
+	"time"
+
+	"goto-bangumi/internal/apperrors"
+	"goto-bangumi/internal/download"
+	"goto-bangumi/internal/model"
+	"goto-bangumi/internal/taskrunner"
+)
+
+const moveMaxRetries = 5
+
+// NewMoveHandler 创建转移处理器，把重命名后的文件从 SavePath 移动到 MediaPath 下的 "番剧名 (年份)/Season N"
+// MediaPath 为空或与 SavePath 相同时不移动
+func NewMoveHandler(dls *download.Manager) taskrunner.PhaseFunc {
+	return func(ctx context.Context, task *model.Task) taskrunner.PhaseResult {
+		dl := dls.Get(task.Downloader)
+		target := mediaLocation(dl, task.Bangumi)
+		if target == "" || task.Torrent.DownloadUID == "" {
+			return taskrunner.PhaseResult{}
+		}
+
+		info, err := dl.GetTorrentInfo(ctx, task.Torrent.DownloadUID)
+		if err != nil {
+			return moveRetry(task, err)
+		}
+		if info == nil {
+			err := &apperrors.DownloadKeyError{Err: errors.New("torrent not found"), Key: task.Torrent.DownloadUID}
+			return moveRetry(task, err)
+		}
+		// 死信重投或重启后再次执行时已经在目标目录下
+		if path.Clean(filepath.ToSlash(info.SavePath)) == target {
+			return taskrunner.PhaseResult{}
+		}
+
+		slog.Info("[move handler] 转移到媒体库",
+			"torrent", task.Torrent.Name, "from", info.SavePath, "to", target)
+		if err := dl.Move(ctx, []string{task.Torrent.DownloadUID}, target); err != nil {
+			return moveRetry(task, err)
+		}
+		return taskrunner.PhaseResult{}
+	}
+}
+
+// mediaLocation 生成番剧在媒体库中的目录，不需要转移时返回空
+func mediaLocation(dl *download.DownloadClient, bangumi *model.Bangumi) string {
+	if dl.MediaPath == "" || bangumi == nil {
+		return ""
+	}
+	mediaPath := path.Clean(filepath.ToSlash(dl.MediaPath))
+	if mediaPath == path.Clean(filepath.ToSlash(dl.SavePath)) {
+		return ""
+	}
+	return path.Join(mediaPath, filepath.ToSlash(genSavePath(bangumi)))
+}
+
+// moveRetry 网络错误稍后重试，其他错误直接失败，交给死信重投
+func moveRetry(task *model.Task, err error) taskrunner.PhaseResult {
+	if !apperrors.IsNetworkError(err) {
+		slog.Error("[move handler] 转移失败", "torrent", task.Torrent.Name, "error", err)
+		return taskrunner.PhaseResult{Err: err}
+	}
+	task.RetryCount++
+	if task.RetryCount > moveMaxRetries {
+		slog.Error("[move handler] 转移失败，超过重试次数", "torrent", task.Torrent.Name, "error", err)
+		return taskrunner.PhaseResult{Err: err}
+	}
+	slog.Warn("[move handler] 转移失败，稍后重试", "torrent", task.Torrent.Name, "retry", task.RetryCount, "error", err)
+	return taskrunner.PhaseResult{PollAfter: 30 * time.Second}
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"goto-bangumi/internal/download"
+	"goto-bangumi/internal/download/downloader"
+	"goto-bangumi/internal/model"
+)
+
+func TestMoveHandlerMovesIntoMediaPath(t *testing.T) {
+	mock := downloader.NewMockDownloader()
+	mock.Init(&model.DownloaderConfig{})
+	mock.AddMockTorrent("hash", &model.TorrentStatus{SavePath: "/downloads/Bangumi", Completed: 1}, []string{"a.mkv"})
+	dl := download.NewDownloadClient()
+	dl.Downloader = mock
+	dl.SavePath = "/downloads/Bangumi"
+	dl.MediaPath = "/media/Anime"
+
+	bangumi := model.NewBangumi()
+	bangumi.OfficialTitle = "葬送的芙莉莲"
+	bangumi.Year = "2023"
+	bangumi.Season = 1
+	task := model.NewAddTask(&model.Torrent{Name: "torrent", DownloadUID: "hash"}, bangumi)
+	handler := NewMoveHandler(download.NewManagerWith(dl))
+
+	if result := handler(context.Background(), task); result.Err != nil || result.PollAfter != 0 {
+		t.Fatalf("handler() = %+v, want success", result)
+	}
+	info, _ := mock.GetTorrentInfo(context.Background(), "hash")
+	want := "/media/Anime/葬送的芙莉莲 (2023)/Season 1"
+	if info.SavePath != want {
+		t.Fatalf("SavePath = %q, want %q", info.SavePath, want)
+	}
+	// 再次执行时已经在目标目录下，不会重复移动
+	if result := handler(context.Background(), task); result.Err != nil {
+		t.Fatalf("second handler() err = %v", result.Err)
+	}
+}
+
+func TestMoveHandlerSkipsWithoutMediaPath(t *testing.T) {
+	dl := download.NewDownloadClient()
+	dl.Downloader = downloader.NewMockDownloader()
+	dl.SavePath = "/downloads/Bangumi"
+	dl.MediaPath = "/downloads/Bangumi/"
+	task := model.NewAddTask(&model.Torrent{Name: "torrent", DownloadUID: "hash"}, model.NewBangumi())
+
+	if result := NewMoveHandler(download.NewManagerWith(dl))(context.Background(), task); result.Err != nil {
+		t.Fatalf("handler() err = %v, want skip", result.Err)
+	}
+}
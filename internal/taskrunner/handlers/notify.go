@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"goto-bangumi/internal/model"
+	"goto-bangumi/internal/network"
+	"goto-bangumi/internal/notification"
+	"goto-bangumi/internal/taskrunner"
+)
+
+const (
+	notifyMaxRetries = 8
+	notifyBaseDelay  = 30 * time.Second
+	notifyMaxDelay   = 30 * time.Minute
+)
+
+// NewNotifyHandler 创建通知处理器，每个种子发送一条更新通知
+// 发送失败时按指数退避重试，不会重复执行重命名
+func NewNotifyHandler(nc *notification.Client) taskrunner.PhaseFunc {
+	return func(ctx context.Context, task *model.Task) taskrunner.PhaseResult {
+		if !nc.Enabled() || len(task.RenamedEpisodes) == 0 {
+			return taskrunner.PhaseResult{}
+		}
+
+		message := &notification.Message{Text: notifyText(task.Bangumi, task.RenamedEpisodes)}
+		// 海报只是锦上添花，下载失败时仍然发送文字通知
+		if task.Bangumi.PosterLink != "" {
+			image, err := network.LoadImage(ctx, task.Bangumi.PosterLink)
+			if err != nil {
+				slog.Warn("[notify handler] 下载海报失败，仅发送文字", "error", err)
+			} else {
+				message.Image = image
+			}
+		}
+
+		if err := nc.Send(ctx, message); err != nil {
+			task.RetryCount++
+			if task.RetryCount > notifyMaxRetries {
+				return taskrunner.PhaseResult{Err: fmt.Errorf("send notification after %d retries: %w", notifyMaxRetries, err)}
+			}
+			delay := notifyBackoff(task.RetryCount)
+			slog.Warn("[notify handler] 发送通知失败，稍后重试",
+				"torrent", task.Torrent.Name, "retry", task.RetryCount, "after", delay, "error", err)
+			return taskrunner.PhaseResult{PollAfter: delay}
+		}
+
+		slog.Info("[notify handler] 通知发送成功", "torrent", task.Torrent.Name)
+		return taskrunner.PhaseResult{}
+	}
+}
+
+// notifyText 生成通知文本，多集时按集数排序列出
+func notifyText(bangumi *model.Bangumi, episodes []int) string {
+	sorted := slices.Clone(episodes)
+	slices.Sort(sorted)
+	sorted = slices.Compact(sorted)
+	eps := make([]string, 0, len(sorted))
+	for _, ep := range sorted {
+		eps = append(eps, strconv.Itoa(ep))
+	}
+	return fmt.Sprintf("番剧名称：%s\n季度：第%d季\n更新集数：第%s集",
+		bangumi.OfficialTitle, bangumi.Season, strings.Join(eps, "、"))
+}
+
+// notifyBackoff 计算第 retry 次重试的等待时间
+func notifyBackoff(retry int) time.Duration {
+	delay := notifyBaseDelay
+	for i := 1; i < retry; i++ {
+		delay *= 2
+		if delay >= notifyMaxDelay {
+			return notifyMaxDelay
+		}
+	}
+	return delay
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"goto-bangumi/internal/model"
+	"goto-bangumi/internal/notification"
+)
+
+type recordNotifier struct {
+	err      error
+	messages []*notification.Message
+}
+
+func (n *recordNotifier) Send(_ context.Context, message *notification.Message) error {
+	n.messages = append(n.messages, message)
+	return n.err
+}
+
+func newNotifyTask(episodes ...int) *model.Task {
+	task := model.NewRenameTask(
+		&model.Torrent{Link: "torrent", Name: "torrent"},
+		&model.Bangumi{OfficialTitle: "败犬女主太多了", Season: 1},
+	)
+	task.RenamedEpisodes = episodes
+	return task
+}
+
+func TestNotifyHandlerSendsOneMessagePerTorrent(t *testing.T) {
+	notifier := &recordNotifier{}
+	handler := NewNotifyHandler(notification.NewClient(notifier))
+
+	result := handler(context.Background(), newNotifyTask(3, 1, 2))
+
+	if result.Err != nil || result.PollAfter != 0 {
+		t.Fatalf("result = %+v, want success", result)
+	}
+	if len(notifier.messages) != 1 {
+		t.Fatalf("messages = %d, want 1", len(notifier.messages))
+	}
+	want := "番剧名称：败犬女主太多了\n季度：第1季\n更新集数：第1、2、3集"
+	if notifier.messages[0].Text != want {
+		t.Errorf("text = %q, want %q", notifier.messages[0].Text, want)
+	}
+}
+
+func TestNotifyHandlerRetriesWithBackoff(t *testing.T) {
+	notifier := &recordNotifier{err: errors.New("telegram unavailable")}
+	handler := NewNotifyHandler(notification.NewClient(notifier))
+	task := newNotifyTask(7)
+
+	first := handler(context.Background(), task)
+	second := handler(context.Background(), task)
+
+	if first.Err != nil || first.PollAfter != 30*time.Second {
+		t.Fatalf("first result = %+v, want PollAfter 30s", first)
+	}
+	if second.Err != nil || second.PollAfter != time.Minute {
+		t.Fatalf("second result = %+v, want PollAfter 1m", second)
+	}
+
+	task.RetryCount = notifyMaxRetries
+	if last := handler(context.Background(), task); last.Err == nil {
+		t.Fatal("handler should fail after max retries")
+	}
+}
+
+func TestNotifyHandlerSkipsWithoutRenamedEpisodes(t *testing.T) {
+	notifier := &recordNotifier{}
+	handler := NewNotifyHandler(notification.NewClient(notifier))
+
+	result := handler(context.Background(), newNotifyTask())
+
+	if result.Err != nil || result.PollAfter != 0 {
+		t.Fatalf("result = %+v, want success", result)
+	}
+	if len(notifier.messages) != 0 {
+		t.Fatalf("messages = %d, want 0", len(notifier.messages))
+	}
+}
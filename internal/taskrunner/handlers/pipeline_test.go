@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"context"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"goto-bangumi/internal/database"
+	"goto-bangumi/internal/download"
+	"goto-bangumi/internal/download/downloader"
+	"goto-bangumi/internal/model"
+	"goto-bangumi/internal/network"
+	"goto-bangumi/internal/rename"
+	"goto-bangumi/internal/rss"
+	"goto-bangumi/internal/taskrunner"
+)
+
+// TestPipelineWithSimulatedDownloader 用模拟模式的下载器跑完 RSS → 添加 → 下载 → 重命名
+func TestPipelineWithSimulatedDownloader(t *testing.T) {
+	testdb := ":memory:"
+	db, err := database.NewDB(&testdb)
+	if err != nil {
+		t.Fatalf("NewDB: %v", err)
+	}
+	ctx := context.Background()
+
+	data, err := os.ReadFile("../../download/test_data/test2.torrent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := "[Skymoon-Raws] SPY×FAMILY Season 3 - 41 [ViuTV][WEB-DL][CHT][SRT][1080p][AVC AAC].mkv"
+	rssURL, torrentURL := "https://mikanani.me/RSS/Bangumi?bangumiId=3141&pipeline=test", "https://example.com/pipeline.torrent"
+	network.SetTestCache(rssURL, []byte(`<?xml version="1.0" encoding="utf-8"?><rss version="2.0"><channel><title>SPY×FAMILY</title>`+
+		`<item><title>`+name+`</title><link>https://example.com/pipeline</link><enclosure url="`+torrentURL+`" /></item></channel></rss>`))
+	network.SetTestCache(torrentURL, data)
+	defer network.ClearTestCache(rssURL)
+	defer network.ClearTestCache(torrentURL)
+
+	torrents, err := rss.GetTorrents(ctx, network.GetRequestClient(), rssURL)
+	if err != nil || len(torrents) != 1 {
+		t.Fatalf("GetTorrents() = %v, %v", torrents, err)
+	}
+	torrent := torrents[0]
+	if err := db.CreateTorrent(ctx, torrent); err != nil {
+		t.Fatalf("CreateTorrent: %v", err)
+	}
+
+	sim := downloader.NewMockDownloader()
+	if err := sim.Init(&model.DownloaderConfig{Type: "mock", Mock: model.MockConfig{Simulate: true, Duration: 60, Seed: 1}}); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	dl := download.NewDownloadClient()
+	dl.Downloader = sim
+	dls := download.NewManagerWith(dl)
+	poller := download.NewStatusPoller(dls, time.Second, nil)
+
+	bangumi := model.NewBangumi()
+	bangumi.OfficialTitle = "SPY×FAMILY"
+	bangumi.Season = 3
+	task := model.NewAddTask(torrent, bangumi)
+	task.StartTime = time.Now()
+
+	step := func(phase string, handler taskrunner.PhaseFunc) taskrunner.PhaseResult {
+		t.Helper()
+		r := handler(ctx, task)
+		if r.Err != nil {
+			t.Fatalf("%s: %v", phase, r.Err)
+		}
+		return r
+	}
+	step("add", NewAddHandler(db, dls))
+	if plan := task.RenamePlan; plan == nil || plan.Collection || !slices.Equal(plan.Episodes, []int{41}) || len(plan.Problems) != 0 {
+		t.Fatalf("RenamePlan = %+v, want episode 41 planned before downloading", plan)
+	}
+	step("check", NewCheckHandler(db, dls))
+
+	// 虚拟时间只随 Advance 前进，每次前进 20 秒，三次后下载完成
+	downloading := NewDownloadingHandler(db, poller, dls, nil, model.FailoverConfig{})
+	for i := 0; ; i++ {
+		step("downloading", downloading)
+		if err := poller.Sweep(ctx); err != nil {
+			t.Fatalf("Sweep: %v", err)
+		}
+		if r := step("downloading", downloading); r.PollAfter == 0 {
+			break
+		}
+		if i > 3 {
+			t.Fatal("simulated download never finished")
+		}
+		sim.Advance(20 * time.Second)
+	}
+
+	step("rename", NewRenameHandler(db, rename.New(db, dls)))
+	if !slices.Equal(task.RenamedEpisodes, []int{41}) {
+		t.Errorf("RenamedEpisodes = %v, want [41]", task.RenamedEpisodes)
+	}
+	renames := sim.Renames()
+	if len(renames) != 1 || renames[0].OldPath != name {
+		t.Errorf("Renames() = %+v, want the file from the metainfo renamed", renames)
+	}
+}
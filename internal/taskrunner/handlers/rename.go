@@ -17,7 +17,7 @@ func NewRenameHandler(db *database.DB, renamer *rename.Renamer) taskrunner.Phase
 			"torrent", task.Torrent.Name,
 			"bangumi", task.Bangumi.OfficialTitle)
 
-		renamer.Rename(ctx, task.Torrent, task.Bangumi)
+		task.RenamedEpisodes = renamer.Rename(ctx, task.Torrent, task.Bangumi)
 
 		if err := db.TorrentRenamed(ctx, task.Torrent.Link); err != nil {
 			slog.Error("[rename handler] 更新种子重命名状态失败",
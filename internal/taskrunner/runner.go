@@ -3,6 +3,7 @@ package taskrunner
 import (
 	"context"
 	"log/slog"
+	"slices"
 	"sync"
 	"time"
 
@@ -43,15 +44,17 @@ Completed: 已结束，不再调度
 
 // TaskRunner 任务执行器
 type TaskRunner struct {
-	handlers map[model.TaskPhase]PhaseFunc
+	handlers    map[model.TaskPhase]PhaseFunc
+	transitions []TransitionFunc
 
-	// 保护 tasks 和 downloadSlots。
+	// 保护 tasks、downloadSlots 以及下面的并发上限。
 	// 同时需要锁 Task 时，固定先获取 mu，再获取 task.Mutex。
 	mu    sync.Mutex
 	tasks map[string]*model.Task
 
-	// channel 信号量：len = 当前运行数，cap = 上限
-	runningSem chan struct{}
+	// 当前运行的 worker 数和上限，上限可以在运行时调整
+	running        int
+	maxConcurrency int
 
 	// 下载槽位由 scheduler 集中分配，key 为 task.Torrent.Link，value 用于区分同 link 的新旧任务。
 	maxDownload   int
@@ -69,21 +72,140 @@ type TaskRunner struct {
 // 默认的并行任务数是 8, 下载槽位数是 4
 func New(maxConcurrency, maxDownload int) *TaskRunner {
 	return &TaskRunner{
-		handlers:      make(map[model.TaskPhase]PhaseFunc),
-		tasks:         make(map[string]*model.Task),
-		runningSem:    make(chan struct{}, maxConcurrency),
-		maxDownload:   maxDownload,
-		downloadSlots: make(map[string]*model.Task),
-		slotTimeout:   10 * time.Minute,
-		signal:        make(chan struct{}, 1),
+		handlers:       make(map[model.TaskPhase]PhaseFunc),
+		tasks:          make(map[string]*model.Task),
+		maxConcurrency: maxConcurrency,
+		maxDownload:    maxDownload,
+		downloadSlots:  make(map[string]*model.Task),
+		slotTimeout:    10 * time.Minute,
+		signal:         make(chan struct{}, 1),
 	}
 }
 
+// SetLimits 运行时调整并发上限，值为 0 的字段保持不变
+// 上限缩小时不会打断正在运行的 worker 和已持有的下载槽位，它们结束后自然回落；
+// 上限扩大时立即唤醒 scheduler。新的 slotTimeout 只对之后开始的任务生效。
+func (r *TaskRunner) SetLimits(l Limits) {
+	r.mu.Lock()
+	if l.MaxConcurrency > 0 {
+		r.maxConcurrency = l.MaxConcurrency
+	}
+	if l.MaxDownload > 0 {
+		r.maxDownload = l.MaxDownload
+	}
+	if l.SlotTimeout > 0 {
+		r.slotTimeout = l.SlotTimeout
+	}
+	limits := r.limitsLocked()
+	r.mu.Unlock()
+
+	slog.Info("[taskrunner] 并发上限已更新",
+		"max_concurrency", limits.MaxConcurrency,
+		"max_download", limits.MaxDownload,
+		"slot_timeout", limits.SlotTimeout)
+	r.notify()
+}
+
+// Limits 返回当前的并发上限
+func (r *TaskRunner) Limits() Limits {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.limitsLocked()
+}
+
+func (r *TaskRunner) limitsLocked() Limits {
+	return Limits{
+		MaxConcurrency: r.maxConcurrency,
+		MaxDownload:    r.maxDownload,
+		SlotTimeout:    r.slotTimeout,
+	}
+}
+
+// Stats 返回当前的占用情况
+func (r *TaskRunner) Stats() Stats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stats := Stats{
+		Limits:        r.limitsLocked(),
+		Running:       r.running,
+		DownloadSlots: len(r.downloadSlots),
+		Tasks:         len(r.tasks),
+	}
+	for _, task := range r.tasks {
+		task.Lock()
+		if task.State == model.TaskStateWaiting {
+			stats.Waiting++
+		}
+		task.Unlock()
+	}
+	return stats
+}
+
+// Has 判断种子是否有未结束的任务
+func (r *TaskRunner) Has(link string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.tasks[link]
+	return ok
+}
+
+// Tasks 返回所有未结束任务的当前状态
+func (r *TaskRunner) Tasks() []TaskStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	tasks := make([]TaskStatus, 0, len(r.tasks))
+	for _, task := range r.tasks {
+		task.Lock()
+		tasks = append(tasks, TaskStatus{
+			Link:       task.Torrent.Link,
+			Name:       task.Torrent.Name,
+			Downloader: task.Downloader,
+			Phase:      task.CurrentPhase,
+			State:      task.State,
+			RetryCount: task.RetryCount,
+			NextPoll:   task.NextPoll,
+			Message:    task.Message,
+			Failovers:  slices.Clone(task.Failovers),
+			RenamePlan: task.RenamePlan,
+		})
+		task.Unlock()
+	}
+	return tasks
+}
+
 // Register 注册阶段处理器
 func (r *TaskRunner) Register(phase model.TaskPhase, handler PhaseFunc) {
 	r.handlers[phase] = handler
 }
 
+// OnTransition 注册阶段结束回调，需在 Start 之前调用
+func (r *TaskRunner) OnTransition(fn TransitionFunc) {
+	r.transitions = append(r.transitions, fn)
+}
+
+// emit 依次调用阶段结束回调，调用方不能持有 r.mu 或 task.Mutex
+func (r *TaskRunner) emit(t Transition) {
+	for _, fn := range r.transitions {
+		fn(t)
+	}
+}
+
+// transitionLocked 生成当前阶段的结束信息，调用方需持有 task.Mutex
+func transitionLocked(task *model.Task, state string, err error) Transition {
+	var duration time.Duration
+	if !task.PhaseStart.IsZero() {
+		duration = time.Since(task.PhaseStart)
+	}
+	return Transition{
+		Task:       task,
+		Phase:      task.CurrentPhase,
+		State:      state,
+		Err:        err,
+		Duration:   duration,
+		RetryCount: task.RetryCount,
+	}
+}
+
 // notify 非阻塞写入 signal
 func (r *TaskRunner) notify() {
 	select {
@@ -135,6 +257,7 @@ func (r *TaskRunner) Cancel(link string) {
 		return
 	}
 	task.Lock()
+	tr := transitionLocked(task, model.HistoryStateCancelled, nil)
 	task.State = model.TaskStateCompleted
 	cancel := task.CancelFunc
 	task.Unlock()
@@ -142,6 +265,7 @@ func (r *TaskRunner) Cancel(link string) {
 	r.mu.Unlock()
 
 	cancel()
+	r.emit(tr)
 	r.notify()
 }
 
@@ -181,21 +305,36 @@ func (r *TaskRunner) scheduler(ctx context.Context) {
 func (r *TaskRunner) schedule() {
 	for {
 		// 非阻塞获取并发槽位
-		select {
-		case r.runningSem <- struct{}{}:
-		default:
+		if !r.tryAcquireRunning() {
 			return
 		}
 		task := r.pickTask()
 		if task == nil {
 			// 到这说明没有可调度的任务了，释放之前占用的并发槽位
-			<-r.runningSem
+			r.releaseRunning()
 			return
 		}
 		r.dispatch(task)
 	}
 }
 
+// tryAcquireRunning 在未达到并发上限时占用一个运行槽位
+func (r *TaskRunner) tryAcquireRunning() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.running >= r.maxConcurrency {
+		return false
+	}
+	r.running++
+	return true
+}
+
+func (r *TaskRunner) releaseRunning() {
+	r.mu.Lock()
+	r.running--
+	r.mu.Unlock()
+}
+
 func (r *TaskRunner) pickTask() *model.Task {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -239,11 +378,14 @@ func (r *TaskRunner) pickTaskLocked(match func(*model.Task) bool) *model.Task {
 	return nil
 }
 
-// dispatch 启动 goroutine 执行任务（调用方已持有一个 runningSem 槽位）
+// dispatch 启动 goroutine 执行任务（调用方已持有一个运行槽位）
 func (r *TaskRunner) dispatch(task *model.Task) {
+	r.mu.Lock()
+	slotTimeout := r.slotTimeout
+	r.mu.Unlock()
 	r.wg.Go(func() {
 		defer func() {
-			<-r.runningSem
+			r.releaseRunning()
 			task.Lock()
 			if task.State == model.TaskStateRunning {
 				task.State = model.TaskStateReady
@@ -263,7 +405,10 @@ func (r *TaskRunner) dispatch(task *model.Task) {
 			task.StartTime = now
 		}
 		if task.EndTime.IsZero() {
-			task.EndTime = now.Add(r.slotTimeout)
+			task.EndTime = now.Add(slotTimeout)
+		}
+		if task.PhaseStart.IsZero() {
+			task.PhaseStart = now
 		}
 		ctx := task.Ctx
 		task.State = model.TaskStateRunning
@@ -299,38 +444,58 @@ func (r *TaskRunner) process(ctx context.Context, task *model.Task) {
 	task.Unlock()
 	handler := r.handlers[phase]
 	if handler == nil {
-		r.advance(task)
+		r.advance(task, "")
 		return
 	}
 
 	result := handler(ctx, task)
 
+	// 任务取消后 handler 会因为 ctx 结束而返回，Cancel 已经记录过，结果直接丢弃
+	// 只看任务自己的 ctx，handler 内部其他原因产生的 context.Canceled 按普通错误处理
+	if result.Err != nil && ctx.Err() != nil {
+		slog.Debug("[taskrunner] 任务已取消，丢弃结果", "torrent", task.Torrent.Name, "phase", phase)
+		return
+	}
+
 	if result.Err != nil {
+		r.mu.Lock()
+		task.Lock()
+		if !r.activeLocked(task) {
+			task.Unlock()
+			r.mu.Unlock()
+			return
+		}
 		slog.Error("[taskrunner] 任务失败",
 			"torrent", task.Torrent.Name,
 			"phase", phase,
 			"error", result.Err)
-		r.mu.Lock()
-		task.Lock()
+		tr := transitionLocked(task, model.HistoryStateFailed, result.Err)
 		task.CurrentPhase = model.PhaseFailed
 		task.State = model.TaskStateCompleted
 		task.ErrorMsg = result.Err.Error()
 		task.Unlock()
 		r.removeTaskLocked(task)
 		r.mu.Unlock()
+		r.emit(tr)
+		return
+	}
+
+	if result.Replace != nil {
+		r.replace(task, result.Replace, result.Message)
 		return
 	}
 
 	if result.PollAfter > 0 {
 		r.mu.Lock()
 		task.Lock()
-		if r.tasks[task.Torrent.Link] != task || task.State != model.TaskStateRunning {
+		if !r.activeLocked(task) {
 			task.Unlock()
 			r.mu.Unlock()
 			return
 		}
 		task.NextPoll = time.Now().Add(result.PollAfter)
 		task.State = model.TaskStateWaiting
+		task.Message = result.Message
 		task.Unlock()
 		r.mu.Unlock()
 		// 到期后将仍在等待的同一个 Task 转为 Ready，再唤醒 scheduler。
@@ -341,29 +506,61 @@ func (r *TaskRunner) process(ctx context.Context, task *model.Task) {
 		})
 		return
 	}
-	r.advance(task)
+	r.advance(task, result.Message)
+}
+
+// replace 结束当前任务并提交新任务，新任务从自己的阶段重新开始
+func (r *TaskRunner) replace(task, next *model.Task, reason string) {
+	r.mu.Lock()
+	task.Lock()
+	if !r.activeLocked(task) {
+		task.Unlock()
+		r.mu.Unlock()
+		return
+	}
+	tr := transitionLocked(task, model.HistoryStateReplaced, nil)
+	tr.Message = reason
+	task.State = model.TaskStateCompleted
+	task.Message = reason
+	task.Unlock()
+	r.removeTaskLocked(task)
+	r.mu.Unlock()
+	r.emit(tr)
+
+	slog.Info("[taskrunner] 任务已替换",
+		"torrent", task.Torrent.Name,
+		"next", next.Torrent.Name,
+		"reason", reason)
+	r.Submit(next)
 }
 
-// advance 推进到下一阶段
+// advance 推进到下一阶段，message 为阶段结束的说明
 // 现在是按阶段推进, 后序再考虑阶段跳转的情况
-func (r *TaskRunner) advance(task *model.Task) {
+func (r *TaskRunner) advance(task *model.Task, message string) {
 	r.mu.Lock()
 	task.Lock()
+	if !r.activeLocked(task) {
+		task.Unlock()
+		r.mu.Unlock()
+		return
+	}
+	tr := transitionLocked(task, model.HistoryStateDone, nil)
+	tr.Message = message
 	oldPhase := task.CurrentPhase
-	nextPhase := oldPhase + 1
 	// FAIL->END, COMPLETED->END
-	if oldPhase >= model.PhaseCompleted {
-		nextPhase = model.PhaseEnd
-	}
+	nextPhase := oldPhase.Next()
 	task.CurrentPhase = nextPhase
 	task.RetryCount = 0
+	task.Message = ""
 	task.NextPoll = time.Time{}
+	task.PhaseStart = time.Time{}
 
 	if nextPhase == model.PhaseEnd {
 		task.State = model.TaskStateCompleted
 		r.removeTaskLocked(task)
 		task.Unlock()
 		r.mu.Unlock()
+		r.emit(tr)
 		slog.Debug("[taskrunner] 任务完成", "torrent", task.Torrent.Name)
 		return
 	}
@@ -373,6 +570,7 @@ func (r *TaskRunner) advance(task *model.Task) {
 	}
 	task.Unlock()
 	r.mu.Unlock()
+	r.emit(tr)
 
 	slog.Debug("[taskrunner] 阶段变更",
 		"torrent", task.Torrent.Name,
@@ -380,6 +578,20 @@ func (r *TaskRunner) advance(task *model.Task) {
 		"to", nextPhase)
 }
 
+// Wake 立即唤醒处于 Waiting 的任务，不用等 PollAfter 到期
+func (r *TaskRunner) Wake(link string) {
+	r.mu.Lock()
+	task, ok := r.tasks[link]
+	r.mu.Unlock()
+	if !ok {
+		return
+	}
+	if r.makeTaskReady(task) {
+		slog.Debug("[taskrunner] 提前唤醒任务", "torrent", task.Torrent.Name)
+		r.notify()
+	}
+}
+
 // makeTaskReady 将到期的等待任务转为 Ready。
 func (r *TaskRunner) makeTaskReady(task *model.Task) bool {
 	r.mu.Lock()
@@ -398,6 +610,12 @@ func (r *TaskRunner) makeTaskReady(task *model.Task) bool {
 	return true
 }
 
+// activeLocked 任务仍在 r.tasks 中且正在执行，已被 Cancel 或替换的任务不能再记录阶段结束
+// 调用方需持有 r.mu 和 task.Mutex
+func (r *TaskRunner) activeLocked(task *model.Task) bool {
+	return r.tasks[task.Torrent.Link] == task && task.State == model.TaskStateRunning
+}
+
 func (r *TaskRunner) removeTaskLocked(task *model.Task) {
 	link := task.Torrent.Link
 	r.releaseSlotLocked(task)
@@ -408,5 +626,9 @@ func (r *TaskRunner) removeTaskLocked(task *model.Task) {
 
 // needsDownloadSlot 判断阶段是否需要下载槽位
 func needsDownloadSlot(phase model.TaskPhase) bool {
-	return phase <= model.PhaseDownloading
+	switch phase {
+	case model.PhaseAdding, model.PhaseChecking, model.PhaseDownloading:
+		return true
+	}
+	return false
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -89,7 +90,7 @@ func TestTaskStateTracksWorkerLifecycle(t *testing.T) {
 		t.Fatal("submit task failed")
 	}
 
-	runner.runningSem <- struct{}{}
+	runner.tryAcquireRunning()
 	if picked := runner.pickTask(); picked != task {
 		t.Fatalf("picked task = %p, want %p", picked, task)
 	}
@@ -138,7 +139,7 @@ func TestPollAfterTransitionsWaitingTaskToReady(t *testing.T) {
 	if !runner.Submit(task) {
 		t.Fatal("submit task failed")
 	}
-	runner.runningSem <- struct{}{}
+	runner.tryAcquireRunning()
 	if picked := runner.pickTask(); picked != task {
 		t.Fatalf("picked task = %p, want %p", picked, task)
 	}
@@ -164,6 +165,37 @@ func TestPollAfterTransitionsWaitingTaskToReady(t *testing.T) {
 	runner.Cancel(task.Torrent.Link)
 }
 
+func TestWakeReadiesWaitingTaskEarly(t *testing.T) {
+	runner := New(1, 1)
+	runner.Register(model.PhaseAdding, func(ctx context.Context, task *model.Task) PhaseResult {
+		return PhaseResult{PollAfter: time.Hour}
+	})
+
+	task := model.NewAddTask(
+		&model.Torrent{Link: "torrent", Name: "torrent"},
+		model.NewBangumi(),
+	)
+	if !runner.Submit(task) {
+		t.Fatal("submit task failed")
+	}
+	runner.tryAcquireRunning()
+	if picked := runner.pickTask(); picked != task {
+		t.Fatalf("picked task = %p, want %p", picked, task)
+	}
+	runner.dispatch(task)
+	runner.wg.Wait()
+	if state := taskState(task); state != model.TaskStateWaiting {
+		t.Fatalf("state after PollAfter = %v, want waiting", state)
+	}
+
+	runner.Wake("not-exist")
+	runner.Wake(task.Torrent.Link)
+	if state := taskState(task); state != model.TaskStateReady {
+		t.Fatalf("state after Wake = %v, want ready", state)
+	}
+	runner.Cancel(task.Torrent.Link)
+}
+
 func TestOldWorkerDoesNotRemoveResubmittedTask(t *testing.T) {
 	runner := New(1, 1)
 	started := make(chan struct{})
@@ -183,7 +215,7 @@ func TestOldWorkerDoesNotRemoveResubmittedTask(t *testing.T) {
 	if !runner.Submit(oldTask) {
 		t.Fatal("submit old task failed")
 	}
-	runner.runningSem <- struct{}{}
+	runner.tryAcquireRunning()
 	if picked := runner.pickTask(); picked != oldTask {
 		t.Fatalf("picked task = %p, want old task %p", picked, oldTask)
 	}
@@ -222,6 +254,157 @@ func TestOldWorkerDoesNotRemoveResubmittedTask(t *testing.T) {
 	runner.Cancel(newTask.Torrent.Link)
 }
 
+func TestTransitionsRecordEveryPhaseEnd(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	runner := New(2, 1)
+	runner.Register(model.PhaseChecking, func(ctx context.Context, task *model.Task) PhaseResult {
+		task.RetryCount = 2
+		return PhaseResult{Err: errors.New("hash not found")}
+	})
+	var mu sync.Mutex
+	var got []Transition
+	runner.OnTransition(func(tr Transition) {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, tr)
+	})
+	runner.Start(ctx)
+	defer runner.Stop()
+
+	runner.Submit(model.NewAddTask(
+		&model.Torrent{Link: "torrent", Name: "torrent"},
+		model.NewBangumi(),
+	))
+
+	waitUntil(t, time.Second, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(got) == 2
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	if got[0].Phase != model.PhaseAdding || got[0].State != model.HistoryStateDone {
+		t.Errorf("first transition = %v/%s, want adding/done", got[0].Phase, got[0].State)
+	}
+	if got[1].Phase != model.PhaseChecking || got[1].State != model.HistoryStateFailed {
+		t.Errorf("second transition = %v/%s, want checking/failed", got[1].Phase, got[1].State)
+	}
+	if got[1].Err == nil || got[1].RetryCount != 2 {
+		t.Errorf("failed transition err = %v, retry = %d, want error and 2 retries", got[1].Err, got[1].RetryCount)
+	}
+}
+
+func TestReplaceSubmitsNewTask(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	runner := New(2, 1)
+	runner.Register(model.PhaseAdding, func(ctx context.Context, task *model.Task) PhaseResult {
+		if task.Torrent.Link == "stalled" {
+			next := model.NewAddTask(&model.Torrent{Link: "alternative", Name: "alternative"}, task.Bangumi)
+			return PhaseResult{Replace: next, Message: "switched to alternative"}
+		}
+		return PhaseResult{PollAfter: time.Hour}
+	})
+	var mu sync.Mutex
+	var got []Transition
+	runner.OnTransition(func(tr Transition) {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, tr)
+	})
+	runner.Start(ctx)
+	defer runner.Stop()
+
+	runner.Submit(model.NewAddTask(
+		&model.Torrent{Link: "stalled", Name: "stalled"},
+		model.NewBangumi(),
+	))
+
+	waitUntil(t, time.Second, func() bool {
+		return runner.Has("alternative")
+	})
+	if runner.Has("stalled") {
+		t.Error("replaced task should be removed")
+	}
+	mu.Lock()
+	if len(got) != 1 || got[0].State != model.HistoryStateReplaced || got[0].Message != "switched to alternative" {
+		t.Errorf("transitions = %+v, want one replaced transition", got)
+	}
+	mu.Unlock()
+	runner.Cancel("alternative")
+}
+
+func TestSetLimitsAdjustsRunningSlots(t *testing.T) {
+	runner := New(1, 1)
+	if !runner.tryAcquireRunning() {
+		t.Fatal("first acquire failed")
+	}
+	if runner.tryAcquireRunning() {
+		t.Fatal("acquired beyond max concurrency")
+	}
+
+	runner.SetLimits(Limits{MaxConcurrency: 2})
+	if !runner.tryAcquireRunning() {
+		t.Fatal("acquire failed after growing limit")
+	}
+
+	// 缩小上限不会影响已经在运行的 worker
+	runner.SetLimits(Limits{MaxConcurrency: 1, SlotTimeout: time.Minute})
+	stats := runner.Stats()
+	if stats.Running != 2 || stats.MaxConcurrency != 1 {
+		t.Fatalf("stats = %+v, want 2 running with limit 1", stats)
+	}
+	if stats.MaxDownload != 1 || stats.SlotTimeout != time.Minute {
+		t.Fatalf("limits = %+v, want download 1 and slot timeout 1m", stats.Limits)
+	}
+	runner.releaseRunning()
+	if runner.tryAcquireRunning() {
+		t.Fatal("acquired while still over the shrunk limit")
+	}
+	runner.releaseRunning()
+	if !runner.tryAcquireRunning() {
+		t.Fatal("acquire failed after running work drained")
+	}
+}
+
+func TestSetLimitsWakesSchedulerForMoreDownloads(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	runner := New(4, 1)
+	release := make(chan struct{})
+	var started atomic.Int32
+	runner.Register(model.PhaseAdding, func(ctx context.Context, task *model.Task) PhaseResult {
+		started.Add(1)
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+		return PhaseResult{}
+	})
+	runner.Start(ctx)
+	defer runner.Stop()
+	defer close(release)
+
+	for _, link := range []string{"a", "b"} {
+		runner.Submit(model.NewAddTask(
+			&model.Torrent{Link: link, Name: link},
+			model.NewBangumi(),
+		))
+	}
+	waitUntil(t, time.Second, func() bool { return started.Load() == 1 })
+
+	runner.SetLimits(Limits{MaxDownload: 2})
+	waitUntil(t, time.Second, func() bool { return started.Load() == 2 })
+	if stats := runner.Stats(); stats.DownloadSlots != 2 {
+		t.Fatalf("download slots = %d, want 2", stats.DownloadSlots)
+	}
+}
+
 func taskState(task *model.Task) model.TaskState {
 	task.Lock()
 	defer task.Unlock()
@@ -239,3 +422,104 @@ func waitUntil(t *testing.T, timeout time.Duration, ok func() bool) {
 	}
 	t.Fatal("condition not met before timeout")
 }
+
+func TestCancelledTaskDoesNotRecordFailure(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	runner := New(2, 1)
+	started := make(chan struct{})
+	runner.Register(model.PhaseAdding, func(ctx context.Context, task *model.Task) PhaseResult {
+		close(started)
+		<-ctx.Done()
+		return PhaseResult{Err: fmt.Errorf("add torrent: %w", ctx.Err())}
+	})
+	var mu sync.Mutex
+	var got []Transition
+	runner.OnTransition(func(tr Transition) {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, tr)
+	})
+	runner.Start(ctx)
+	defer runner.Stop()
+
+	runner.Submit(model.NewAddTask(
+		&model.Torrent{Link: "torrent", Name: "torrent"},
+		model.NewBangumi(),
+	))
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("handler did not start")
+	}
+	runner.Cancel("torrent")
+
+	runner.Stop()
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) != 1 || got[0].State != model.HistoryStateCancelled {
+		t.Errorf("transitions = %+v, want only the cancelled transition", got)
+	}
+}
+
+func TestCanceledErrorWithLiveContextRecordsFailure(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	runner := New(2, 1)
+	runner.Register(model.PhaseAdding, func(ctx context.Context, task *model.Task) PhaseResult {
+		return PhaseResult{Err: fmt.Errorf("request aborted: %w", context.Canceled)}
+	})
+	var mu sync.Mutex
+	var got []Transition
+	runner.OnTransition(func(tr Transition) {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, tr)
+	})
+	runner.Start(ctx)
+	defer runner.Stop()
+
+	runner.Submit(model.NewAddTask(
+		&model.Torrent{Link: "torrent", Name: "torrent"},
+		model.NewBangumi(),
+	))
+
+	waitUntil(t, time.Second, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(got) == 1
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	if got[0].State != model.HistoryStateFailed {
+		t.Errorf("transition state = %s, want failed", got[0].State)
+	}
+}
+
+func TestPhaseNextFollowsPipelineOrder(t *testing.T) {
+	want := []model.TaskPhase{
+		model.PhaseAdding,
+		model.PhaseChecking,
+		model.PhaseDownloading,
+		model.PhaseRenaming,
+		model.PhaseMoving,
+		model.PhaseNotifying,
+		model.PhaseCompleted,
+		model.PhaseEnd,
+	}
+	for i := 0; i < len(want)-1; i++ {
+		if got := want[i].Next(); got != want[i+1] {
+			t.Errorf("%v.Next() = %v, want %v", want[i], got, want[i+1])
+		}
+	}
+	if got := model.PhaseFailed.Next(); got != model.PhaseEnd {
+		t.Errorf("failed.Next() = %v, want end", got)
+	}
+	// 阶段的数值保存在死信表中，不能变
+	if model.PhaseNotifying != 4 || model.PhaseFailed != 6 || model.PhaseMoving != 8 {
+		t.Errorf("phase values changed: notifying=%d failed=%d moving=%d", model.PhaseNotifying, model.PhaseFailed, model.PhaseMoving)
+	}
+}
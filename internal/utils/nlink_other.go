@@ -0,0 +1,10 @@
+//go:build !unix
+
+package utils
+
+import "fmt"
+
+// LinkCount 当前平台不支持获取硬链接数
+func LinkCount(path string) (uint64, error) {
+	return 0, fmt.Errorf("当前平台不支持获取硬链接数: %s", path)
+}
@@ -0,0 +1,22 @@
+//go:build unix
+
+package utils
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// LinkCount 返回文件的硬链接数
+func LinkCount(path string) (uint64, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, fmt.Errorf("无法获取硬链接数: %s", path)
+	}
+	return uint64(st.Nlink), nil
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
@@ -11,6 +12,14 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "redrive" {
+		if err := core.RunRedriveCommand(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// logDir 和 dbDir 为同一目录
 	logDir := "./data"
 	posterDir := filepath.Join(logDir, "posters")
@@ -35,8 +44,9 @@ func main() {
 	program.Start(ctx)
 	<-ctx.Done()
 	// 启动 API 服务器（阻塞）
-	// server := api.NewServer()
-	// // 或者指定端口: server := api.NewServerWithPort(8080)
+	// deps := routes.Deps{DB: program.DB(), Runner: program.Runner(), Reconciler: program.Reconciler(), Downloaders: program.Downloaders()}
+	// server := api.NewServer(deps)
+	// // 或者指定端口: server := api.NewServerWithPort(8080, deps)
 	// if err := server.Run(); err != nil {
 	// 	panic(err)
 	// }